@@ -0,0 +1,219 @@
+package webmux
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ErrPatternValues is returned when building a path from a pattern with the
+// wrong number of values.
+var ErrPatternValues = errors.New("webmux: wrong number of pattern values")
+
+// aliasSet is the set of localized patterns registered for the same route.
+type aliasSet struct {
+	pattern  string            // canonical pattern
+	byLocale map[string]string // locale to pattern
+}
+
+// forLocale returns the pattern for locale, or the canonical pattern if there is none.
+func (a *aliasSet) forLocale(locale string) string {
+	if p, ok := a.byLocale[locale]; ok {
+		return p
+	}
+
+	return a.pattern
+}
+
+// Alias returns a RouteOption registering pattern as an alias of the route for
+// locale. Requests matching the alias are handled exactly like requests
+// matching the route, which allows serving localized URLs such as "/ueber-uns"
+// and "/a-propos" for "/about":
+//
+//	mux.Handle(http.MethodGet, "/about", about,
+//		webmux.Alias("de", "/ueber-uns"),
+//		webmux.Alias("fr", "/a-propos"),
+//	)
+//
+// The alias must contain the same parameters as the route, in the same order.
+// Use MuxMatch.AliasPath or ServeMux.Path to generate the URL for a locale.
+func Alias(locale, pattern string) RouteOption {
+	return func(cfg *routeConfig) {
+		if cfg.aliases == nil {
+			cfg.aliases = make(map[string]string)
+		}
+
+		cfg.aliases[locale] = pattern
+	}
+}
+
+// registerAliases registers the aliases in cfg for the route registered at pattern.
+func (mux *ServeMux) registerAliases(methods MethodSet, pattern string, handler Handler, cfg *routeConfig) {
+	set, ok := mux.aliases[pattern]
+
+	if !ok {
+		set = &aliasSet{pattern: pattern, byLocale: make(map[string]string)}
+
+		if mux.aliases == nil {
+			mux.aliases = make(map[string]*aliasSet)
+		}
+
+		mux.aliases[pattern] = set
+	}
+
+	params := patternParams(pattern)
+
+	// Aliases are registered like the route itself, minus the aliases
+	aliasCfg := *cfg
+	aliasCfg.aliases = nil
+
+	for locale, alias := range cfg.aliases {
+		if !slices.Equal(patternParams(alias), params) {
+			panic(fmt.Sprintf("webmux: alias %s has different parameters than %s", alias, pattern))
+		}
+
+		set.byLocale[locale] = alias
+
+		if alias != pattern {
+			mux.handle(methods, alias, handler, &aliasCfg)
+		}
+
+		mux.aliases[alias] = set
+	}
+
+	root := mux.hostTree(cfg.host)
+	setAliases(root, pattern, set)
+
+	for _, alias := range set.byLocale {
+		setAliases(root, alias, set)
+	}
+}
+
+// setAliases sets the alias set of the entry registered for pattern in the tree root.
+func setAliases(root *node, pattern string, set *aliasSet) {
+	if n := root.find(pattern); n != nil && n.entry != nil {
+		n.entry = n.entry.clone()
+		n.entry.aliases = set
+	}
+}
+
+// Path returns the path for the route registered with pattern in the given
+// locale, substituting values for the parameters in order. If no alias is
+// registered for locale the route pattern itself is used.
+func (mux *ServeMux) Path(pattern, locale string, values ...string) (string, error) {
+	mux.mu.RLock()
+	set, ok := mux.aliases[pattern]
+	mux.mu.RUnlock()
+
+	if ok {
+		pattern = set.forLocale(locale)
+	}
+
+	return BuildPath(pattern, values...)
+}
+
+// AliasPattern returns the pattern registered for the matched route in locale,
+// or the matched pattern if the route has no alias for locale.
+func (m *MuxMatch) AliasPattern(locale string) string {
+	if m.muxEntry == nil {
+		return ""
+	}
+
+	if m.aliases == nil {
+		return m.pattern
+	}
+
+	return m.aliases.forLocale(locale)
+}
+
+// AliasPath returns the path of the matched route in locale, using the values
+// captured from the request. It is useful for linking to the current page in
+// another language.
+func (m *MuxMatch) AliasPath(locale string) string {
+	p, _ := BuildPath(m.AliasPattern(locale), m.values...)
+
+	return p
+}
+
+// BuildPath returns the path for pattern, substituting values for the
+// parameters in the order they appear. Named parameter values are escaped,
+// while wildcard values may contain slashes.
+func BuildPath(pattern string, values ...string) (string, error) {
+	var b strings.Builder
+
+	path := cleanPath(pattern)
+	i := 0
+
+	for path != "" {
+		head, tail := shiftPath(path)
+
+		if head == "" {
+			break
+		}
+
+		b.WriteByte('/')
+
+		if head[0] == ':' || head[0] == '*' {
+			if i >= len(values) {
+				return "", ErrPatternValues
+			}
+
+			if head[0] == ':' {
+				b.WriteString(url.PathEscape(values[i]))
+			} else {
+				b.WriteString(escapeWildcard(values[i]))
+			}
+
+			i++
+		} else {
+			b.WriteString(head)
+		}
+
+		path = tail
+	}
+
+	if i != len(values) {
+		return "", ErrPatternValues
+	}
+
+	if b.Len() == 0 {
+		return "/", nil
+	}
+
+	return b.String(), nil
+}
+
+// escapeWildcard escapes each segment of a wildcard value.
+func escapeWildcard(v string) string {
+	segments := strings.Split(v, "/")
+
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// patternParams returns the parameter names of pattern in order.
+func patternParams(pattern string) []string {
+	params := make([]string, 0)
+	path := cleanPath(pattern)
+
+	for path != "" {
+		head, tail := shiftPath(path)
+
+		if head == "" {
+			break
+		}
+
+		if head[0] == ':' || head[0] == '*' {
+			params = append(params, paramName(head))
+		}
+
+		path = tail
+	}
+
+	return params
+}
@@ -0,0 +1,196 @@
+package webmux
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ByteCount is the number of bytes transferred by a request.
+type ByteCount struct {
+	// Read is the number of bytes of the request body read by the handler.
+	Read int64
+
+	// Written is the number of bytes of the response body written by the handler.
+	Written int64
+}
+
+// CountBytes returns a middleware counting the bytes of the request body read
+// and of the response body written, calling report with the counts once the
+// request is handled, for instance to export them as metrics:
+//
+//	mux.Use(webmux.CountBytes(func(r *http.Request, n webmux.ByteCount) {
+//		m, _ := webmux.FromContext(r.Context())
+//		written.WithLabelValues(m.Pattern()).Add(float64(n.Written))
+//	}))
+//
+// The counts exclude the headers, and the response written by the error
+// handler for errors returned by the handler, since the error handler is
+// called with the writer of the mux.
+func CountBytes(report func(r *http.Request, n ByteCount)) Middleware {
+	if report == nil {
+		panic("webmux: nil byte count report")
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			cw := &countingWriter{ResponseWriter: w}
+
+			var body *countingReader
+
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &countingReader{ReadCloser: r.Body}
+				r.Body = body
+			}
+
+			err := next.ServeHTTPErr(cw, r)
+
+			n := ByteCount{Written: cw.written.Load()}
+
+			if body != nil {
+				n.Read = body.read.Load()
+			}
+
+			report(r, n)
+
+			return err
+		})
+	}
+}
+
+// countingReader is a request body counting the bytes read.
+type countingReader struct {
+	io.ReadCloser
+	read atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.read.Add(int64(n))
+
+	return n, err
+}
+
+// countingWriter is a ResponseWriter counting the bytes written.
+type countingWriter struct {
+	http.ResponseWriter
+	written atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.written.Add(int64(n))
+
+	return n, err
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// LimitBandwidth returns a middleware limiting the rate at which responses
+// are written to rate bytes per second, shared by all the requests handled
+// by the middleware. Applied to a single route, it keeps large downloads from
+// using up the bandwidth of a host shared with latency-sensitive APIs:
+//
+//	mux.Handle(http.MethodGet, "/exports/:id", export, webmux.WithMiddleware(webmux.LimitBandwidth(10<<20, 0)))
+//
+// The limit is a token bucket holding up to burst bytes, which may be sent
+// at once after a period of inactivity. If burst is not positive, a tenth of
+// a second worth of data is used. Writes wait for tokens according to the
+// clock of the mux, returning early with the error of the request context
+// if it is canceled.
+//
+// Unlike DownloadOptions.RateLimit, which limits each download separately,
+// the limit applies to the sum of the concurrent responses.
+func LimitBandwidth(rate, burst int64) Middleware {
+	if rate <= 0 {
+		panic("webmux: bandwidth rate must be positive")
+	}
+
+	if burst <= 0 {
+		burst = max(rate/10, 1)
+	}
+
+	bucket := &tokenBucket{rate: rate, burst: burst, tokens: float64(burst)}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return next.ServeHTTPErr(&bucketWriter{ResponseWriter: w, r: r, bucket: bucket}, r)
+		})
+	}
+}
+
+// tokenBucket is a token bucket of bytes shared by concurrent writers.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   int64 // bytes per second
+	burst  int64 // capacity of the bucket
+	tokens float64
+	last   time.Time // time tokens were last added, zero until first used
+}
+
+// take removes n tokens from the bucket at now, returning how long to wait
+// before they are available. The tokens may be overdrawn, so that writers
+// waiting concurrently are served in turn.
+func (b *tokenBucket) take(now time.Time, n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * float64(b.rate)
+		b.tokens = min(b.tokens, float64(b.burst))
+	}
+
+	if now.After(b.last) {
+		b.last = now
+	}
+
+	b.tokens -= float64(n)
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+}
+
+// bucketWriter is a ResponseWriter taking the bytes written from a token bucket.
+type bucketWriter struct {
+	http.ResponseWriter
+	r      *http.Request
+	bucket *tokenBucket
+}
+
+// Write writes p in chunks of at most the burst of the bucket, waiting for
+// the tokens of each chunk before writing it.
+func (b *bucketWriter) Write(p []byte) (int, error) {
+	ctx := b.r.Context()
+	chunk := int(b.bucket.burst)
+	n := 0
+
+	for n < len(p) {
+		end := min(n+chunk, len(p))
+
+		if err := sleepContext(ctx, b.bucket.take(Now(ctx), end-n)); err != nil {
+			return n, err
+		}
+
+		m, err := b.ResponseWriter.Write(p[n:end])
+		n += m
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (b *bucketWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
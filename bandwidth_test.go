@@ -0,0 +1,67 @@
+package webmux_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestCountBytes(t *testing.T) {
+	var counts []webmux.ByteCount
+	var patterns []string
+
+	mux := webmux.New()
+	mux.Use(webmux.CountBytes(func(r *http.Request, n webmux.ByteCount) {
+		m, _ := webmux.FromContext(r.Context())
+		patterns = append(patterns, m.Pattern())
+		counts = append(counts, n)
+	}))
+	mux.HandleFunc(http.MethodPost, "/echo", func(w http.ResponseWriter, r *http.Request) error {
+		_, err := io.Copy(w, r.Body)
+		return err
+	})
+	mux.Handle(http.MethodGet, "/hello", newTestHandler("hello"))
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("ping pong")))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	assert.Equal(t, []string{"/echo", "/hello"}, patterns)
+	assert.Equal(t, []webmux.ByteCount{{Read: 9, Written: 9}, {Written: 5}}, counts)
+}
+
+func TestLimitBandwidth(t *testing.T) {
+	clock := muxtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	mux := webmux.New()
+	mux.SetClock(clock)
+	mux.Handle(http.MethodGet, "/download", newTestHandler(strings.Repeat("x", 25)), webmux.WithMiddleware(webmux.LimitBandwidth(10, 10)))
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download", nil))
+	}()
+
+	// The burst is written at once, the rest waits a second for each 10 bytes
+	for i := 0; i < 2; i++ {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		clock.Advance(time.Second)
+	}
+
+	<-done
+
+	assert.Equal(t, 25, w.Body.Len())
+	assert.Equal(t, clock.Now().Sub(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), 2*time.Second)
+}
@@ -0,0 +1,213 @@
+package webmux
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrBatchTooLarge is returned by the batch handler when a batch contains too many requests.
+var ErrBatchTooLarge = errors.New("webmux: too many batch requests")
+
+// Defaults of BatchOptions.
+const (
+	defaultBatchMaxRequests = 20
+	defaultBatchMaxBytes    = 1 << 20
+)
+
+// BatchRequest is a sub-request of a batch.
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"` // path and optional query
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the response to a sub-request of a batch.
+// JSON response bodies are embedded as is, other bodies as a JSON string.
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchOptions configures the batch handler.
+type BatchOptions struct {
+	// MaxRequests is the maximum number of sub-requests in a batch.
+	// If zero, a default of 20 is used.
+	MaxRequests int
+
+	// MaxBytes limits the size of the batch request body. If zero, a
+	// default of 1 MiB is used.
+	MaxBytes int64
+}
+
+// Batch returns a handler accepting a JSON array of sub-requests, which are
+// dispatched through mux in order, responding with a JSON array of their
+// responses. This lets clients such as mobile apps save round trips:
+//
+//	[{"method":"GET","path":"/users/1"},{"method":"POST","path":"/events","body":{"type":"open"}}]
+//
+// Sub-requests inherit the context and headers of the batch request, such as
+// Authorization and Cookie, and run through the middleware of their routes
+// like any other request. A failing sub-request does not stop the batch, its
+// error response is included instead. Sub-requests for paths not registered
+// with mux respond with 404 Not Found without being dispatched, and batches
+// may not be nested. Batch bodies larger than the limit are rejected with a
+// 413 Request Entity Too Large [HTTPError] wrapping ErrBodyTooLarge, and
+// bodies with a Content-Type other than JSON with a 415 Unsupported Media
+// Type.
+func Batch(mux *ServeMux, opts BatchOptions) Handler {
+	if opts.MaxRequests <= 0 {
+		opts.MaxRequests = defaultBatchMaxRequests
+	}
+
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultBatchMaxBytes
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Context().Value(batchKey) != nil {
+			return NewHTTPError(http.StatusBadRequest, errors.New("webmux: nested batch request"))
+		}
+
+		if !isJSONContentType(r.Header.Get("Content-Type")) {
+			return NewHTTPError(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+		}
+
+		var reqs []BatchRequest
+
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, opts.MaxBytes)).Decode(&reqs); err != nil {
+			var maxErr *http.MaxBytesError
+
+			if errors.As(err, &maxErr) {
+				return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit))
+			}
+
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if len(reqs) > opts.MaxRequests {
+			return NewHTTPError(http.StatusRequestEntityTooLarge, ErrBatchTooLarge)
+		}
+
+		ctx := context.WithValue(r.Context(), batchKey, true)
+		resps := make([]BatchResponse, 0, len(reqs))
+
+		for _, sub := range reqs {
+			resp, err := mux.serveBatchRequest(ctx, r, sub)
+
+			if err != nil {
+				return err
+			}
+
+			resps = append(resps, resp)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		return json.NewEncoder(w).Encode(resps)
+	})
+}
+
+// serveBatchRequest dispatches the sub-request sub of the batch request parent.
+func (mux *ServeMux) serveBatchRequest(ctx context.Context, parent *http.Request, sub BatchRequest) (BatchResponse, error) {
+	if sub.Method == "" {
+		sub.Method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, sub.Method, sub.Path, bytes.NewReader(sub.Body))
+
+	if err != nil || !strings.HasPrefix(sub.Path, "/") {
+		return BatchResponse{Status: http.StatusBadRequest}, nil
+	}
+
+	if mux.LookupPath(req.URL.EscapedPath()) == nil {
+		return BatchResponse{Status: http.StatusNotFound}, nil
+	}
+
+	req.Header = parent.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Del("Content-Type")
+	req.Host = parent.Host
+	req.RemoteAddr = parent.RemoteAddr
+	req.RequestURI = sub.Path
+
+	if len(sub.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+
+	// Handler errors are rendered into the sub-response by the error handler
+	out, _ := mux.do(req)
+
+	resp := BatchResponse{
+		Status:  out.StatusCode,
+		Headers: make(map[string]string, len(out.Header)),
+	}
+
+	for k := range out.Header {
+		resp.Headers[k] = out.Header.Get(k)
+	}
+
+	if len(out.Body) == 0 {
+		return resp, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(out.Header.Get("Content-Type"))
+
+	if (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(out.Body) {
+		resp.Body = bytes.TrimSpace(out.Body)
+		return resp, nil
+	}
+
+	body, err := json.Marshal(string(out.Body))
+
+	if err != nil {
+		return BatchResponse{}, fmt.Errorf("webmux: batch response: %w", err)
+	}
+
+	resp.Body = body
+
+	return resp, nil
+}
+
+// responseBuffer is an in-memory [http.ResponseWriter].
+type responseBuffer struct {
+	code        int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+// newResponseBuffer returns a new responseBuffer.
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{code: http.StatusOK, header: make(http.Header)}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.wroteHeader {
+		return
+	}
+
+	b.code = code
+	b.wroteHeader = true
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+
+	return b.body.Write(p)
+}
@@ -0,0 +1,65 @@
+package webmux_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestBatch(t *testing.T) {
+	mux := webmux.New()
+
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		m, _ := webmux.FromContext(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(w, `{"id":"`+m.Param("id")+`","auth":"`+r.Header.Get("Authorization")+`"}`)
+		return err
+	})
+	mux.HandleFunc(http.MethodPost, "/echo", func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		_, err := io.Copy(w, r.Body)
+		return err
+	})
+	mux.Handle(http.MethodPost, "/batch", webmux.Batch(mux, webmux.BatchOptions{MaxRequests: 5, MaxBytes: 1024}))
+
+	serveType := func(contentType, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer t")
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		return serveType("application/json", body)
+	}
+
+	w := serve(`[
+		{"method":"GET","path":"/users/1"},
+		{"method":"POST","path":"/echo","body":{"a":1}},
+		{"method":"DELETE","path":"/users/1"},
+		{"path":"/missing"},
+		{"method":"POST","path":"/batch","body":[]}
+	]`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[`+
+		`{"status":200,"headers":{"Content-Type":"application/json"},"body":{"id":"1","auth":"Bearer t"}},`+
+		`{"status":201,"body":"{\"a\":1}"},`+
+		`{"status":405,"headers":{"Allow":"OPTIONS, GET, HEAD","Content-Type":"text/plain; charset=utf-8","X-Content-Type-Options":"nosniff"},"body":"Method Not Allowed\n"},`+
+		`{"status":404},`+
+		`{"status":400,"headers":{"Content-Type":"text/plain; charset=utf-8","X-Content-Type-Options":"nosniff"},"body":"Bad Request\n"}`+
+		`]`+"\n", w.Body.String())
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(`[{},{},{},{},{},{}]`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(`{`).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(`[{"path":"`+strings.Repeat("a", 1024)+`"}]`).Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, serveType("text/plain", `[]`).Code)
+	assert.Equal(t, http.StatusOK, serveType("", `[]`).Code)
+}
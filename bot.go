@@ -0,0 +1,141 @@
+package webmux
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrBotDetected is returned by the BotGuard middleware when a request is
+// rejected as automated.
+var ErrBotDetected = errors.New("webmux: bot detected")
+
+// defaultBotMaxBody is the default of BotOptions.MaxBodyBytes.
+const defaultBotMaxBody = 64 << 10
+
+// A ChallengeProvider verifies that a request was made by a human, for example
+// by validating a CAPTCHA response submitted with a form.
+type ChallengeProvider interface {
+	// Verify returns nil if r passed the challenge.
+	Verify(r *http.Request) error
+}
+
+// The ChallengeFunc type is an adapter to allow the use of ordinary functions
+// as challenge providers.
+type ChallengeFunc func(r *http.Request) error
+
+// Verify calls f(r).
+func (f ChallengeFunc) Verify(r *http.Request) error {
+	return f(r)
+}
+
+// BotOptions configures the BotGuard middleware.
+type BotOptions struct {
+	// HoneypotField is the name of a form field hidden from humans with CSS.
+	// Bots filling in every field reveal themselves by submitting a value.
+	HoneypotField string
+
+	// MaxBodyBytes is the size of the largest URL-encoded body read for the
+	// honeypot field. Other bodies are not checked for the field. If zero, a
+	// default of 64KiB is used.
+	MaxBodyBytes int64
+
+	// Suspicious reports whether r looks automated, typically based on the
+	// User-Agent header. SuspiciousUserAgent is a basic implementation.
+	// If nil, every request is considered suspicious when Challenge is set.
+	Suspicious func(r *http.Request) bool
+
+	// Challenge verifies suspicious requests. If nil, suspicious requests are rejected.
+	Challenge ChallengeProvider
+}
+
+// BotGuard returns a middleware applying lightweight bot defenses to unsafe
+// requests, such as form submissions. It is intended to wrap the handlers of
+// forms that attract spam, like signup and contact forms.
+//
+// Requests that fill in the honeypot field of their URL-encoded body are
+// rejected, and the body is left unread for the handler. Requests deemed
+// suspicious must pass the challenge. Rejected requests result in a 403
+// Forbidden [HTTPError] wrapping ErrBotDetected, and malformed bodies in a
+// 400 Bad Request.
+func BotGuard(opts BotOptions) Middleware {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultBotMaxBody
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if isSafeMethod(r.Method) {
+				return next.ServeHTTPErr(w, r)
+			}
+
+			forbidden := NewHTTPError(http.StatusForbidden, ErrBotDetected)
+
+			if opts.HoneypotField != "" {
+				form, err := peekForm(r, opts.MaxBodyBytes)
+
+				if err != nil {
+					return NewHTTPError(http.StatusBadRequest, err)
+				}
+
+				if form.Get(opts.HoneypotField) != "" {
+					return forbidden
+				}
+			}
+
+			suspicious := false
+
+			if opts.Suspicious != nil {
+				suspicious = opts.Suspicious(r)
+			} else {
+				suspicious = opts.Challenge != nil
+			}
+
+			if suspicious {
+				if opts.Challenge == nil {
+					return forbidden
+				}
+
+				if err := opts.Challenge.Verify(r); err != nil {
+					return NewHTTPError(http.StatusForbidden, errors.Join(ErrBotDetected, err))
+				}
+			}
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+}
+
+// botUserAgents are substrings of user agents commonly sent by HTTP libraries and crawlers.
+var botUserAgents = []string{
+	"bot",
+	"crawler",
+	"spider",
+	"curl",
+	"wget",
+	"python-requests",
+	"python-urllib",
+	"go-http-client",
+	"java/",
+	"libwww-perl",
+	"headlesschrome",
+	"phantomjs",
+}
+
+// SuspiciousUserAgent reports whether the User-Agent of r is missing or
+// belongs to a well-known crawler, HTTP library, or headless browser.
+func SuspiciousUserAgent(r *http.Request) bool {
+	ua := strings.ToLower(r.UserAgent())
+
+	if ua == "" {
+		return true
+	}
+
+	for _, s := range botUserAgents {
+		if strings.Contains(ua, s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package webmux_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestBotGuardHoneypot(t *testing.T) {
+	var body string
+
+	mux := webmux.New()
+	mux.Use(webmux.BotGuard(webmux.BotOptions{HoneypotField: "website", MaxBodyBytes: 64}))
+	mux.HandleFunc(http.MethodPost, "/signup", func(w http.ResponseWriter, r *http.Request) error {
+		b, err := io.ReadAll(r.Body)
+		body = string(b)
+		return err
+	})
+
+	serve := func(contentType, form string) int {
+		body = ""
+
+		r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	const urlencoded = "application/x-www-form-urlencoded"
+
+	assert.Equal(t, http.StatusOK, serve(urlencoded, "email=a%40b.c&website="))
+	assert.Equal(t, "email=a%40b.c&website=", body)
+
+	assert.Equal(t, http.StatusForbidden, serve(urlencoded, "email=a%40b.c&website=spam"))
+	assert.Equal(t, http.StatusBadRequest, serve(urlencoded, "email=%zz"))
+
+	// Other and oversized bodies are not read for the field
+	assert.Equal(t, http.StatusOK, serve("text/plain", "website=spam"))
+	assert.Equal(t, "website=spam", body)
+
+	long := "website=spam&note=" + strings.Repeat("a", 64)
+
+	assert.Equal(t, http.StatusOK, serve(urlencoded, long))
+	assert.Equal(t, long, body)
+}
+
+func TestBotGuardChallenge(t *testing.T) {
+	errChallenge := errors.New("challenge failed")
+
+	mux := webmux.New()
+	mux.Use(webmux.BotGuard(webmux.BotOptions{
+		Suspicious: webmux.SuspiciousUserAgent,
+		Challenge: webmux.ChallengeFunc(func(r *http.Request) error {
+			if r.Header.Get("X-Captcha") != "ok" {
+				return errChallenge
+			}
+
+			return nil
+		}),
+	}))
+	mux.Handle(http.MethodGet, "/contact", newTestHandler("form"))
+	mux.Handle(http.MethodPost, "/contact", newTestHandler("sent"))
+
+	var err error
+
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, e error) {
+		err = e
+		webmux.StatusErrorHandler().ErrorHTTP(w, r, e)
+	})
+
+	serve := func(method, ua, captcha string) int {
+		r := httptest.NewRequest(method, "/contact", nil)
+		r.Header.Set("User-Agent", ua)
+		r.Header.Set("X-Captcha", captcha)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "curl/8.0", ""))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "Mozilla/5.0", ""))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "curl/8.0", "ok"))
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "curl/8.0", ""))
+	assert.IsError(t, err, webmux.ErrBotDetected)
+	assert.IsError(t, err, errChallenge)
+}
+
+func TestSuspiciousUserAgent(t *testing.T) {
+	for ua, want := range map[string]bool{
+		"":                                true,
+		"Googlebot/2.1":                   true,
+		"python-requests/2.31":            true,
+		"Go-http-client/1.1":              true,
+		"Mozilla/5.0 (X11) Firefox/120.0": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("User-Agent", ua)
+
+		assert.Equal(t, want, webmux.SuspiciousUserAgent(r), ua)
+	}
+}
@@ -0,0 +1,95 @@
+package webmux
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// AcceptBraceParams makes mux accept patterns using the brace syntax of
+// [http.ServeMux] and other routers during a migration to webmux, so both
+// syntaxes can be used in the same routing table. Brace params are
+// normalized to the syntax of webmux when the route is registered:
+//
+//	{id}        -> :id
+//	{id:\d+}    -> :id(\d+)
+//	{path...}   -> *path
+//	/users/{$}  -> /users/
+//
+// The brace syntax is deprecated, warn is called with the pattern and its
+// normalized form for each pattern using it, so remaining usages can be
+// found. If warn is nil they are logged. Braces must span a whole segment,
+// AcceptBraceParams panics at registration for patterns like "/{a}-{b}".
+//
+// Without AcceptBraceParams, braces are literal characters of a pattern.
+// AcceptBraceParams sets the pattern engine of mux, see SetPatternEngine.
+func (mux *ServeMux) AcceptBraceParams(warn func(pattern, normalized string)) {
+	if warn == nil {
+		warn = func(pattern, normalized string) {
+			log.Printf("mux deprecated: pattern %s uses brace params, use %s", pattern, normalized)
+		}
+	}
+
+	mux.engine = PatternEngineFunc(func(pattern string) ([]Segment, error) {
+		if !strings.Contains(pattern, "{") {
+			return parseDefaultPattern(pattern)
+		}
+
+		normalized := normalizeBraces(pattern)
+
+		if normalized != pattern {
+			warn(pattern, normalized)
+		}
+
+		return parseDefaultPattern(normalized)
+	})
+}
+
+// normalizeBraces replaces the brace params of pattern with the webmux syntax.
+func normalizeBraces(pattern string) string {
+	segments := strings.Split(pattern, "/")
+
+	for i, s := range segments {
+		if !strings.ContainsAny(s, "{}") {
+			continue
+		}
+
+		if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+			panic(fmt.Sprintf("webmux: brace param must span a whole segment in %s", pattern))
+		}
+
+		inner := s[1 : len(s)-1]
+		name, expr, ok := strings.Cut(inner, ":")
+
+		if strings.ContainsAny(name, "{}") {
+			panic(fmt.Sprintf("webmux: brace param must span a whole segment in %s", pattern))
+		}
+
+		switch {
+		case inner == "$":
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("webmux: {$} must end the pattern %s", pattern))
+			}
+
+			segments[i] = ""
+		case strings.HasSuffix(inner, "..."):
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("webmux: wildcard must end the pattern %s", pattern))
+			}
+
+			segments[i] = "*" + strings.TrimSuffix(inner, "...")
+		default:
+			if name == "" {
+				panic(fmt.Sprintf("webmux: unnamed brace param in %s", pattern))
+			}
+
+			if ok {
+				segments[i] = ":" + name + "(" + expr + ")"
+			} else {
+				segments[i] = ":" + name
+			}
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
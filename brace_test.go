@@ -0,0 +1,71 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServeMuxAcceptBraceParams(t *testing.T) {
+	var warnings []string
+
+	mux := webmux.New()
+	mux.AcceptBraceParams(func(pattern, normalized string) {
+		warnings = append(warnings, pattern+" -> "+normalized)
+	})
+
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		m, _ := webmux.FromContext(r.Context())
+		_, err := w.Write([]byte(m.Pattern() + " " + m.Param("id") + m.Param("path")))
+		return err
+	})
+
+	mux.Handle(http.MethodGet, "/users/{id}", h)
+	mux.Handle(http.MethodGet, "/orders/{id:\\d+}", h)
+	mux.Handle(http.MethodGet, "/files/{path...}", h)
+	mux.Handle(http.MethodGet, "/posts/:id", h)
+	mux.HandlePattern("GET /teams/{$}", h)
+
+	for path, want := range map[string]string{
+		"/users/1":       "/users/:id 1",
+		"/orders/42":     "/orders/:id(\\d+) 42",
+		"/files/a/b.txt": "/files/*path a/b.txt",
+		"/posts/7":       "/posts/:id 7",
+		"/teams":         "/teams/ ",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, w.Body.String(), path)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/abc", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Equal(t, []string{
+		"/users/{id} -> /users/:id",
+		"/orders/{id:\\d+} -> /orders/:id(\\d+)",
+		"/files/{path...} -> /files/*path",
+		"/teams/{$} -> /teams/",
+	}, warnings)
+
+	assert.Panics(t, func() {
+		mux.Handle(http.MethodGet, "/dates/{month}-{day}", h)
+	})
+}
+
+func TestServeMuxBracesLiteral(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/{id}", newTestHandler("literal"))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/%7Bid%7D", nil))
+	assert.Equal(t, "literal", w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,128 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when an upstream request is not attempted
+// because the deadline of the request has passed.
+var ErrBudgetExhausted = errors.New("webmux: request budget exhausted")
+
+// RequestTimeoutHeader is the default header used by BudgetTransport to
+// propagate the remaining time budget, in milliseconds.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// Budget returns the time remaining until the deadline of ctx, or false if
+// ctx has no deadline. A deadline is typically set by timeout middleware.
+func Budget(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+
+	if !ok {
+		return 0, false
+	}
+
+	return time.Until(deadline), true
+}
+
+// BudgetTransport is an [http.RoundTripper] propagating the remaining time
+// budget of the request context to upstream servers, so that upstreams stop
+// working on requests whose result will not be used. It is intended for
+// proxies such as [httputil.ReverseProxy]:
+//
+//	proxy := httputil.NewSingleHostReverseProxy(upstream)
+//	proxy.Transport = &webmux.BudgetTransport{Reserve: 50 * time.Millisecond}
+//
+// The upstream request is canceled at the deadline, and is not attempted at
+// all if the budget is already exhausted. Requests without a deadline are
+// passed through unchanged.
+type BudgetTransport struct {
+	// Base performs the requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Header is the header set to the remaining budget in milliseconds.
+	// If empty, RequestTimeoutHeader is used.
+	Header string
+
+	// Reserve is subtracted from the budget given to the upstream, leaving
+	// time to handle its response before the deadline.
+	Reserve time.Duration
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *BudgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	budget, ok := Budget(req.Context())
+
+	if !ok {
+		return base.RoundTrip(req)
+	}
+
+	budget -= t.Reserve
+
+	if budget <= 0 {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+
+		return nil, ErrBudgetExhausted
+	}
+
+	header := t.Header
+
+	if header == "" {
+		header = RequestTimeoutHeader
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(header, strconv.FormatInt(budget.Milliseconds(), 10))
+
+	return base.RoundTrip(req)
+}
+
+// formatGRPCTimeout formats d as a grpc-timeout header value, which has at most eight digits.
+func formatGRPCTimeout(d time.Duration) string {
+	if ms := d.Milliseconds(); ms < 1e8 {
+		return strconv.FormatInt(max(ms, 1), 10) + "m"
+	}
+
+	return strconv.FormatInt(min(int64(d/time.Second), 1e8-1), 10) + "S"
+}
+
+// parseGRPCTimeout parses a grpc-timeout header value.
+func parseGRPCTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+
+	unit, ok := units[v[len(v)-1]]
+
+	if !ok {
+		return 0, false
+	}
+
+	return time.Duration(n) * unit, true
+}
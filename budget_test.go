@@ -0,0 +1,48 @@
+package webmux_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestBudgetTransport(t *testing.T) {
+	var got string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(webmux.RequestTimeoutHeader)
+	}))
+	t.Cleanup(upstream.Close)
+
+	client := &http.Client{Transport: &webmux.BudgetTransport{Reserve: 100 * time.Millisecond}}
+
+	resp, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "", got)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	ms, err := strconv.Atoi(got)
+	assert.NoError(t, err)
+	assert.True(t, ms > 800 && ms <= 900, got)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	_, err = client.Do(req)
+	assert.IsError(t, err, webmux.ErrBudgetExhausted)
+}
@@ -0,0 +1,357 @@
+package webmux
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// CBOR major types, see RFC 8949 section 3.1.
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// cborBreak terminates an indefinite length item.
+const cborBreak byte = 0xff
+
+// errCBORBreak is returned when a break stop code is decoded.
+var errCBORBreak = errors.New("cbor: unexpected break")
+
+// CBORCodec is a Codec for CBOR as defined by RFC 8949.
+// Values are converted using their JSON representation, so json struct tags
+// and [json.Marshaler] implementations are honored. Tags are decoded as their
+// tagged content.
+type CBORCodec struct {
+	// MaxDepth limits the nesting of decoded arrays, maps, and tags. If zero,
+	// DefaultMaxCodecDepth is used.
+	MaxDepth int
+}
+
+// Decode implements Codec.
+func (c CBORCodec) Decode(r io.Reader, v any) error {
+	tree, err := decodeCBOR(bufio.NewReader(r), codecDepth(c.MaxDepth))
+
+	if err != nil {
+		return err
+	}
+
+	return fromJSONValue(tree, v)
+}
+
+// Encode implements Codec.
+func (CBORCodec) Encode(w io.Writer, v any) error {
+	tree, err := toJSONValue(v)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := appendCBOR(nil, tree)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+// appendCBORHead appends the initial bytes of a data item with major type major and argument n.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+// appendCBOR appends the CBOR encoding of the JSON value v to b.
+func appendCBOR(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, cborSimple|22), nil
+	case bool:
+		if v {
+			return append(b, cborSimple|21), nil
+		}
+
+		return append(b, cborSimple|20), nil
+	case json.Number:
+		kind, i, u, f, err := parseNumber(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case numberInt:
+			if i >= 0 {
+				return appendCBORHead(b, cborUint, uint64(i)), nil
+			}
+
+			return appendCBORHead(b, cborNegInt, uint64(-1-i)), nil
+		case numberUint:
+			return appendCBORHead(b, cborUint, u), nil
+		}
+
+		return binary.BigEndian.AppendUint64(append(b, cborSimple|27), math.Float64bits(f)), nil
+	case string:
+		b = appendCBORHead(b, cborText, uint64(len(v)))
+
+		return append(b, v...), nil
+	case []any:
+		b = appendCBORHead(b, cborArray, uint64(len(v)))
+
+		var err error
+
+		for _, e := range v {
+			if b, err = appendCBOR(b, e); err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	case map[string]any:
+		b = appendCBORHead(b, cborMap, uint64(len(v)))
+
+		var err error
+
+		for _, k := range sortedKeys(v) {
+			b = appendCBORHead(b, cborText, uint64(len(k)))
+			b = append(b, k...)
+
+			if b, err = appendCBOR(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("cbor: unsupported type %T", v)
+}
+
+// decodeCBOR decodes a single CBOR data item from r, nesting arrays, maps,
+// and tags at most depth levels.
+func decodeCBOR(r *bufio.Reader, depth int) (any, error) {
+	c, err := r.ReadByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c == cborBreak {
+		return nil, errCBORBreak
+	}
+
+	major, info := c&0xe0, c&0x1f
+	indefinite := info == 31
+
+	if indefinite && (major == cborUint || major == cborNegInt || major == cborTag) {
+		return nil, fmt.Errorf("cbor: invalid indefinite length for major type %d", major>>5)
+	}
+
+	if depth == 0 && (major == cborArray || major == cborMap || major == cborTag) {
+		return nil, errCodecDepth("cbor")
+	}
+
+	var n uint64
+
+	if major != cborSimple && !indefinite {
+		if n, err = readCBORArg(r, info); err != nil {
+			return nil, err
+		}
+	}
+
+	switch major {
+	case cborUint:
+		return n, nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return -1 - float64(n), nil
+		}
+
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		var b []byte
+
+		if indefinite {
+			b, err = readCBORChunks(r, major)
+		} else {
+			b, err = readBytes(r, int(n))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if major == cborText {
+			return string(b), nil
+		}
+
+		return b, nil
+	case cborArray:
+		a := make([]any, 0, min(n, 1024))
+
+		for i := uint64(0); indefinite || i < n; i++ {
+			v, err := decodeCBOR(r, depth-1)
+
+			if indefinite && err == errCBORBreak {
+				break
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			a = append(a, v)
+		}
+
+		return a, nil
+	case cborMap:
+		m := make(map[string]any, min(n, 1024))
+
+		for i := uint64(0); indefinite || i < n; i++ {
+			k, err := decodeCBOR(r, depth-1)
+
+			if indefinite && err == errCBORBreak {
+				break
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := decodeCBOR(r, depth-1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			m[mapKey(k)] = v
+		}
+
+		return m, nil
+	case cborTag:
+		return decodeCBOR(r, depth-1)
+	}
+
+	return decodeCBORSimple(r, info)
+}
+
+// readCBORArg reads the argument of a data item with additional information info.
+func readCBORArg(r io.Reader, info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		return readUint(r, 1<<(info-24))
+	}
+
+	return 0, fmt.Errorf("cbor: invalid additional information %d", info)
+}
+
+// readCBORChunks reads the definite length chunks of an indefinite length string of type major.
+func readCBORChunks(r *bufio.Reader, major byte) ([]byte, error) {
+	var b []byte
+
+	for {
+		c, err := r.ReadByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if c == cborBreak {
+			return b, nil
+		}
+
+		if c&0xe0 != major {
+			return nil, fmt.Errorf("cbor: invalid chunk type %d", c>>5)
+		}
+
+		n, err := readCBORArg(r, c&0x1f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		chunk, err := readBytes(r, int(n))
+
+		if err != nil {
+			return nil, err
+		}
+
+		b = append(b, chunk...)
+	}
+}
+
+// decodeCBORSimple decodes a simple value or float with additional information info.
+func decodeCBORSimple(r io.Reader, info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		n, err := readUint(r, 2)
+
+		return halfToFloat64(uint16(n)), err
+	case 26:
+		n, err := readUint(r, 4)
+
+		return float64(math.Float32frombits(uint32(n))), err
+	case 27:
+		n, err := readUint(r, 8)
+
+		return math.Float64frombits(n), err
+	}
+
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+}
+
+// halfToFloat64 converts an IEEE 754 half precision float to a float64.
+func halfToFloat64(h uint16) float64 {
+	sign := 1.0
+
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 31:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+
+		return math.NaN()
+	}
+
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
@@ -0,0 +1,161 @@
+package webmux
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrChaos is the error of failures injected by Chaos.
+var ErrChaos = errors.New("webmux: injected failure")
+
+// ChaosRule describes the faults injected into the requests for a route.
+type ChaosRule struct {
+	Method  string        `json:"method,omitempty"`  // request method, any if empty
+	Pattern string        `json:"pattern,omitempty"` // route pattern, any if empty
+	Rate    float64       `json:"rate"`              // fraction of matching requests affected, from 0 to 1
+	Latency time.Duration `json:"latency,omitempty"` // delay added before the handler is called
+	Status  int           `json:"status,omitempty"`  // if set, respond with this status instead of calling the handler
+	Reset   bool          `json:"reset,omitempty"`   // abort the connection instead of responding
+}
+
+// match returns true if the rule applies to a request for pattern.
+func (rule *ChaosRule) match(method, pattern string) bool {
+	return (rule.Method == "" || rule.Method == method) && (rule.Pattern == "" || rule.Pattern == pattern)
+}
+
+// Chaos injects latency, errors, and connection resets into requests, for
+// testing the retry and timeout behavior of clients in staging environments.
+//
+// Chaos is opt-in: a new Chaos is disabled, and does nothing until enabled
+// with SetEnabled or its admin handler. It is safe for concurrent use.
+type Chaos struct {
+	mu      sync.RWMutex
+	enabled bool
+	rules   []ChaosRule
+	rand    func() float64
+}
+
+// NewChaos returns a new disabled Chaos injecting faults according to rules.
+func NewChaos(rules ...ChaosRule) *Chaos {
+	return &Chaos{rules: rules, rand: rand.Float64}
+}
+
+// SetEnabled enables or disables fault injection.
+func (c *Chaos) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	c.enabled = enabled
+	c.mu.Unlock()
+}
+
+// SetRules replaces the rules of c.
+func (c *Chaos) SetRules(rules ...ChaosRule) {
+	c.mu.Lock()
+	c.rules = rules
+	c.mu.Unlock()
+}
+
+// Middleware returns a middleware injecting faults into requests.
+//
+// Rules are matched against the route pattern when the middleware is applied
+// to the handlers of routes, otherwise against the request path. Every
+// matching rule is rolled independently. Latency is added before the handler
+// is called, and a rule with Reset aborts the connection by panicking with
+// [http.ErrAbortHandler]. A rule with Status returns an [HTTPError] wrapping
+// ErrChaos instead of calling the handler.
+func (c *Chaos) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			c.mu.RLock()
+			enabled, rules := c.enabled, c.rules
+			c.mu.RUnlock()
+
+			if !enabled {
+				return next.ServeHTTPErr(w, r)
+			}
+
+			pattern := r.URL.Path
+
+			if match, ok := FromContext(r.Context()); ok {
+				pattern = match.Pattern()
+			}
+
+			for i := range rules {
+				rule := &rules[i]
+
+				if !rule.match(r.Method, pattern) || c.rand() >= rule.Rate {
+					continue
+				}
+
+				if rule.Latency > 0 {
+					if err := sleepContext(r.Context(), rule.Latency); err != nil {
+						return err
+					}
+				}
+
+				if rule.Reset {
+					panic(http.ErrAbortHandler)
+				}
+
+				if rule.Status != 0 {
+					return NewHTTPError(rule.Status, ErrChaos)
+				}
+			}
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+}
+
+// chaosState is the representation of Chaos used by the admin handler.
+type chaosState struct {
+	Enabled bool        `json:"enabled"`
+	Rules   []ChaosRule `json:"rules"`
+}
+
+// AdminHandler returns a handler for toggling fault injection at runtime.
+// GET responds with the current state as JSON:
+//
+//	{"enabled":true,"rules":[{"pattern":"/users/:id","rate":0.1,"status":503}]}
+//
+// PUT replaces the state with the JSON request body, and DELETE disables
+// fault injection. Latency is given in nanoseconds. The handler must only be
+// reachable by operators, such as behind authentication or on an internal
+// listener.
+func (c *Chaos) AdminHandler() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut:
+			var state chaosState
+
+			if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+				return NewHTTPError(http.StatusBadRequest, err)
+			}
+
+			c.mu.Lock()
+			c.enabled, c.rules = state.Enabled, state.Rules
+			c.mu.Unlock()
+		case http.MethodDelete:
+			c.SetEnabled(false)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+			return NewHTTPError(http.StatusMethodNotAllowed, nil)
+		}
+
+		c.mu.RLock()
+		state := chaosState{Enabled: c.enabled, Rules: c.rules}
+		c.mu.RUnlock()
+
+		if state.Rules == nil {
+			state.Rules = []ChaosRule{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		return json.NewEncoder(w).Encode(state)
+	})
+}
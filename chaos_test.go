@@ -0,0 +1,49 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestChaos(t *testing.T) {
+	chaos := webmux.NewChaos(webmux.ChaosRule{Pattern: "/flaky/:id", Rate: 1, Status: http.StatusServiceUnavailable})
+	ok := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/flaky/:id", chaos.Middleware()(ok))
+	mux.Handle(http.MethodGet, "/stable", chaos.Middleware()(ok))
+	mux.HandleMethods(webmux.AnyMethod(), "/admin/chaos", chaos.AdminHandler())
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	// Disabled by default
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/flaky/1", "").Code)
+
+	chaos.SetEnabled(true)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodGet, "/flaky/1", "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/stable", "").Code)
+
+	w := serve(http.MethodPut, "/admin/chaos", `{"enabled":true,"rules":[{"pattern":"/stable","rate":1,"status":500}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"enabled":true,"rules":[{"pattern":"/stable","rate":1,"status":500}]}`+"\n", w.Body.String())
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/flaky/1", "").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(http.MethodGet, "/stable", "").Code)
+
+	serve(http.MethodDelete, "/admin/chaos", "")
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/stable", "").Code)
+
+	chaos.SetRules(webmux.ChaosRule{Rate: 1, Reset: true})
+	chaos.SetEnabled(true)
+	assert.Panics(t, func() { serve(http.MethodGet, "/stable", "") })
+}
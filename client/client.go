@@ -0,0 +1,371 @@
+// Package client provides an [http.Client] for handlers calling other
+// services, propagating the request ID, trace context and time budget of the
+// inbound request, and retrying failed idempotent requests:
+//
+//	mux.Use(client.Inbound())
+//
+//	c := client.New(&client.Options{Retries: 2, Timeout: 2 * time.Second})
+//
+//	func getUser(w http.ResponseWriter, r *http.Request) error {
+//		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, usersURL, nil)
+//		res, err := c.Do(req)
+//		// ...
+//	}
+//
+// Outbound requests must use the context of the inbound request, or a
+// context derived from it.
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.destructure.dev/webmux"
+)
+
+// RequestIDHeader is the default header carrying the request ID.
+const RequestIDHeader = webmux.RequestIDHeader
+
+// ctxKey is an unexported type to prevent collisions.
+type ctxKey int
+
+const inboundKey ctxKey = iota // key for inbound values
+
+// inbound is the state of the inbound request propagated to outbound requests.
+type inbound struct {
+	header     string // request ID header
+	requestID  string
+	traceID    string // trace ID of the traceparent header, empty if there is none
+	traceFlags string
+	traceState string
+}
+
+// Inbound returns a middleware recording the request ID and trace context
+// of inbound requests, which the Transport propagates to outbound requests.
+//
+// The request ID is the one assigned by [webmux.RequestID], if the
+// middleware runs before Inbound. Otherwise it is read from the
+// RequestIDHeader, and a random ID is generated if the header is missing or
+// longer than 128 bytes. The ID is set on the response as well, so clients
+// can report it. The trace context
+// is read from the W3C traceparent and tracestate headers, if present.
+func Inbound() webmux.Middleware {
+	return InboundHeader(RequestIDHeader)
+}
+
+// InboundHeader is like Inbound, reading the request ID from header.
+func InboundHeader(header string) webmux.Middleware {
+	return func(next webmux.Handler) webmux.Handler {
+		return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			in := &inbound{header: header, requestID: webmux.RequestIDFromContext(r.Context())}
+
+			if in.requestID == "" {
+				in.requestID = r.Header.Get(header)
+			}
+
+			if in.requestID == "" || len(in.requestID) > 128 {
+				in.requestID = randomHex(16)
+			}
+
+			if traceID, flags, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+				in.traceID, in.traceFlags = traceID, flags
+				in.traceState = r.Header.Get("tracestate")
+			}
+
+			w.Header().Set(header, in.requestID)
+
+			return next.ServeHTTPErr(w, r.WithContext(context.WithValue(r.Context(), inboundKey, in)))
+		})
+	}
+}
+
+// RequestID returns the ID of the inbound request of ctx, recorded by
+// Inbound or assigned by [webmux.RequestID], or an empty string if there is
+// none.
+func RequestID(ctx context.Context) string {
+	in, ok := ctx.Value(inboundKey).(*inbound)
+
+	if !ok {
+		return webmux.RequestIDFromContext(ctx)
+	}
+
+	return in.requestID
+}
+
+// Attempt describes a single attempt of an outbound request, see Options.Trace.
+type Attempt struct {
+	Request  *http.Request  // the request sent, with the propagated headers
+	Response *http.Response // the response, nil if Err is not nil
+	Err      error          // the error of the attempt, if any
+	Number   int            // number of the attempt, starting at 1
+	Duration time.Duration  // time until the response header was received
+}
+
+// Options configures New and Transport.
+type Options struct {
+	// Base performs the requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Timeout limits the duration of each attempt, including reading the
+	// response body. Attempts are canceled at the deadline of the inbound
+	// request as well. Zero means no limit other than the deadline.
+	Timeout time.Duration
+
+	// Reserve is subtracted from the budget of the inbound request, leaving
+	// time to handle the response before the deadline, see
+	// [webmux.BudgetTransport].
+	Reserve time.Duration
+
+	// Retries is the number of times an idempotent request is retried after
+	// a network error or a 502, 503 or 504 response. Requests with a body
+	// are only retried if [http.Request.GetBody] is set.
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled for each of the
+	// following retries up to MaxBackoff. If zero, 100ms is used. Retries
+	// are not attempted if the delay exceeds the budget of the request.
+	Backoff time.Duration
+
+	// MaxBackoff limits the delay between retries. If zero, 5s is used.
+	MaxBackoff time.Duration
+
+	// Trace is called after each attempt, for instance to record spans or
+	// metrics of outbound requests.
+	Trace func(a Attempt)
+}
+
+// New returns an [http.Client] using a Transport configured with opts.
+// A nil opts is equivalent to a zero Options.
+func New(opts *Options) *http.Client {
+	return &http.Client{Transport: NewTransport(opts)}
+}
+
+// Transport is an [http.RoundTripper] propagating the request ID, trace
+// context and time budget of the inbound request to outbound requests, and
+// retrying failed idempotent requests.
+//
+// The request ID is sent in the header used by Inbound. The trace context
+// is sent in the traceparent and tracestate headers, with a new parent ID
+// for each attempt, and the remaining budget in the
+// [webmux.RequestTimeoutHeader].
+type Transport struct {
+	opts   Options
+	budget *webmux.BudgetTransport
+}
+
+// NewTransport returns a Transport configured with opts.
+// A nil opts is equivalent to a zero Options.
+func NewTransport(opts *Options) *Transport {
+	t := &Transport{}
+
+	if opts != nil {
+		t.opts = *opts
+	}
+
+	if t.opts.Backoff <= 0 {
+		t.opts.Backoff = 100 * time.Millisecond
+	}
+
+	if t.opts.MaxBackoff <= 0 {
+		t.opts.MaxBackoff = 5 * time.Second
+	}
+
+	t.budget = &webmux.BudgetTransport{Base: t.opts.Base, Reserve: t.opts.Reserve}
+
+	return t
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retries := 0
+
+	if t.retryable(req) {
+		retries = t.opts.Retries
+	}
+
+	backoff := t.opts.Backoff
+
+	for n := 1; ; n++ {
+		res, err := t.attempt(req, n)
+
+		if n > retries || !shouldRetry(res, err) || ctx.Err() != nil {
+			return res, err
+		}
+
+		if budget, ok := webmux.Budget(ctx); ok && budget-t.opts.Reserve <= backoff {
+			return res, err
+		}
+
+		if res != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(res.Body, 4<<10))
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, t.opts.MaxBackoff)
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// attempt sends the n-th attempt of req.
+func (t *Transport) attempt(req *http.Request, n int) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(nil)
+
+	if t.opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.opts.Timeout)
+	}
+
+	out := req.Clone(ctx)
+	propagate(out)
+
+	start := time.Now()
+	res, err := t.budget.RoundTrip(out)
+
+	if t.opts.Trace != nil {
+		t.opts.Trace(Attempt{Request: out, Response: res, Err: err, Number: n, Duration: time.Since(start)})
+	}
+
+	if cancel == nil {
+		return res, err
+	}
+
+	if err != nil {
+		cancel()
+		return res, err
+	}
+
+	// The timeout applies until the body is closed
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+
+	return res, nil
+}
+
+// retryable returns true if req may be sent again.
+func (t *Transport) retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// shouldRetry returns true if the result of an attempt is a transient failure.
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, webmux.ErrBudgetExhausted)
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// propagate sets the request ID and trace context headers of out from the
+// inbound request of its context.
+func propagate(out *http.Request) {
+	in, ok := out.Context().Value(inboundKey).(*inbound)
+
+	if !ok {
+		// Without Inbound, only the ID of webmux.RequestID is propagated
+		if id := webmux.RequestIDFromContext(out.Context()); id != "" {
+			out.Header.Set(RequestIDHeader, id)
+		}
+
+		return
+	}
+
+	out.Header.Set(in.header, in.requestID)
+
+	if in.traceID == "" {
+		return
+	}
+
+	out.Header.Set("traceparent", "00-"+in.traceID+"-"+randomHex(8)+"-"+in.traceFlags)
+
+	if in.traceState != "" {
+		out.Header.Set("tracestate", in.traceState)
+	} else {
+		out.Header.Del("tracestate")
+	}
+}
+
+// parseTraceparent returns the trace ID and flags of a version 00 W3C
+// traceparent header, like "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func parseTraceparent(v string) (traceID, flags string, ok bool) {
+	parts := strings.Split(v, "-")
+
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", "", false
+	}
+
+	for _, p := range parts[1:] {
+		if _, err := hex.DecodeString(p); err != nil || strings.ToLower(p) != p {
+			return "", "", false
+		}
+	}
+
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+
+	return parts[1], parts[3], true
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) string {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		panic("client: " + err.Error())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// cancelBody is a response body canceling the context of its request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+
+	return err
+}
@@ -0,0 +1,123 @@
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/client"
+)
+
+func TestClient(t *testing.T) {
+	var calls atomic.Int32
+	var headers []http.Header
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	var attempts []int
+
+	c := client.New(&client.Options{
+		Retries: 2,
+		Backoff: time.Millisecond,
+		Timeout: time.Second,
+		Trace: func(a client.Attempt) {
+			attempts = append(attempts, a.Response.StatusCode)
+		},
+	})
+
+	mux := webmux.New()
+	mux.Use(client.Inbound())
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		res, err := c.Do(req)
+
+		if err != nil {
+			return err
+		}
+
+		defer res.Body.Close()
+
+		w.WriteHeader(res.StatusCode)
+
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "abc", w.Header().Get("X-Request-Id"))
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusOK}, attempts)
+	assert.Equal(t, 2, len(headers))
+
+	for _, h := range headers {
+		assert.Equal(t, "abc", h.Get("X-Request-Id"))
+		assert.True(t, strings.HasPrefix(h.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+		assert.True(t, strings.HasSuffix(h.Get("traceparent"), "-01"))
+		assert.NotEqual(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", h.Get("traceparent"))
+	}
+
+	t.Run("no retry", func(t *testing.T) {
+		calls.Store(0)
+
+		req, _ := http.NewRequest(http.MethodPost, upstream.URL, strings.NewReader("{}"))
+		res, err := c.Do(req)
+
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("generated request id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+		assert.Equal(t, 32, len(w.Header().Get("X-Request-Id")))
+		assert.Equal(t, "", client.RequestID(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+	})
+
+	t.Run("webmux request id", func(t *testing.T) {
+		headers = nil
+
+		mux := webmux.New()
+		mux.Use(webmux.RequestID(webmux.RequestIDOptions{}))
+		mux.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) error {
+			assert.Equal(t, "xyz", client.RequestID(r.Context()))
+
+			req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+			res, err := c.Do(req)
+
+			if err != nil {
+				return err
+			}
+
+			return res.Body.Close()
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Request-Id", "xyz")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, "xyz", headers[0].Get("X-Request-Id"))
+	})
+}
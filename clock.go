@@ -0,0 +1,56 @@
+package webmux
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is a source of time. The time-dependent features of webmux, like
+// Dedupe windows, Server-Timing metrics and download throttling, read the
+// time from the clock of the mux dispatching the request, so tests can
+// replace it with a fake clock to advance time deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock reading the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SetClock sets the clock used by requests dispatched by mux.
+// If c is nil, the system clock is used.
+//
+// Requests dispatched to a mounted mux use the clock of the parent mux.
+func (mux *ServeMux) SetClock(c Clock) {
+	mux.clock = c
+}
+
+// Now returns the current time according to the clock of the mux dispatching
+// the request of ctx, or the system time if ctx is not the context of a
+// request dispatched by ServeMux.
+func Now(ctx context.Context) time.Time {
+	return clockFrom(ctx).Now()
+}
+
+// clockFrom returns the clock of the mux dispatching the request of ctx.
+func clockFrom(ctx context.Context) Clock {
+	s, _ := ctx.Value(storeKey).(*requestStore)
+
+	if s == nil || s.clock == nil {
+		return systemClock{}
+	}
+
+	return s.clock
+}
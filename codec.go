@@ -0,0 +1,279 @@
+package webmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Codec encodes and decodes request and response bodies of a single media type.
+type Codec interface {
+	Decode(r io.Reader, v any) error
+	Encode(w io.Writer, v any) error
+}
+
+// DefaultMaxCodecDepth is the nesting limit of MsgpackCodec and CBORCodec.
+const DefaultMaxCodecDepth = 32
+
+// codecDepth returns the nesting limit max, or DefaultMaxCodecDepth if zero.
+func codecDepth(max int) int {
+	if max == 0 {
+		return DefaultMaxCodecDepth
+	}
+
+	return max
+}
+
+// errCodecDepth returns the 400 [HTTPError] of a body of the named format
+// nesting deeper than the limit of its codec.
+func errCodecDepth(format string) error {
+	return NewHTTPError(http.StatusBadRequest, fmt.Errorf("%s: nesting exceeds depth limit", format))
+}
+
+// Codecs is a registry of codecs keyed by media type.
+// Decode selects a codec using the request's Content-Type header, and Encode
+// selects a codec using the request's Accept header.
+//
+// A Codecs is safe for concurrent use.
+type Codecs struct {
+	mu     sync.RWMutex
+	types  []string         // media types in registration order
+	codecs map[string]Codec // media type to codec
+}
+
+// DefaultCodecs is the registry used by Decode and Encode.
+// It has codecs registered for JSON, XML, MessagePack, and CBOR, with JSON
+// as the default.
+var DefaultCodecs = NewCodecs()
+
+func init() {
+	DefaultCodecs.Register("application/json", JSONCodec{})
+	DefaultCodecs.Register("application/xml", XMLCodec{})
+	DefaultCodecs.Register("text/xml", XMLCodec{})
+	DefaultCodecs.Register("application/msgpack", MsgpackCodec{})
+	DefaultCodecs.Register("application/x-msgpack", MsgpackCodec{})
+	DefaultCodecs.Register("application/cbor", CBORCodec{})
+}
+
+// NewCodecs allocates and returns a new empty Codecs registry.
+func NewCodecs() *Codecs {
+	return &Codecs{
+		codecs: make(map[string]Codec),
+	}
+}
+
+// Register registers codec for mediaType, replacing any existing codec.
+// The first registered media type is the default, used when a request does
+// not specify a preference.
+func (c *Codecs) Register(mediaType string, codec Codec) {
+	if codec == nil {
+		panic("webmux: nil codec")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.codecs[mediaType]; !ok {
+		c.types = append(c.types, mediaType)
+	}
+
+	c.codecs[mediaType] = codec
+}
+
+// Lookup returns the codec registered for mediaType, if any.
+func (c *Codecs) Lookup(mediaType string) (Codec, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	codec, ok := c.codecs[mediaType]
+
+	return codec, ok
+}
+
+// MediaTypes returns the registered media types in registration order.
+func (c *Codecs) MediaTypes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	types := make([]string, len(c.types))
+	copy(types, c.types)
+
+	return types
+}
+
+// Decode decodes the request body of r into v using the codec registered for
+// the request Content-Type. If the request has no Content-Type the default
+// codec is used. An unregistered Content-Type results in a 415 [HTTPError],
+// and a malformed body results in a 400 [HTTPError].
+func (c *Codecs) Decode(r *http.Request, v any) error {
+	mediaType := ""
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, _ = mime.ParseMediaType(ct)
+	} else if types := c.MediaTypes(); len(types) > 0 {
+		mediaType = types[0]
+	}
+
+	codec, ok := c.Lookup(mediaType)
+
+	if !ok {
+		return NewHTTPError(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+	}
+
+	if err := codec.Decode(r.Body, v); err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Errorf("webmux: decode %s: %w", mediaType, err))
+	}
+
+	return nil
+}
+
+// Encode writes v to w with the status code code, using the codec that best
+// matches the Accept header of r. If none of the registered media types are
+// acceptable a 406 [HTTPError] is returned before anything is written.
+func (c *Codecs) Encode(w http.ResponseWriter, r *http.Request, code int, v any) error {
+	AddVary(w.Header(), "Accept")
+
+	mediaType := negotiateContentType(r.Header.Get("Accept"), c.MediaTypes())
+
+	codec, ok := c.Lookup(mediaType)
+
+	if !ok {
+		return NewHTTPError(http.StatusNotAcceptable, nil)
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(code)
+
+	return codec.Encode(w, v)
+}
+
+// RegisterCodec registers codec for mediaType in DefaultCodecs.
+func RegisterCodec(mediaType string, codec Codec) {
+	DefaultCodecs.Register(mediaType, codec)
+}
+
+// Decode decodes the request body of r into v using DefaultCodecs.
+func Decode(r *http.Request, v any) error {
+	return DefaultCodecs.Decode(r, v)
+}
+
+// Encode writes v to w with the status code code using DefaultCodecs.
+func Encode(w http.ResponseWriter, r *http.Request, code int, v any) error {
+	return DefaultCodecs.Encode(w, r, code, v)
+}
+
+// JSONCodec is a Codec for JSON using [encoding/json].
+type JSONCodec struct{}
+
+// Decode implements Codec.
+func (JSONCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Encode implements Codec.
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XMLCodec is a Codec for XML using [encoding/xml].
+// Input in the ISO-8859-1 and US-ASCII charsets is supported.
+type XMLCodec struct{}
+
+// Decode implements Codec.
+func (XMLCodec) Decode(r io.Reader, v any) error {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = defaultCharsetReader
+
+	return dec.Decode(v)
+}
+
+// Encode implements Codec.
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// toJSONValue converts v to a tree of nil, bool, [json.Number], string, []any,
+// and map[string]any values using its JSON representation. This allows codecs
+// for other formats to honor json struct tags and [json.Marshaler].
+func toJSONValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var tree any
+
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// fromJSONValue stores the tree of decoded values in v using its JSON representation.
+func fromJSONValue(tree any, v any) error {
+	b, err := json.Marshal(tree)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// numberKind classifies a [json.Number] for binary encoding.
+type numberKind int
+
+const (
+	numberInt numberKind = iota
+	numberUint
+	numberFloat
+)
+
+// parseNumber parses n as the most precise numeric type.
+func parseNumber(n json.Number) (numberKind, int64, uint64, float64, error) {
+	s := string(n)
+
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return numberInt, i, 0, 0, nil
+		}
+
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return numberUint, 0, u, 0, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+
+	return numberFloat, 0, 0, f, err
+}
+
+// sortedKeys returns the keys of m in sorted order, for deterministic encoding.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,144 @@
+package webmux_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+type codecValue struct {
+	Name  string  `json:"name" xml:"name"`
+	Count int64   `json:"count" xml:"count"`
+	Ratio float64 `json:"ratio" xml:"ratio"`
+	Tags  []bool  `json:"tags" xml:"tags"`
+}
+
+func TestCodecsEncoding(t *testing.T) {
+	v := codecValue{Name: "a", Count: -300, Ratio: 0.5, Tags: []bool{true}}
+
+	var tests = []struct {
+		name  string
+		codec webmux.Codec
+		want  []byte
+	}{
+		{
+			"msgpack",
+			webmux.MsgpackCodec{},
+			[]byte{
+				0x84,
+				0xa5, 'c', 'o', 'u', 'n', 't', 0xd1, 0xfe, 0xd4,
+				0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a',
+				0xa5, 'r', 'a', 't', 'i', 'o', 0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+				0xa4, 't', 'a', 'g', 's', 0x91, 0xc3,
+			},
+		},
+		{
+			"cbor",
+			webmux.CBORCodec{},
+			[]byte{
+				0xa4,
+				0x65, 'c', 'o', 'u', 'n', 't', 0x39, 0x01, 0x2b,
+				0x64, 'n', 'a', 'm', 'e', 0x61, 'a',
+				0x65, 'r', 'a', 't', 'i', 'o', 0xfb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+				0x64, 't', 'a', 'g', 's', 0x81, 0xf5,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			assert.NoError(t, tc.codec.Encode(&buf, v))
+			assert.Equal(t, tc.want, buf.Bytes())
+
+			var got codecValue
+
+			assert.NoError(t, tc.codec.Decode(bytes.NewReader(tc.want), &got))
+			assert.Equal(t, v, got)
+		})
+	}
+}
+
+func TestCodecsNegotiation(t *testing.T) {
+	var tests = []struct {
+		name   string
+		accept string
+		want   string
+		code   int
+	}{
+		{"no accept", "", "application/json", http.StatusOK},
+		{"exact", "application/cbor", "application/cbor", http.StatusOK},
+		{"quality", "application/json;q=0.5, application/msgpack", "application/msgpack", http.StatusOK},
+		{"wildcard", "text/*", "text/xml", http.StatusOK},
+		{"any", "*/*", "application/json", http.StatusOK},
+		{"not acceptable", "image/png", "", http.StatusNotAcceptable},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+			w.Header().Set("Vary", "accept, Cookie")
+
+			err := webmux.Encode(w, r, http.StatusOK, codecValue{Name: "a"})
+
+			assert.Equal(t, []string{"Accept", "Cookie"}, webmux.VaryHeaders(w.Header()))
+
+			if tc.code != http.StatusOK {
+				var httpErr *webmux.HTTPError
+
+				assert.True(t, errors.As(err, &httpErr))
+				assert.Equal(t, tc.code, httpErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, w.Header().Get("Content-Type"))
+
+			r = httptest.NewRequest(http.MethodPost, "/", w.Body)
+			r.Header.Set("Content-Type", tc.want)
+
+			var got codecValue
+
+			assert.NoError(t, webmux.Decode(r, &got))
+			assert.Equal(t, "a", got.Name)
+		})
+	}
+}
+
+func TestCodecsDepth(t *testing.T) {
+	var tests = []struct {
+		name  string
+		codec webmux.Codec
+		array byte
+	}{
+		{"msgpack", webmux.MsgpackCodec{}, 0x91},
+		{"cbor", webmux.CBORCodec{}, 0x81},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			nested := func(depth int) []byte {
+				return append(bytes.Repeat([]byte{tc.array}, depth), tc.array&0xf0)
+			}
+
+			var v any
+
+			assert.NoError(t, tc.codec.Decode(bytes.NewReader(nested(webmux.DefaultMaxCodecDepth-1)), &v))
+
+			err := tc.codec.Decode(bytes.NewReader(nested(1<<20)), &v)
+
+			var httpErr *webmux.HTTPError
+
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
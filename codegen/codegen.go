@@ -0,0 +1,180 @@
+// Package codegen generates API clients and gateway configuration from the
+// routes registered with a webmux.ServeMux.
+//
+// Generators are run from a small program which builds the application's
+// mux and writes the generated source, typically invoked with go generate:
+//
+//	//go:generate go run ./cmd/gen-client
+package codegen
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"go.destructure.dev/webmux"
+)
+
+// Route is a route to generate client code for.
+type Route struct {
+	Name    string  // exported identifier derived from the method and pattern, like "GetUsersByID"
+	Method  string  // HTTP method
+	Pattern string  // URL pattern
+	Params  []Param // path parameters in the order they appear in Pattern
+	HasBody bool    // whether requests have a body
+}
+
+// Param is a path parameter of a route.
+type Param struct {
+	Name     string // name in the pattern, like "id"
+	Field    string // exported identifier, like "ID"
+	Segment  string // segment in the pattern, like ":id" or ":id(\d+)"
+	Wildcard bool   // whether the parameter may span multiple segments
+}
+
+// Routes returns the routes registered with mux to generate code for,
+// in a stable order. Routes registered for several patterns or with several
+// handlers for the same method are only returned once. Implicit HEAD and
+// OPTIONS routes are excluded.
+func Routes(mux *webmux.ServeMux) []Route {
+	routes := make([]Route, 0)
+	names := make(map[string]int)
+	seen := make(map[string]bool)
+
+	mux.Walk(func(method, pattern string, _ webmux.Handler) error {
+		key := method + " " + pattern
+
+		if seen[key] {
+			return nil
+		}
+
+		seen[key] = true
+
+		r := Route{
+			Method:  method,
+			Pattern: pattern,
+			Params:  patternParams(pattern),
+			HasBody: hasBody(method),
+		}
+
+		r.Name = routeName(method, r.Params, pattern)
+
+		// Disambiguate routes that map to the same identifier
+		if n := names[r.Name]; n > 0 {
+			names[r.Name]++
+			r.Name += strings.Repeat("_", n)
+		} else {
+			names[r.Name] = 1
+		}
+
+		routes = append(routes, r)
+
+		return nil
+	})
+
+	return routes
+}
+
+// hasBody reports whether requests with method conventionally have a body.
+func hasBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+
+	return true
+}
+
+// patternParams returns the parameters of pattern.
+func patternParams(pattern string) []Param {
+	params := make([]Param, 0)
+
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+
+		name := segment[1:]
+
+		// Strip any constraint, like "(\d+)" in ":id(\d+)"
+		if i := strings.IndexByte(name, '('); i >= 0 && segment[0] == ':' {
+			name = name[:i]
+		}
+
+		field := identifier(name)
+
+		if name == "" {
+			field = "Rest"
+		}
+
+		params = append(params, Param{Name: name, Field: field, Segment: segment, Wildcard: segment[0] == '*'})
+	}
+
+	return params
+}
+
+// routeName returns an identifier for the route with method and pattern, like
+// "GetUsersByID" for "GET /users/:id".
+func routeName(method string, params []Param, pattern string) string {
+	var b strings.Builder
+
+	b.WriteString(identifier(strings.ToLower(method)))
+
+	i := 0
+
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" {
+			continue
+		}
+
+		if segment[0] == ':' || segment[0] == '*' {
+			b.WriteString("By")
+			b.WriteString(params[i].Field)
+			i++
+
+			continue
+		}
+
+		b.WriteString(identifier(segment))
+	}
+
+	if b.Len() == len(method) {
+		b.WriteString("Root")
+	}
+
+	return b.String()
+}
+
+// commonInitialisms are written in upper case in identifiers, following Go conventions.
+var commonInitialisms = map[string]bool{
+	"API": true, "CSS": true, "HTML": true, "HTTP": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// identifier converts a path segment or parameter name to an exported Go identifier.
+func identifier(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+
+	for _, w := range words {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	id := b.String()
+
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "X" + id
+	}
+
+	return id
+}
@@ -0,0 +1,177 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"text/template"
+
+	"go.destructure.dev/webmux"
+)
+
+// GoClientOptions configures GoClient.
+type GoClientOptions struct {
+	// Package is the package name of the generated file. Defaults to "client".
+	Package string
+}
+
+// GoClient generates the source of a typed Go HTTP client for the routes
+// registered with mux.
+//
+// The client has a method per route taking the path parameters as a struct,
+// an optional request body, and a value to decode the JSON response into.
+// Error responses using the JSON error envelope {"error": {"code", "message"}}
+// are decoded into an *Error.
+func GoClient(mux *webmux.ServeMux, opts GoClientOptions) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "client"
+	}
+
+	var buf bytes.Buffer
+
+	err := goClientTemplate.Execute(&buf, struct {
+		Package string
+		Routes  []Route
+	}{opts.Package, Routes(mux)})
+
+	if err != nil {
+		return nil, fmt.Errorf("codegen: %w", err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+
+	if err != nil {
+		return nil, fmt.Errorf("codegen: format go client: %w", err)
+	}
+
+	return src, nil
+}
+
+var goClientTemplate = template.Must(template.New("client").Parse(`// Code generated by webmux codegen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is an HTTP client for the API.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a new Client sending requests to baseURL using httpClient.
+// If httpClient is nil, http.DefaultClient is used.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: httpClient}
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int    ` + "`json:\"-\"`" + `
+	Code       string ` + "`json:\"code\"`" + `
+	Message    string ` + "`json:\"message\"`" + `
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("api: %d %s", e.StatusCode, e.Message)
+}
+{{range .Routes}}{{if .Params}}
+// {{.Name}}Params are the path parameters of {{.Method}} {{.Pattern}}.
+type {{.Name}}Params struct {
+{{- range .Params}}
+	{{.Field}} string
+{{- end}}
+}
+{{end}}
+// {{.Name}} sends a request to {{.Method}} {{.Pattern}}.
+// If out is non-nil the JSON response body is decoded into it.
+func (c *Client) {{.Name}}(ctx context.Context{{if .Params}}, params {{.Name}}Params{{end}}{{if .HasBody}}, body any{{end}}, out any) error {
+	path := {{printf "%q" .Pattern}}
+{{- range .Params}}
+	path = strings.Replace(path, {{printf "%q" .Segment}}, {{if .Wildcard}}escapeWildcard(params.{{.Field}}){{else}}url.PathEscape(params.{{.Field}}){{end}}, 1)
+{{- end}}
+
+	return c.do(ctx, {{printf "%q" .Method}}, path, {{if .HasBody}}body{{else}}nil{{end}}, out)
+}
+{{end}}
+// do sends a request with a JSON body and decodes the JSON response.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, r)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var envelope struct {
+			Error Error ` + "`json:\"error\"`" + `
+		}
+
+		json.NewDecoder(resp.Body).Decode(&envelope)
+		envelope.Error.StatusCode = resp.StatusCode
+
+		return &envelope.Error
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// escapeWildcard escapes each segment of a wildcard parameter value.
+func escapeWildcard(v string) string {
+	segments := strings.Split(v, "/")
+
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
+`))
@@ -0,0 +1,54 @@
+package codegen_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/codegen"
+)
+
+func newTestMux() *webmux.ServeMux {
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", h)
+	mux.Handle(http.MethodPost, "/users", h)
+	mux.Handle(http.MethodGet, `/users/:id(\d+)`, h)
+	mux.Handle(http.MethodGet, "/files/*path", h)
+
+	return mux
+}
+
+func TestRoutes(t *testing.T) {
+	routes := codegen.Routes(newTestMux())
+
+	names := make([]string, 0, len(routes))
+
+	for _, r := range routes {
+		names = append(names, r.Name)
+	}
+
+	assert.Equal(t, []string{"GetFilesByPath", "GetUsers", "PostUsers", "GetUsersByID"}, names)
+}
+
+func TestGoClient(t *testing.T) {
+	src, err := codegen.GoClient(newTestMux(), codegen.GoClientOptions{Package: "api"})
+
+	assert.NoError(t, err)
+
+	for _, want := range []string{
+		"package api",
+		"type GetUsersByIDParams struct {\n\tID string\n}",
+		"func (c *Client) GetUsersByID(ctx context.Context, params GetUsersByIDParams, out any) error {",
+		"func (c *Client) PostUsers(ctx context.Context, body any, out any) error {",
+		`path = strings.Replace(path, "*path", escapeWildcard(params.Path), 1)`,
+		`path = strings.Replace(path, ":id(\\d+)", url.PathEscape(params.ID), 1)`,
+	} {
+		assert.True(t, strings.Contains(string(src), want), "missing %q", want)
+	}
+}
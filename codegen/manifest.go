@@ -0,0 +1,317 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.destructure.dev/webmux"
+)
+
+// ErrNoService is returned by the manifest generators when ManifestOptions.Service is empty.
+var ErrNoService = errors.New("codegen: no backend service")
+
+// Limits of the Gateway API on the matches of an HTTPRoute.
+const (
+	maxHTTPRouteMatches = 64
+	maxHTTPRouteRules   = 16
+)
+
+// ManifestOptions configures the route manifests generated for external
+// gateways by HTTPRoute, EnvoyRoutes, and Caddyfile.
+type ManifestOptions struct {
+	Name      string   // name of the HTTPRoute or Envoy route configuration, "webmux" if empty
+	Namespace string   // Kubernetes namespace of the HTTPRoute, if any
+	Gateway   string   // name of the parent Gateway of the HTTPRoute, if any
+	Hostnames []string // host names to route, any if empty
+	Service   string   // backend: Kubernetes service, Envoy cluster, or Caddy upstream
+	Port      int      // port of the Kubernetes service
+}
+
+// manifestRoute is a route of a manifest, matching a path for a set of methods.
+type manifestRoute struct {
+	match   string   // "Exact", "PathPrefix", or "RegularExpression"
+	path    string   // path, prefix, or anchored regular expression
+	methods []string // methods, including HEAD for GET
+}
+
+// manifestRoutes returns the routes registered with mux in the order of
+// [webmux.ServeMux.Routes], most specific first.
+func manifestRoutes(mux *webmux.ServeMux, opts *ManifestOptions) ([]manifestRoute, error) {
+	if opts.Service == "" {
+		return nil, ErrNoService
+	}
+
+	if opts.Name == "" {
+		opts.Name = "webmux"
+	}
+
+	routes := make([]manifestRoute, 0)
+
+	for _, info := range mux.Routes() {
+		r := patternMatch(info.Pattern)
+		r.methods = append(r.methods, info.Methods...)
+
+		if info.Methods.Has(http.MethodGet) && !info.Methods.Has(http.MethodHead) {
+			r.methods = append(r.methods, http.MethodHead)
+		}
+
+		routes = append(routes, r)
+	}
+
+	return routes, nil
+}
+
+// patternMatch returns the path match of pattern: an exact match for
+// patterns without parameters, a prefix match for patterns ending in an
+// unconstrained wildcard, and a regular expression otherwise.
+func patternMatch(pattern string) manifestRoute {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	static := true
+
+	for i, s := range segments {
+		if s != "" && (s[0] == ':' || s[0] == '*') {
+			if s[0] == '*' && i == len(segments)-1 && static {
+				return manifestRoute{match: "PathPrefix", path: strings.TrimSuffix(pattern, s)}
+			}
+
+			static = false
+		}
+	}
+
+	if static {
+		return manifestRoute{match: "Exact", path: pattern}
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('^')
+
+	for _, s := range segments {
+		b.WriteByte('/')
+
+		switch {
+		case s != "" && s[0] == '*':
+			b.WriteString(".+")
+		case s != "" && s[0] == ':':
+			if i := strings.IndexByte(s, '('); i >= 0 && strings.HasSuffix(s, ")") {
+				b.WriteString("(?:" + s[i+1:len(s)-1] + ")")
+			} else {
+				b.WriteString("[^/]+")
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(s))
+		}
+	}
+
+	b.WriteByte('$')
+
+	return manifestRoute{match: "RegularExpression", path: b.String()}
+}
+
+// HTTPRoute generates a Kubernetes Gateway API HTTPRoute in YAML, routing the
+// paths and methods of the routes registered with mux to opts.Service, so the
+// edge configuration stays in sync with the application.
+//
+// Routes are grouped into rules of at most 64 matches. An error is returned if
+// the routes exceed the 16 rules allowed in an HTTPRoute.
+func HTTPRoute(mux *webmux.ServeMux, opts ManifestOptions) ([]byte, error) {
+	routes, err := manifestRoutes(mux, &opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+
+	for _, r := range routes {
+		for _, method := range r.methods {
+			matches = append(matches, fmt.Sprintf("    - path:\n        type: %s\n        value: %s\n      method: %s\n", r.match, strconv.Quote(r.path), method))
+		}
+	}
+
+	if len(matches) > maxHTTPRouteMatches*maxHTTPRouteRules {
+		return nil, fmt.Errorf("codegen: %d route matches exceed the limit of an HTTPRoute", len(matches))
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString("apiVersion: gateway.networking.k8s.io/v1\nkind: HTTPRoute\nmetadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", strconv.Quote(opts.Name))
+
+	if opts.Namespace != "" {
+		fmt.Fprintf(&b, "  namespace: %s\n", strconv.Quote(opts.Namespace))
+	}
+
+	b.WriteString("spec:\n")
+
+	if opts.Gateway != "" {
+		fmt.Fprintf(&b, "  parentRefs:\n  - name: %s\n", strconv.Quote(opts.Gateway))
+	}
+
+	if len(opts.Hostnames) > 0 {
+		b.WriteString("  hostnames:\n")
+
+		for _, h := range opts.Hostnames {
+			fmt.Fprintf(&b, "  - %s\n", strconv.Quote(h))
+		}
+	}
+
+	b.WriteString("  rules:\n")
+
+	for len(matches) > 0 {
+		n := min(len(matches), maxHTTPRouteMatches)
+
+		b.WriteString("  - matches:\n")
+
+		for _, m := range matches[:n] {
+			b.WriteString(m)
+		}
+
+		fmt.Fprintf(&b, "    backendRefs:\n    - name: %s\n", strconv.Quote(opts.Service))
+
+		if opts.Port != 0 {
+			fmt.Fprintf(&b, "      port: %d\n", opts.Port)
+		}
+
+		matches = matches[n:]
+	}
+
+	return b.Bytes(), nil
+}
+
+// Envoy route configuration types, encoded as JSON.
+type (
+	envoyRouteConfig struct {
+		Name         string             `json:"name"`
+		VirtualHosts []envoyVirtualHost `json:"virtual_hosts"`
+	}
+
+	envoyVirtualHost struct {
+		Name    string       `json:"name"`
+		Domains []string     `json:"domains"`
+		Routes  []envoyRoute `json:"routes"`
+	}
+
+	envoyRoute struct {
+		Match envoyMatch  `json:"match"`
+		Route envoyAction `json:"route"`
+	}
+
+	envoyMatch struct {
+		Path      string               `json:"path,omitempty"`
+		Prefix    string               `json:"prefix,omitempty"`
+		SafeRegex *envoyRegex          `json:"safe_regex,omitempty"`
+		Headers   []envoyHeaderMatcher `json:"headers"`
+	}
+
+	envoyRegex struct {
+		Regex string `json:"regex"`
+	}
+
+	envoyHeaderMatcher struct {
+		Name        string           `json:"name"`
+		StringMatch envoyStringMatch `json:"string_match"`
+	}
+
+	envoyStringMatch struct {
+		SafeRegex envoyRegex `json:"safe_regex"`
+	}
+
+	envoyAction struct {
+		Cluster string `json:"cluster"`
+	}
+)
+
+// EnvoyRoutes generates an Envoy route configuration in JSON, routing the
+// paths and methods of the routes registered with mux to the cluster
+// opts.Service. Envoy uses the first matching route, so routes are ordered
+// from the most to the least specific.
+func EnvoyRoutes(mux *webmux.ServeMux, opts ManifestOptions) ([]byte, error) {
+	routes, err := manifestRoutes(mux, &opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	host := envoyVirtualHost{Name: opts.Name, Domains: opts.Hostnames, Routes: make([]envoyRoute, 0, len(routes))}
+
+	if len(host.Domains) == 0 {
+		host.Domains = []string{"*"}
+	}
+
+	for _, r := range routes {
+		route := envoyRoute{
+			Match: envoyMatch{
+				Headers: []envoyHeaderMatcher{{
+					Name:        ":method",
+					StringMatch: envoyStringMatch{SafeRegex: envoyRegex{Regex: strings.Join(r.methods, "|")}},
+				}},
+			},
+			Route: envoyAction{Cluster: opts.Service},
+		}
+
+		switch r.match {
+		case "Exact":
+			route.Match.Path = r.path
+		case "PathPrefix":
+			route.Match.Prefix = r.path
+		default:
+			// Envoy regular expressions match the whole path without anchors
+			route.Match.SafeRegex = &envoyRegex{Regex: strings.TrimSuffix(strings.TrimPrefix(r.path, "^"), "$")}
+		}
+
+		host.Routes = append(host.Routes, route)
+	}
+
+	return json.MarshalIndent(envoyRouteConfig{Name: opts.Name, VirtualHosts: []envoyVirtualHost{host}}, "", "  ")
+}
+
+// Caddyfile generates a Caddyfile snippet proxying the paths and methods of
+// the routes registered with mux to the upstream opts.Service, for inclusion
+// in a site block with the import directive.
+func Caddyfile(mux *webmux.ServeMux, opts ManifestOptions) ([]byte, error) {
+	routes, err := manifestRoutes(mux, &opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# Routes of %s, generated by webmux\n", opts.Name)
+
+	for i, r := range routes {
+		name := fmt.Sprintf("@%s_%d", opts.Name, i)
+
+		fmt.Fprintf(&b, "%s {\n\tmethod %s\n", name, strings.Join(r.methods, " "))
+
+		switch r.match {
+		case "Exact":
+			fmt.Fprintf(&b, "\tpath %s\n", caddyToken(r.path))
+		case "PathPrefix":
+			fmt.Fprintf(&b, "\tpath %s\n", caddyToken(r.path+"*"))
+		default:
+			fmt.Fprintf(&b, "\tpath_regexp %s\n", caddyToken(r.path))
+		}
+
+		fmt.Fprintf(&b, "}\nreverse_proxy %s %s\n", name, caddyToken(opts.Service))
+	}
+
+	return b.Bytes(), nil
+}
+
+// caddyToken returns s as a Caddyfile token, quoted with backticks if it
+// contains whitespace or quotes.
+func caddyToken(s string) string {
+	if strings.ContainsAny(s, " \t\n\"`") {
+		return "`" + strings.ReplaceAll(s, "`", "") + "`"
+	}
+
+	return s
+}
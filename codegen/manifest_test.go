@@ -0,0 +1,96 @@
+package codegen_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux/codegen"
+)
+
+var manifestOptions = codegen.ManifestOptions{
+	Name:      "api",
+	Namespace: "prod",
+	Gateway:   "public",
+	Hostnames: []string{"api.example.com"},
+	Service:   "api",
+	Port:      8080,
+}
+
+func TestHTTPRoute(t *testing.T) {
+	src, err := codegen.HTTPRoute(newTestMux(), manifestOptions)
+
+	assert.NoError(t, err)
+
+	for _, want := range []string{
+		"kind: HTTPRoute\nmetadata:\n  name: \"api\"\n  namespace: \"prod\"\nspec:\n  parentRefs:\n  - name: \"public\"\n  hostnames:\n  - \"api.example.com\"\n",
+		"    - path:\n        type: PathPrefix\n        value: \"/files/\"\n      method: GET\n",
+		"    - path:\n        type: Exact\n        value: \"/users\"\n      method: POST\n",
+		"    - path:\n        type: RegularExpression\n        value: \"^/users/(?:\\\\d+)$\"\n      method: HEAD\n",
+		"    backendRefs:\n    - name: \"api\"\n      port: 8080\n",
+	} {
+		assert.True(t, strings.Contains(string(src), want), "missing %q in\n%s", want, src)
+	}
+
+	_, err = codegen.HTTPRoute(newTestMux(), codegen.ManifestOptions{})
+
+	assert.IsError(t, err, codegen.ErrNoService)
+}
+
+func TestEnvoyRoutes(t *testing.T) {
+	src, err := codegen.EnvoyRoutes(newTestMux(), manifestOptions)
+
+	assert.NoError(t, err)
+
+	var config struct {
+		VirtualHosts []struct {
+			Domains []string `json:"domains"`
+			Routes  []struct {
+				Match struct {
+					Path      string `json:"path"`
+					Prefix    string `json:"prefix"`
+					SafeRegex struct {
+						Regex string `json:"regex"`
+					} `json:"safe_regex"`
+					Headers []struct {
+						StringMatch struct {
+							SafeRegex struct {
+								Regex string `json:"regex"`
+							} `json:"safe_regex"`
+						} `json:"string_match"`
+					} `json:"headers"`
+				} `json:"match"`
+				Route struct {
+					Cluster string `json:"cluster"`
+				} `json:"route"`
+			} `json:"routes"`
+		} `json:"virtual_hosts"`
+	}
+
+	assert.NoError(t, json.Unmarshal(src, &config))
+
+	host := config.VirtualHosts[0]
+
+	assert.Equal(t, []string{"api.example.com"}, host.Domains)
+	assert.Equal(t, 3, len(host.Routes))
+	assert.Equal(t, "/files/", host.Routes[0].Match.Prefix)
+	assert.Equal(t, "/users", host.Routes[1].Match.Path)
+	assert.Equal(t, "GET|POST|HEAD", host.Routes[1].Match.Headers[0].StringMatch.SafeRegex.Regex)
+	assert.Equal(t, `/users/(?:\d+)`, host.Routes[2].Match.SafeRegex.Regex)
+	assert.Equal(t, "api", host.Routes[2].Route.Cluster)
+}
+
+func TestCaddyfile(t *testing.T) {
+	src, err := codegen.Caddyfile(newTestMux(), manifestOptions)
+
+	assert.NoError(t, err)
+
+	for _, want := range []string{
+		"@api_0 {\n\tmethod GET HEAD\n\tpath /files/*\n}\nreverse_proxy @api_0 api\n",
+		"@api_1 {\n\tmethod GET POST HEAD\n\tpath /users\n}\n",
+		"@api_2 {\n\tmethod GET HEAD\n\tpath_regexp ^/users/(?:\\d+)$\n}\n",
+	} {
+		assert.True(t, strings.Contains(string(src), want), "missing %q in\n%s", want, src)
+	}
+}
@@ -0,0 +1,319 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.destructure.dev/webmux"
+)
+
+// RouteTypes are the Go types of the request and response bodies of a route.
+// They are given as values, such as User{}, and may be nil if the route has
+// no body or the body is untyped.
+type RouteTypes struct {
+	Request  any
+	Response any
+}
+
+// TypeScriptOptions configures TypeScript.
+type TypeScriptOptions struct {
+	// Types maps routes, formatted as "METHOD pattern" with the pattern as
+	// registered, like `GET /users/:id(\d+)`, to their body types.
+	Types map[string]RouteTypes
+}
+
+// TypeScript generates a TypeScript module for the routes registered with mux.
+//
+// The module exports a path builder per route in the paths object, and a
+// fetch wrapper per route. Go types given in opts.Types are converted to
+// TypeScript interfaces using their JSON representation, so that frontend
+// code is type checked against the backend's bindings. An error is returned
+// if opts.Types has a key matching no route, which is likely a stale pattern.
+func TypeScript(mux *webmux.ServeMux, opts TypeScriptOptions) ([]byte, error) {
+	g := &tsGenerator{decls: make(map[string]string)}
+
+	type tsRoute struct {
+		Route
+		Func     string
+		Request  string
+		Response string
+	}
+
+	routes := make([]tsRoute, 0)
+	keys := make(map[string]bool)
+
+	for _, r := range Routes(mux) {
+		key := r.Method + " " + r.Pattern
+		types := opts.Types[key]
+		keys[key] = true
+
+		tr := tsRoute{
+			Route:    r,
+			Func:     lowerFirst(r.Name),
+			Request:  "unknown",
+			Response: "unknown",
+		}
+
+		if types.Request != nil {
+			tr.Request = g.typeOf(reflect.TypeOf(types.Request))
+		}
+
+		if types.Response != nil {
+			tr.Response = g.typeOf(reflect.TypeOf(types.Response))
+		}
+
+		routes = append(routes, tr)
+	}
+
+	for key := range opts.Types {
+		if !keys[key] {
+			return nil, fmt.Errorf("codegen: no route for types of %q", key)
+		}
+	}
+
+	names := make([]string, 0, len(g.decls))
+
+	for name := range g.decls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	decls := make([]string, 0, len(names))
+
+	for _, name := range names {
+		decls = append(decls, g.decls[name])
+	}
+
+	var buf bytes.Buffer
+
+	err := tsTemplate.Execute(&buf, struct {
+		Decls  []string
+		Routes []tsRoute
+	}{decls, routes})
+
+	if err != nil {
+		return nil, fmt.Errorf("codegen: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// tsGenerator converts Go types to TypeScript types.
+type tsGenerator struct {
+	decls map[string]string // interface name to declaration
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeOf returns the TypeScript type for t, declaring interfaces for named structs.
+func (g *tsGenerator) typeOf(t reflect.Type) string {
+	if t == timeType {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeOf(t.Elem()) + " | null"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+
+		elem := g.typeOf(t.Elem())
+
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<string, " + g.typeOf(t.Elem()) + ">"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structBody(t, "")
+		}
+
+		name := t.Name()
+
+		if _, ok := g.decls[name]; !ok {
+			g.decls[name] = "" // reserve the name to terminate recursive types
+			g.decls[name] = "export interface " + name + " " + g.structBody(t, "") + "\n"
+		}
+
+		return name
+	}
+
+	return "unknown"
+}
+
+// structBody returns the TypeScript object type for the struct t.
+func (g *tsGenerator) structBody(t reflect.Type, indent string) string {
+	var b strings.Builder
+
+	b.WriteString("{\n")
+	g.writeFields(&b, t, indent+"  ")
+	b.WriteString(indent + "}")
+
+	return b.String()
+}
+
+// writeFields writes the JSON fields of the struct t to b, flattening embedded structs.
+func (g *tsGenerator) writeFields(b *strings.Builder, t reflect.Type, indent string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				g.writeFields(b, ft, indent)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		optional := ""
+
+		if strings.Contains(options, "omitempty") {
+			optional = "?"
+		}
+
+		fmt.Fprintf(b, "%s%s%s: %s;\n", indent, name, optional, g.typeOf(f.Type))
+	}
+}
+
+// lowerFirst returns s with the first letter in lower case.
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+// tsPath returns a TypeScript template literal building the path of pattern.
+func tsPath(r Route) string {
+	var b strings.Builder
+
+	b.WriteByte('`')
+
+	i := 0
+
+	for _, segment := range strings.Split(r.Pattern, "/") {
+		if segment == "" {
+			continue
+		}
+
+		b.WriteByte('/')
+
+		if segment[0] == ':' || segment[0] == '*' {
+			p := r.Params[i]
+			i++
+
+			if p.Wildcard {
+				fmt.Fprintf(&b, "${params.%s.split(\"/\").map(encodeURIComponent).join(\"/\")}", p.Field)
+			} else {
+				fmt.Fprintf(&b, "${encodeURIComponent(params.%s)}", p.Field)
+			}
+
+			continue
+		}
+
+		b.WriteString(segment)
+	}
+
+	if i == 0 && !strings.Contains(b.String(), "/") {
+		b.WriteByte('/')
+	}
+
+	b.WriteByte('`')
+
+	return b.String()
+}
+
+var tsTemplate = template.Must(template.New("ts").Funcs(template.FuncMap{"path": tsPath}).Parse(`// Code generated by webmux codegen. DO NOT EDIT.
+
+{{range .Decls}}{{.}}
+{{end -}}
+export interface ApiError {
+  code: string;
+  message: string;
+}
+
+export class ApiRequestError extends Error {
+  constructor(public status: number, public error: ApiError) {
+    super(error.message || ` + "`${status}`" + `);
+  }
+}
+
+let baseURL = "";
+
+export function setBaseURL(url: string): void {
+  baseURL = url.replace(/\/$/, "");
+}
+
+export const paths = {
+{{- range .Routes}}
+  {{.Func}}: ({{if .Params}}params: { {{range .Params}}{{.Field}}: string; {{end}}}{{end}}): string => {{path .Route}},
+{{- end}}
+};
+{{range .Routes}}
+export function {{.Func}}({{if .Params}}params: { {{range .Params}}{{.Field}}: string; {{end}}}, {{end}}{{if .HasBody}}body: {{.Request}}, {{end}}init?: RequestInit): Promise<{{.Response}}> {
+  return request({{printf "%q" .Method}}, paths.{{.Func}}({{if .Params}}params{{end}}), {{if .HasBody}}body{{else}}undefined{{end}}, init);
+}
+{{end}}
+async function request<T>(method: string, path: string, body: unknown, init?: RequestInit): Promise<T> {
+  const headers = new Headers(init?.headers);
+  headers.set("Accept", "application/json");
+
+  if (body !== undefined) {
+    headers.set("Content-Type", "application/json");
+  }
+
+  const resp = await fetch(baseURL + path, {
+    ...init,
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  if (!resp.ok) {
+    const envelope = await resp.json().catch(() => ({}));
+    throw new ApiRequestError(resp.status, envelope.error ?? { code: "", message: resp.statusText });
+  }
+
+  if (resp.status === 204) {
+    return undefined as T;
+  }
+
+  return resp.json() as Promise<T>;
+}
+`))
@@ -0,0 +1,49 @@
+package codegen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux/codegen"
+)
+
+type tsUser struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Email   *string   `json:"email,omitempty"`
+	Friends []*tsUser `json:"friends"`
+	secret  string
+}
+
+func TestTypeScript(t *testing.T) {
+	src, err := codegen.TypeScript(newTestMux(), codegen.TypeScriptOptions{
+		Types: map[string]codegen.RouteTypes{
+			`GET /users/:id(\d+)`: {Response: tsUser{}},
+			"POST /users":         {Request: tsUser{}, Response: tsUser{}},
+		},
+	})
+
+	assert.NoError(t, err)
+
+	for _, want := range []string{
+		"export interface tsUser {\n  id: number;\n  name: string;\n  email?: string | null;\n  friends: (tsUser | null)[];\n}",
+		"getUsersByID: (params: { ID: string; }): string => `/users/${encodeURIComponent(params.ID)}`,",
+		"getFilesByPath: (params: { Path: string; }): string => `/files/${params.Path.split(\"/\").map(encodeURIComponent).join(\"/\")}`,",
+		"export function postUsers(body: tsUser, init?: RequestInit): Promise<tsUser> {",
+		"export function getUsersByID(params: { ID: string; }, init?: RequestInit): Promise<tsUser> {",
+		"export function getUsers(init?: RequestInit): Promise<unknown> {",
+	} {
+		assert.True(t, strings.Contains(string(src), want), "missing %q in\n%s", want, src)
+	}
+}
+
+func TestTypeScriptUnknownRoute(t *testing.T) {
+	_, err := codegen.TypeScript(newTestMux(), codegen.TypeScriptOptions{
+		Types: map[string]codegen.RouteTypes{
+			"GET /users/:id": {Response: tsUser{}},
+		},
+	})
+
+	assert.EqualError(t, err, `codegen: no route for types of "GET /users/:id"`)
+}
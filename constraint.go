@@ -0,0 +1,77 @@
+package webmux
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// constrainedNode is a child node for a named group whose value must match a
+// regular expression, like ":id(\d+)".
+type constrainedNode struct {
+	expr string         // the constraint as written in the pattern
+	re   *regexp.Regexp // the compiled constraint, anchored to the whole segment
+	node *node
+}
+
+// splitParam splits a named group segment like ":id(\d+)" into its name and
+// constraint, without the leading colon. The constraint is empty if there is none.
+func splitParam(segment string) (name, expr string) {
+	name = segment[1:]
+	i := strings.IndexByte(name, '(')
+
+	if i < 0 {
+		return name, ""
+	}
+
+	if !strings.HasSuffix(name, ")") {
+		panic(fmt.Sprintf("webmux: invalid constraint in %s", segment))
+	}
+
+	return name[:i], name[i+1 : len(name)-1]
+}
+
+// paramName returns the name of the parameter of segment, without any constraint.
+func paramName(segment string) string {
+	if segment[0] == ':' {
+		name, _ := splitParam(segment)
+		return name
+	}
+
+	return segment[1:]
+}
+
+// constrainedChild returns the child of n for the constraint expr, adding it if add is true.
+func (n *node) constrainedChild(expr string, add bool) *node {
+	for _, c := range n.constrained {
+		if c.expr == expr {
+			return c.node
+		}
+	}
+
+	if !add {
+		return nil
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+
+	if err != nil {
+		panic(fmt.Sprintf("webmux: invalid constraint %q: %s", expr, err))
+	}
+
+	c := &constrainedNode{expr: expr, re: re, node: &node{}}
+	n.constrained = append(n.constrained, c)
+
+	return c.node
+}
+
+// matchConstrained returns the first child of n whose constraint matches segment.
+func (n *node) matchConstrained(segment string) (*node, bool) {
+	for _, c := range n.constrained {
+		if c.re.MatchString(segment) {
+			return c.node, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,213 @@
+// Package cors provides a middleware handling Cross-Origin Resource Sharing
+// for a [webmux.ServeMux], deriving the methods allowed by preflight
+// responses from the routes of the mux:
+//
+//	mux := webmux.New()
+//	cors.Apply(mux, cors.Options{
+//		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.dev"},
+//		AllowCredentials: true,
+//		MaxAge:           time.Hour,
+//	})
+//
+// A preflight request for "/users/1" is allowed the methods of the route
+// matching it, like "OPTIONS, GET, HEAD, DELETE", without listing them in
+// the configuration.
+package cors
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.destructure.dev/webmux"
+)
+
+// Options configures Middleware.
+type Options struct {
+	// AllowedOrigins are the origins allowed to make requests, like
+	// "https://app.example.com". An origin may contain a single "*" to
+	// match any subdomain, as in "https://*.example.com", and "*" allows
+	// any origin. Origins are compared case-insensitively.
+	AllowedOrigins []string
+
+	// AllowOrigin is called for origins not in AllowedOrigins, allowing the
+	// origin if it returns true.
+	AllowOrigin func(r *http.Request, origin string) bool
+
+	// AllowedHeaders are the request headers allowed in requests. If nil,
+	// the headers requested by preflight requests are allowed.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers, besides the CORS-safelisted
+	// ones, that scripts may read.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with cookies and HTTP authentication.
+	// The origin of the request is then sent instead of "*", since browsers
+	// reject credentialed responses allowing any origin.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache the result of a preflight
+	// request. Zero lets browsers use their default of a few seconds.
+	MaxAge time.Duration
+}
+
+// Apply adds Middleware configured with opts to mux. Since mux answers
+// OPTIONS requests without running middleware unless it has an options
+// handler, Apply sets an options handler responding with 204 No Content,
+// which replaces any options handler set before.
+func Apply(mux *webmux.ServeMux, opts Options) {
+	mux.Use(Middleware(opts))
+	mux.SetOptionsHandler(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}))
+}
+
+// Middleware returns a middleware handling CORS requests with opts. It must
+// be added with [webmux.ServeMux.Use] and the mux must have an options
+// handler, see Apply.
+//
+// Preflight requests, OPTIONS requests with an Access-Control-Request-Method
+// header, are answered by the middleware with 204 No Content. The requested
+// method must be handled by the matched route, and the allowed methods are
+// those of the route, see [webmux.MuxMatch.Allow]. Preflight requests from
+// an origin that is not allowed, or for a method the route does not handle,
+// are answered without CORS headers, so browsers block the actual request.
+// Other requests from an allowed origin are handled with the CORS headers
+// set on the response.
+func Middleware(opts Options) webmux.Middleware {
+	c := &policy{opts: opts}
+
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(origin)
+
+		switch {
+		case origin == "*":
+			c.any = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		default:
+			c.origins = append(c.origins, origin)
+		}
+	}
+
+	if opts.AllowedHeaders != nil {
+		c.allowedHeaders = strings.Join(opts.AllowedHeaders, ", ")
+	}
+
+	c.exposedHeaders = strings.Join(opts.ExposedHeaders, ", ")
+
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge / time.Second))
+	}
+
+	return c.middleware
+}
+
+// policy is the compiled form of Options.
+type policy struct {
+	opts           Options
+	any            bool        // any origin is allowed
+	origins        []string    // allowed origins, lowercase
+	wildcards      [][2]string // prefixes and suffixes of allowed origins with a "*"
+	allowedHeaders string      // empty if the requested headers are allowed
+	exposedHeaders string
+	maxAge         string // preflight max age in seconds, empty if unset
+}
+
+func (c *policy) middleware(next webmux.Handler) webmux.Handler {
+	return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if preflight {
+			webmux.AddVary(w.Header(), "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+		} else if !c.any || c.opts.AllowCredentials {
+			webmux.AddVary(w.Header(), "Origin")
+		}
+
+		if origin == "" {
+			return next.ServeHTTPErr(w, r)
+		}
+
+		allowed := c.allowed(r, origin)
+
+		if !preflight {
+			if allowed {
+				c.setOrigin(w.Header(), origin)
+
+				if c.exposedHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", c.exposedHeaders)
+				}
+			}
+
+			return next.ServeHTTPErr(w, r)
+		}
+
+		match, ok := webmux.FromContext(r.Context())
+		method := r.Header.Get("Access-Control-Request-Method")
+
+		if allowed && ok && slices.Contains(strings.Split(match.Allow(), ", "), method) {
+			c.setOrigin(w.Header(), origin)
+			w.Header().Set("Access-Control-Allow-Methods", match.Allow())
+
+			if headers := c.allowedHeaders; headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			} else if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+
+			if c.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", c.maxAge)
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+		return nil
+	})
+}
+
+// allowed returns true if requests from origin are allowed.
+func (c *policy) allowed(r *http.Request, origin string) bool {
+	if c.any {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+
+	if slices.Contains(c.origins, lower) {
+		return true
+	}
+
+	for _, w := range c.wildcards {
+		if len(lower) <= len(w[0])+len(w[1]) || !strings.HasPrefix(lower, w[0]) || !strings.HasSuffix(lower, w[1]) {
+			continue
+		}
+
+		// The wildcard matches subdomains, not another scheme or port
+		if !strings.ContainsAny(lower[len(w[0]):len(lower)-len(w[1])], "/:") {
+			return true
+		}
+	}
+
+	return c.opts.AllowOrigin != nil && c.opts.AllowOrigin(r, origin)
+}
+
+// setOrigin sets the headers allowing origin.
+func (c *policy) setOrigin(h http.Header, origin string) {
+	if c.any && !c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+
+	if c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
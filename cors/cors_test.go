@@ -0,0 +1,105 @@
+package cors_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/cors"
+)
+
+func TestApply(t *testing.T) {
+	mux := webmux.New()
+	cors.Apply(mux, cors.Options{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.dev"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+	mux.HandleMethodsFunc(webmux.Methods(http.MethodGet, http.MethodDelete), "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("ok"))
+		return nil
+	})
+
+	preflight := func(origin, method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		r.Header.Set("Access-Control-Request-Headers", "content-type")
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := preflight("https://app.example.com", http.MethodDelete)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "OPTIONS, GET, HEAD, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+
+	w = preflight("https://pr-12.example.dev", http.MethodGet)
+	assert.Equal(t, "https://pr-12.example.dev", w.Header().Get("Access-Control-Allow-Origin"))
+
+	for _, tt := range []struct{ origin, method string }{
+		{"https://evil.example.com", http.MethodGet},
+		{"https://example.dev", http.MethodGet},
+		{"https://evil.com:443/.example.dev", http.MethodGet},
+		{"https://app.example.com", http.MethodPut},
+	} {
+		w = preflight(tt.origin, tt.method)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"), tt.origin)
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Methods"), tt.origin)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Origin", "https://APP.example.com")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "https://APP.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	// A plain OPTIONS request is answered by the options handler
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users/1", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "OPTIONS, GET, HEAD, DELETE", w.Header().Get("Allow"))
+}
+
+func TestMiddlewareAnyOrigin(t *testing.T) {
+	mux := webmux.New()
+	cors.Apply(mux, cors.Options{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"Content-Type", "Authorization"}})
+	mux.HandleFunc(http.MethodPost, "/events", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodOptions, "/events", nil)
+	r.Header.Set("Origin", "https://anywhere.test")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	r = httptest.NewRequest(http.MethodPost, "/events", nil)
+	r.Header.Set("Origin", "https://anywhere.test")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "", w.Header().Get("Vary"))
+}
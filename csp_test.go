@@ -0,0 +1,46 @@
+package webmux_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestContentSecurityPolicy(t *testing.T) {
+	mux := webmux.New()
+	mux.Use(webmux.ContentSecurityPolicy("script-src 'nonce-{nonce}'; style-src 'nonce-{nonce}'"))
+	mux.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, webmux.CSPNonce(r.Context()))
+		return err
+	})
+
+	nonces := make(map[string]bool)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		nonce := w.Body.String()
+
+		assert.Equal(t, 24, len(nonce))
+		assert.Equal(t, "script-src 'nonce-"+nonce+"'; style-src 'nonce-"+nonce+"'", w.Header().Get("Content-Security-Policy"))
+
+		nonces[nonce] = true
+	}
+
+	assert.Equal(t, 2, len(nonces))
+	assert.Equal(t, "", webmux.CSPNonce(context.Background()))
+
+	// Outside of a mux, the nonce is stored in the context
+	h := webmux.ContentSecurityPolicy("script-src 'nonce-{nonce}'")(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		assert.NotEqual(t, "", webmux.CSPNonce(r.Context()))
+		return nil
+	}))
+
+	assert.NoError(t, h.ServeHTTPErr(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)))
+}
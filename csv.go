@@ -0,0 +1,154 @@
+package webmux
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"mime"
+	"net/http"
+)
+
+// defaultCSVFlushRows is the number of rows buffered between flushes by default.
+const defaultCSVFlushRows = 64
+
+// utf8BOM is the UTF-8 byte order mark, which Excel requires to detect UTF-8 CSV files.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// CSVWriter streams rows to a client as CSV.
+// Configure the writer by setting its fields before the first call to Write.
+//
+// Rows are flushed to the client in batches of FlushRows. Because flushing
+// blocks until the client has accepted the data, a slow client slows down the
+// producer rather than causing the response to be buffered in memory.
+type CSVWriter struct {
+	// Header is written as the first row, if non-empty.
+	Header []string
+
+	// Filename, if set, is sent in a Content-Disposition header so that
+	// browsers download the response as an attachment.
+	Filename string
+
+	// BOM writes a UTF-8 byte order mark before the first row, which is
+	// required for Excel to correctly open files containing non-ASCII text.
+	BOM bool
+
+	// FlushRows is the number of rows written between flushes.
+	// If zero, a default of 64 rows is used.
+	FlushRows int
+
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	cw      *csv.Writer
+	ctx     context.Context
+	started bool
+	pending int
+}
+
+// CSV returns a new CSVWriter writing to w.
+// The response Content-Type is set to "text/csv; charset=utf-8" unless already set.
+func CSV(w http.ResponseWriter) *CSVWriter {
+	return &CSVWriter{
+		w:   w,
+		rc:  http.NewResponseController(w),
+		cw:  csv.NewWriter(w),
+		ctx: context.Background(),
+	}
+}
+
+// WithContext returns a shallow copy of c that stops writing once ctx is done.
+func (c *CSVWriter) WithContext(ctx context.Context) *CSVWriter {
+	if ctx == nil {
+		panic("webmux: nil context")
+	}
+
+	c2 := *c
+	c2.ctx = ctx
+
+	return &c2
+}
+
+// Write writes a single CSV record.
+// Write returns the context error without writing if the context is done.
+func (c *CSVWriter) Write(record []string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	if !c.started {
+		if err := c.start(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.cw.Write(record); err != nil {
+		return err
+	}
+
+	c.pending++
+
+	flushRows := c.FlushRows
+
+	if flushRows <= 0 {
+		flushRows = defaultCSVFlushRows
+	}
+
+	if c.pending >= flushRows {
+		return c.Flush()
+	}
+
+	return nil
+}
+
+// Flush writes any buffered rows to the client.
+// Flush must be called after the last row has been written.
+func (c *CSVWriter) Flush() error {
+	if !c.started {
+		if err := c.start(); err != nil {
+			return err
+		}
+	}
+
+	c.pending = 0
+	c.cw.Flush()
+
+	if err := c.cw.Error(); err != nil {
+		return err
+	}
+
+	err := c.rc.Flush()
+
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+
+	return err
+}
+
+// start writes the response headers, byte order mark, and header row.
+func (c *CSVWriter) start() error {
+	c.started = true
+
+	h := c.w.Header()
+
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", "text/csv; charset=utf-8")
+	}
+
+	if c.Filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": c.Filename}))
+	}
+
+	c.w.WriteHeader(http.StatusOK)
+
+	if c.BOM {
+		if _, err := c.w.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Header) > 0 {
+		return c.cw.Write(c.Header)
+	}
+
+	return nil
+}
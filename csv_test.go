@@ -0,0 +1,79 @@
+package webmux_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	c := webmux.CSV(w)
+	c.Header = []string{"name", "note"}
+	c.Filename = "users.csv"
+	c.BOM = true
+
+	assert.NoError(t, c.Write([]string{"ada", `says "hi", twice`}))
+	assert.NoError(t, c.Write([]string{"grace", "line\nbreak"}))
+	assert.NoError(t, c.Flush())
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=users.csv`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "\xef\xbb\xbfname,note\nada,\"says \"\"hi\"\", twice\"\ngrace,\"line\nbreak\"\n", w.Body.String())
+}
+
+func TestCSVEmpty(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "text/plain")
+
+	c := webmux.CSV(w)
+	c.Header = []string{"name"}
+
+	assert.NoError(t, c.Flush())
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "name\n", w.Body.String())
+}
+
+func TestCSVStreaming(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	c := webmux.CSV(w)
+	c.FlushRows = 2
+
+	assert.NoError(t, c.Write([]string{"1"}))
+	assert.Equal(t, "", w.Body.String())
+	assert.False(t, w.Flushed)
+
+	assert.NoError(t, c.Write([]string{"2"}))
+	assert.Equal(t, "1\n2\n", w.Body.String())
+	assert.True(t, w.Flushed)
+
+	assert.NoError(t, c.Write([]string{"3"}))
+	assert.Equal(t, "1\n2\n", w.Body.String())
+
+	assert.NoError(t, c.Flush())
+	assert.Equal(t, "1\n2\n3\n", w.Body.String())
+}
+
+func TestCSVContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := httptest.NewRecorder()
+	c := webmux.CSV(w).WithContext(ctx)
+
+	assert.NoError(t, c.Write([]string{"1"}))
+
+	cancel()
+
+	assert.IsError(t, c.Write([]string{"2"}), context.Canceled)
+	assert.NoError(t, c.Flush())
+	assert.Equal(t, "1\n", w.Body.String())
+}
@@ -0,0 +1,217 @@
+package webmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ErrBodyTooLarge is wrapped by the errors of request bodies exceeding a
+// size limit, like that of DecodeJSON.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// Default limits of DecodeJSON.
+const (
+	DefaultMaxJSONBytes = 1 << 20
+	DefaultMaxJSONDepth = 32
+)
+
+// DecodeJSONOptions configures DecodeJSON.
+type DecodeJSONOptions struct {
+	// MaxBytes limits the size of the body. If zero, DefaultMaxJSONBytes is
+	// used.
+	MaxBytes int64
+
+	// MaxDepth limits the nesting of arrays and objects. If zero,
+	// DefaultMaxJSONDepth is used.
+	MaxDepth int
+
+	// AllowUnknownFields accepts object properties without a matching
+	// struct field, which are rejected by default.
+	AllowUnknownFields bool
+}
+
+// DecodeJSON decodes the JSON body of r into dst, enforcing the limits of
+// opts, so handlers taking JSON input reject oversized, deeply nested and
+// misspelled input the same way. A nil opts uses the defaults.
+//
+// The body must be a single JSON value. Bodies larger than the limit are
+// rejected with a 413 Request Entity Too Large [HTTPError] wrapping
+// ErrBodyTooLarge, and bodies with a Content-Type other than JSON with a
+// 415 Unsupported Media Type. Other invalid bodies are rejected with a 400
+// Bad Request wrapping ErrInvalidBody and a *FieldError, which
+// JSONErrorHandler lists in the fields of the error. The field is the path
+// of the invalid value, like "$.address.city", and the message includes
+// the byte offset of the error in the body:
+//
+//	{"field":"$.age","message":"expected integer, got string at offset 11"}
+func DecodeJSON(r *http.Request, dst any, opts *DecodeJSONOptions) error {
+	var o DecodeJSONOptions
+
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultMaxJSONBytes
+	}
+
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = DefaultMaxJSONDepth
+	}
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return NewHTTPError(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+	}
+
+	var body []byte
+
+	if r.Body != nil {
+		var err error
+
+		body, err = io.ReadAll(io.LimitReader(r.Body, o.MaxBytes+1))
+
+		if err != nil {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+
+	if int64(len(body)) > o.MaxBytes {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, o.MaxBytes))
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return invalidJSON("$", "missing body")
+	}
+
+	if offset, ok := exceedsDepth(body, o.MaxDepth); ok {
+		return invalidJSON("$", fmt.Sprintf("nesting exceeds depth %d at offset %d", o.MaxDepth, offset))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+
+	if !o.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeJSONError(err, len(body))
+	}
+
+	if rest := bytes.TrimLeft(body[dec.InputOffset():], " \t\r\n"); len(rest) > 0 {
+		return invalidJSON("$", fmt.Sprintf("unexpected data after JSON value at offset %d", len(body)-len(rest)))
+	}
+
+	return nil
+}
+
+// invalidJSON returns the error of DecodeJSON for an invalid value at field.
+func invalidJSON(field, message string) error {
+	return NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidBody, &FieldError{Field: field, Message: message}))
+}
+
+// decodeJSONError returns the error of DecodeJSON for the decoding error err
+// of a body of n bytes.
+func decodeJSONError(err error, n int) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var invalidErr *json.InvalidUnmarshalError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return invalidJSON("$", fmt.Sprintf("%s at offset %d", syntaxErr.Error(), syntaxErr.Offset))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidJSON("$", fmt.Sprintf("unexpected end of JSON at offset %d", n))
+	case errors.As(err, &typeErr):
+		field := "$"
+
+		if typeErr.Field != "" {
+			field += "." + typeErr.Field
+		}
+
+		return invalidJSON(field, fmt.Sprintf("expected %s, got %s at offset %d", jsonKind(typeErr.Type), typeErr.Value, typeErr.Offset))
+	case errors.As(err, &invalidErr):
+		// The destination is not a pointer, a bug of the handler
+		return err
+	}
+
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return invalidJSON("$", "unknown property "+name)
+	}
+
+	return NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidBody, err))
+}
+
+// jsonKind returns the JSON Schema type of the values decoded into t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	}
+
+	return t.String()
+}
+
+// isJSONContentType reports whether the Content-Type ct is JSON, or is
+// missing, in which case the body is assumed to be JSON.
+func isJSONContentType(ct string) bool {
+	if ct == "" {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(ct)
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// exceedsDepth returns the offset of the first array or object of data
+// nested deeper than limit, if any.
+func exceedsDepth(data []byte, limit int) (int, bool) {
+	depth := 0
+	inString, escaped := false, false
+
+	for i, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			depth++
+
+			if depth > limit {
+				return i, true
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+
+	return 0, false
+}
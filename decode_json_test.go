@@ -0,0 +1,82 @@
+package webmux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+
+	type user struct {
+		Name    string  `json:"name"`
+		Age     int     `json:"age"`
+		Address address `json:"address"`
+		Tags    []any   `json:"tags"`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        *webmux.DecodeJSONOptions
+		code        int
+		field       string
+		message     string
+	}{
+		{"valid", "application/json", `{"name":"Ann","age":30,"address":{"city":"Oslo"}}`, nil, 0, "", ""},
+		{"no content type", "", `{"name":"Ann"}`, nil, 0, "", ""},
+		{"json suffix", "application/merge-patch+json", `{"name":"Ann"}`, nil, 0, "", ""},
+		{"unsupported", "text/plain", `{}`, nil, http.StatusUnsupportedMediaType, "", ""},
+		{"too large", "application/json", `{"name":"Ann"}`, &webmux.DecodeJSONOptions{MaxBytes: 8}, http.StatusRequestEntityTooLarge, "", ""},
+		{"empty", "application/json", ` `, nil, http.StatusBadRequest, "$", "missing body"},
+		{"syntax", "application/json", `{"name":}`, nil, http.StatusBadRequest, "$", "invalid character '}' looking for beginning of value at offset 9"},
+		{"truncated", "application/json", `{"name":"Ann"`, nil, http.StatusBadRequest, "$", "unexpected end of JSON at offset 13"},
+		{"type", "application/json", `{"address":{"city":1}}`, nil, http.StatusBadRequest, "$.address.city", "expected string, got number at offset 20"},
+		{"integer", "application/json", `{"age":"30"}`, nil, http.StatusBadRequest, "$.age", "expected integer, got string at offset 11"},
+		{"unknown", "application/json", `{"nmae":"Ann"}`, nil, http.StatusBadRequest, "$", `unknown property "nmae"`},
+		{"allow unknown", "application/json", `{"nmae":"Ann"}`, &webmux.DecodeJSONOptions{AllowUnknownFields: true}, 0, "", ""},
+		{"depth", "application/json", `{"tags":[[["}}}"]]]}`, &webmux.DecodeJSONOptions{MaxDepth: 3}, http.StatusBadRequest, "$", "nesting exceeds depth 3 at offset 10"},
+		{"trailing", "application/json", `{} {}` + "\n", nil, http.StatusBadRequest, "$", "unexpected data after JSON value at offset 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var u user
+			err := webmux.DecodeJSON(r, &u, tt.opts)
+
+			if tt.code == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var httpErr *webmux.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, tt.code, httpErr.Code)
+
+			if tt.field == "" {
+				return
+			}
+
+			var fieldErr *webmux.FieldError
+			assert.True(t, errors.As(err, &fieldErr))
+			assert.IsError(t, err, webmux.ErrInvalidBody)
+			assert.Equal(t, tt.field, fieldErr.Field)
+			assert.Equal(t, tt.message, fieldErr.Message)
+		})
+	}
+}
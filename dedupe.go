@@ -0,0 +1,206 @@
+package webmux
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateRequest is returned by the Dedupe middleware when a duplicate
+// request is rejected.
+var ErrDuplicateRequest = errors.New("webmux: duplicate request")
+
+// Defaults for DedupeOptions.
+const (
+	defaultDedupeWindow  = 10 * time.Second
+	defaultDedupeMaxBody = 64 << 10
+)
+
+// DedupeOptions configures the Dedupe middleware.
+type DedupeOptions struct {
+	// Key returns the key identifying duplicate requests, typically derived
+	// from the authenticated principal and a one-time form token.
+	// Requests with an empty key are never treated as duplicates.
+	Key func(r *http.Request) string
+
+	// Window is how long a completed request is remembered.
+	// If zero, a default of 10 seconds is used.
+	Window time.Duration
+
+	// Replay sends duplicates the response of the original request instead of
+	// a 409 Conflict. A duplicate arriving while the original is still being
+	// handled waits for it to complete.
+	Replay bool
+
+	// MaxBody is the largest response body that will be replayed, larger
+	// responses are rejected as conflicts. If zero, a default of 64KiB is used.
+	MaxBody int
+}
+
+// Dedupe returns a middleware that detects duplicate submissions of unsafe
+// requests, such as a form being posted twice by an impatient double click.
+//
+// Only the first request with a given key is passed to the handler. Duplicates
+// within the window either receive the original response or a 409 Conflict
+// [HTTPError] wrapping ErrDuplicateRequest, depending on opts.Replay.
+// If the original handler returns an error the key is forgotten so the
+// request can be retried.
+func Dedupe(opts DedupeOptions) Middleware {
+	if opts.Key == nil {
+		panic("webmux: nil dedupe key func")
+	}
+
+	if opts.Window <= 0 {
+		opts.Window = defaultDedupeWindow
+	}
+
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = defaultDedupeMaxBody
+	}
+
+	d := &deduper{opts: opts, entries: make(map[string]*dedupeEntry)}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if isSafeMethod(r.Method) {
+				return next.ServeHTTPErr(w, r)
+			}
+
+			key := opts.Key(r)
+
+			if key == "" {
+				return next.ServeHTTPErr(w, r)
+			}
+
+			return d.serve(w, r, key, next)
+		})
+	}
+}
+
+// FormTokenKey returns a Dedupe key function combining the principal returned
+// by principal with the value of the form field named field.
+func FormTokenKey(field string, principal func(r *http.Request) string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		token := r.PostFormValue(field)
+
+		if token == "" {
+			return ""
+		}
+
+		return principal(r) + "\x00" + token
+	}
+}
+
+// deduper tracks in-flight and recently completed requests by key.
+type deduper struct {
+	opts      DedupeOptions
+	mu        sync.Mutex
+	entries   map[string]*dedupeEntry
+	lastSweep time.Time
+}
+
+// dedupeEntry is the state of the original request for a key.
+type dedupeEntry struct {
+	done    chan struct{} // closed when the original request completes
+	resp    capturedResponse
+	ok      bool // resp was captured and can be replayed
+	failed  bool // the original request returned an error
+	expires time.Time
+}
+
+// serve handles the request with key, passing it to next only if it is the first.
+func (d *deduper) serve(w http.ResponseWriter, r *http.Request, key string, next Handler) (err error) {
+	now := Now(r.Context())
+
+	d.mu.Lock()
+	d.sweep(now)
+
+	entry, dup := d.entries[key]
+
+	if dup && !entry.expires.IsZero() && now.After(entry.expires) {
+		dup = false
+	}
+
+	if !dup {
+		entry = &dedupeEntry{done: make(chan struct{})}
+		d.entries[key] = entry
+	}
+
+	d.mu.Unlock()
+
+	if dup {
+		return d.duplicate(w, r, entry)
+	}
+
+	capture := newResponseCapture(w, d.opts.MaxBody)
+	completed := false
+
+	// A handler which panics fails too, so that duplicates are not kept
+	// waiting and the request can be retried
+	defer func() {
+		d.mu.Lock()
+
+		if err != nil || !completed {
+			entry.failed = true
+			delete(d.entries, key)
+		} else {
+			entry.resp, entry.ok = capture.response()
+			entry.expires = Now(r.Context()).Add(d.opts.Window)
+		}
+
+		d.mu.Unlock()
+		close(entry.done)
+	}()
+
+	err = next.ServeHTTPErr(capture, r)
+	completed = true
+
+	return err
+}
+
+// duplicate responds to a duplicate of the request tracked by entry.
+func (d *deduper) duplicate(w http.ResponseWriter, r *http.Request, entry *dedupeEntry) error {
+	conflict := NewHTTPError(http.StatusConflict, ErrDuplicateRequest)
+
+	if !d.opts.Replay {
+		return conflict
+	}
+
+	select {
+	case <-entry.done:
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+
+	if entry.failed || !entry.ok {
+		return conflict
+	}
+
+	return entry.resp.writeTo(w)
+}
+
+// sweep removes expired entries. It must be called with d.mu held.
+func (d *deduper) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.opts.Window {
+		return
+	}
+
+	d.lastSweep = now
+
+	for k, e := range d.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(d.entries, k)
+		}
+	}
+}
+
+// isSafeMethod returns true if method is defined as safe by RFC 7231 section 4.2.1.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
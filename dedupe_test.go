@@ -0,0 +1,111 @@
+package webmux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestDedupe(t *testing.T) {
+	calls := 0
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte("order placed"))
+		return err
+	})
+
+	key := webmux.FormTokenKey("token", func(r *http.Request) string { return "user-1" })
+
+	post := func(h webmux.Handler, token string) (*httptest.ResponseRecorder, error) {
+		form := url.Values{"token": {token}}
+		r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		return w, h.ServeHTTPErr(w, r)
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		calls = 0
+		dh := webmux.Dedupe(webmux.DedupeOptions{Key: key})(h)
+
+		_, err := post(dh, "a")
+		assert.NoError(t, err)
+
+		_, err = post(dh, "a")
+		var httpErr *webmux.HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusConflict, httpErr.Code)
+		assert.IsError(t, err, webmux.ErrDuplicateRequest)
+
+		_, err = post(dh, "b")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		calls = 0
+		dh := webmux.Dedupe(webmux.DedupeOptions{Key: key, Replay: true})(h)
+
+		_, err := post(dh, "a")
+		assert.NoError(t, err)
+
+		w, err := post(dh, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "order placed", w.Body.String())
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		calls = 0
+		panicking := true
+		dh := webmux.Dedupe(webmux.DedupeOptions{Key: key, Replay: true})(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if panicking {
+				panic("boom")
+			}
+
+			return h.ServeHTTPErr(w, r)
+		}))
+
+		assert.Panics(t, func() { post(dh, "a") })
+
+		panicking = false
+
+		w, err := post(dh, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("window", func(t *testing.T) {
+		calls = 0
+		clock := muxtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		mux := webmux.New()
+		mux.SetClock(clock)
+		mux.Use(webmux.Dedupe(webmux.DedupeOptions{Key: key, Window: time.Minute}))
+		mux.Handle(http.MethodPost, "/orders", h)
+
+		_, err := post(mux, "a")
+		assert.NoError(t, err)
+
+		clock.Advance(59 * time.Second)
+		_, err = post(mux, "a")
+		assert.IsError(t, err, webmux.ErrDuplicateRequest)
+
+		clock.Advance(2 * time.Second)
+		_, err = post(mux, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+}
@@ -0,0 +1,144 @@
+package webmux
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DeviceClass is a broad category of client device.
+type DeviceClass int
+
+const (
+	DeviceDesktop DeviceClass = iota // desktop or laptop browser
+	DeviceMobile                     // phone
+	DeviceTablet                     // tablet
+	DeviceBot                        // crawler or other automated client
+)
+
+// String implements [fmt.Stringer].
+func (c DeviceClass) String() string {
+	switch c {
+	case DeviceMobile:
+		return "mobile"
+	case DeviceTablet:
+		return "tablet"
+	case DeviceBot:
+		return "bot"
+	}
+
+	return "desktop"
+}
+
+// DeviceProfile describes the client device making a request.
+type DeviceProfile struct {
+	Class    DeviceClass
+	Platform string // operating system, like "Android" or "macOS", if known
+	Model    string // device model, if known
+}
+
+// clientHints are the client hint headers used to determine the device profile.
+const clientHints = "Sec-CH-UA-Mobile, Sec-CH-UA-Platform, Sec-CH-UA-Model"
+
+// ParseDevice determines the device profile of r. The User-Agent Client Hints
+// headers (Sec-CH-UA-*) are preferred, falling back to the User-Agent header.
+// A request with Sec-CH-UA-Mobile set to "?0" is never classified as mobile,
+// whatever its User-Agent, though it may be a tablet, which browsers do not
+// report as mobile either.
+func ParseDevice(r *http.Request) DeviceProfile {
+	ua := strings.ToLower(r.UserAgent())
+	mobile := r.Header.Get("Sec-CH-UA-Mobile")
+
+	d := DeviceProfile{
+		Platform: unquoteHint(r.Header.Get("Sec-CH-UA-Platform")),
+		Model:    unquoteHint(r.Header.Get("Sec-CH-UA-Model")),
+	}
+
+	if d.Platform == "" {
+		d.Platform = userAgentPlatform(ua)
+	}
+
+	switch {
+	case SuspiciousUserAgent(r):
+		d.Class = DeviceBot
+	case mobile == "?1":
+		d.Class = DeviceMobile
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		d.Class = DeviceTablet
+	case mobile != "?0" && (strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone")):
+		d.Class = DeviceMobile
+	}
+
+	return d
+}
+
+// userAgentPlatform guesses the platform from the lowercase user agent ua.
+func userAgentPlatform(ua string) string {
+	switch {
+	case strings.Contains(ua, "android"):
+		return "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		return "iOS"
+	case strings.Contains(ua, "windows"):
+		return "Windows"
+	case strings.Contains(ua, "mac os"):
+		return "macOS"
+	case strings.Contains(ua, "cros"):
+		return "Chrome OS"
+	case strings.Contains(ua, "linux"):
+		return "Linux"
+	}
+
+	return ""
+}
+
+// unquoteHint returns the value of a structured header string, like `"Android"`.
+func unquoteHint(v string) string {
+	return strings.Trim(strings.TrimSpace(v), `"`)
+}
+
+// ClientHints returns a middleware that determines the device profile of each
+// request and stores it in the request context, see DeviceFromContext.
+//
+// The Accept-CH response header is set so that supporting browsers send the
+// client hints on subsequent requests.
+func ClientHints() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set("Accept-CH", clientHints)
+			AddVary(w.Header(), strings.Split(clientHints, ",")...)
+
+			ctx := context.WithValue(r.Context(), deviceKey, ParseDevice(r))
+
+			return next.ServeHTTPErr(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// DeviceFromContext returns the DeviceProfile stored in ctx by ClientHints, if any.
+func DeviceFromContext(ctx context.Context) (DeviceProfile, bool) {
+	d, ok := ctx.Value(deviceKey).(DeviceProfile)
+	return d, ok
+}
+
+// Device returns a Predicate satisfied by requests from any of the device classes.
+// It is used with When to register variants of a route per device class:
+//
+//	mux.Handle(http.MethodGet, "/", mobileHome, webmux.When(webmux.Device(webmux.DeviceMobile)))
+//	mux.Handle(http.MethodGet, "/", home)
+//
+// Since responses then vary by device, the response should include a Vary
+// header for the client hints, which the ClientHints middleware sets.
+func Device(classes ...DeviceClass) Predicate {
+	return func(r *http.Request) bool {
+		d, ok := DeviceFromContext(r.Context())
+
+		if !ok {
+			d = ParseDevice(r)
+		}
+
+		return slices.Contains(classes, d.Class)
+	}
+}
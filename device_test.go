@@ -0,0 +1,102 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"
+	iPadUA    = "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+)
+
+func TestParseDevice(t *testing.T) {
+	tests := []struct {
+		name    string
+		ua      string
+		hints   map[string]string
+		want    webmux.DeviceProfile
+		wantStr string
+	}{
+		{"desktop", desktopUA, nil, webmux.DeviceProfile{Class: webmux.DeviceDesktop, Platform: "Windows"}, "desktop"},
+		{"iphone", iPhoneUA, nil, webmux.DeviceProfile{Class: webmux.DeviceMobile, Platform: "iOS"}, "mobile"},
+		{"ipad", iPadUA, nil, webmux.DeviceProfile{Class: webmux.DeviceTablet, Platform: "iOS"}, "tablet"},
+		{"android", androidUA, nil, webmux.DeviceProfile{Class: webmux.DeviceMobile, Platform: "Android"}, "mobile"},
+		{"bot", "Googlebot/2.1", nil, webmux.DeviceProfile{Class: webmux.DeviceBot}, "bot"},
+		{
+			"client hints",
+			desktopUA,
+			map[string]string{"Sec-CH-UA-Mobile": "?1", "Sec-CH-UA-Platform": `"Android"`, "Sec-CH-UA-Model": `"Pixel 8"`},
+			webmux.DeviceProfile{Class: webmux.DeviceMobile, Platform: "Android", Model: "Pixel 8"},
+			"mobile",
+		},
+		{
+			"not mobile hint",
+			androidUA,
+			map[string]string{"Sec-CH-UA-Mobile": "?0"},
+			webmux.DeviceProfile{Class: webmux.DeviceDesktop, Platform: "Android"},
+			"desktop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("User-Agent", tt.ua)
+
+			for k, v := range tt.hints {
+				r.Header.Set(k, v)
+			}
+
+			d := webmux.ParseDevice(r)
+
+			assert.Equal(t, tt.want, d)
+			assert.Equal(t, tt.wantStr, d.Class.String())
+		})
+	}
+}
+
+func TestClientHints(t *testing.T) {
+	mux := webmux.New()
+	mux.Use(webmux.ClientHints())
+	mux.Handle(http.MethodGet, "/", newTestHandler("mobile"), webmux.When(webmux.Device(webmux.DeviceMobile, webmux.DeviceTablet)))
+	mux.Handle(http.MethodGet, "/", newTestHandler("desktop"))
+	mux.HandleFunc(http.MethodGet, "/device", func(w http.ResponseWriter, r *http.Request) error {
+		d, ok := webmux.DeviceFromContext(r.Context())
+		assert.True(t, ok)
+		_, err := w.Write([]byte(d.Class.String()))
+		return err
+	})
+
+	serve := func(path, ua string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/", iPhoneUA)
+	assert.Equal(t, "mobile", w.Body.String())
+	assert.Equal(t, "Sec-CH-UA-Mobile, Sec-CH-UA-Platform, Sec-CH-UA-Model", w.Header().Get("Accept-CH"))
+	assert.Equal(t, "Sec-Ch-Ua-Mobile,Sec-Ch-Ua-Platform,Sec-Ch-Ua-Model", strings.Join(w.Header().Values("Vary"), ","))
+
+	assert.Equal(t, "mobile", serve("/", iPadUA).Body.String())
+	assert.Equal(t, "desktop", serve("/", desktopUA).Body.String())
+	assert.Equal(t, "tablet", serve("/device", iPadUA).Body.String())
+}
+
+func TestDeviceWithoutClientHints(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", iPhoneUA)
+
+	assert.True(t, webmux.Device(webmux.DeviceMobile)(r))
+	assert.False(t, webmux.Device(webmux.DeviceDesktop, webmux.DeviceBot)(r))
+}
@@ -0,0 +1,48 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"syscall"
+)
+
+// StatusClientClosedRequest is the non-standard status code of requests
+// whose client disconnected before the response was written, as logged by
+// nginx. It is never sent, since the client is gone, but error handlers and
+// logs use it so disconnects are not counted as server errors.
+const StatusClientClosedRequest = 499
+
+// IsClientDisconnect returns true if err was caused by the client of r
+// disconnecting, rather than by a failure of the server: a
+// [context.Canceled] error once the context of r is canceled, or a broken
+// pipe or connection reset error writing the response. Handlers commonly
+// return such errors when the client gives up waiting, and they are noise
+// in the logs and error rates of the server.
+func IsClientDisconnect(r *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) && errors.Is(r.Context().Err(), context.Canceled) {
+		return true
+	}
+
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// HandleDisconnect registers the function called with the errors of requests
+// whose client disconnected, see IsClientDisconnect. Such errors are not
+// passed to the error handler, so they are neither logged as server errors
+// nor reported by error handlers, and no response is written. The function
+// may log them at a debug level:
+//
+//	mux.HandleDisconnect(func(r *http.Request, err error) {
+//		slog.Debug("client disconnected", "path", r.URL.Path, "err", err)
+//	})
+//
+// If no function is registered, disconnects are ignored. ServeHTTPErr still
+// returns the error of the handler.
+func (mux *ServeMux) HandleDisconnect(fn func(r *http.Request, err error)) {
+	mux.disconnect = fn
+}
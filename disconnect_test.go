@@ -0,0 +1,76 @@
+package webmux_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestHandleDisconnect(t *testing.T) {
+	var handled, disconnected []error
+
+	mux := webmux.New()
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = append(handled, err)
+		webmux.StatusError(w, r, err)
+	})
+	mux.HandleDisconnect(func(r *http.Request, err error) {
+		disconnected = append(disconnected, err)
+	})
+	mux.HandleFunc(http.MethodGet, "/wait", func(w http.ResponseWriter, r *http.Request) error {
+		<-r.Context().Done()
+		return fmt.Errorf("query: %w", r.Context().Err())
+	})
+	mux.HandleFunc(http.MethodGet, "/write", func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("write: %w", syscall.EPIPE)
+	})
+	mux.HandleFunc(http.MethodGet, "/canceled", func(w http.ResponseWriter, r *http.Request) error {
+		return context.Canceled
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w := httptest.NewRecorder()
+	err := mux.ServeHTTPErr(w, httptest.NewRequest(http.MethodGet, "/wait", nil).WithContext(ctx))
+	assert.IsError(t, err, context.Canceled)
+
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/wait", nil).WithContext(ctx))
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/write", nil))
+
+	assert.Equal(t, 2, len(disconnected))
+	assert.Equal(t, 0, len(handled))
+	assert.Equal(t, 0, w.Body.Len())
+
+	// A canceled operation of a request that is still alive is a server error
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/canceled", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, len(handled))
+}
+
+func TestIsClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	canceled := r.WithContext(ctx)
+
+	assert.True(t, webmux.IsClientDisconnect(canceled, context.Canceled))
+	assert.True(t, webmux.IsClientDisconnect(r, fmt.Errorf("write: %w", syscall.ECONNRESET)))
+	assert.False(t, webmux.IsClientDisconnect(r, context.Canceled))
+	assert.False(t, webmux.IsClientDisconnect(canceled, errors.New("boom")))
+	assert.False(t, webmux.IsClientDisconnect(canceled, nil))
+
+	w := httptest.NewRecorder()
+	webmux.StatusError(w, canceled, context.Canceled)
+	assert.Equal(t, webmux.StatusClientClosedRequest, w.Code)
+}
@@ -0,0 +1,66 @@
+package webmux
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Response is the response of a request executed in-process with ServeMux.Do.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Do executes a request for method and path in-process, without a network
+// round trip, and returns the response. The request runs through the route
+// middleware and error handler exactly like a request received by a server,
+// which is useful for background jobs and for tests. When called from a
+// handler, the request does not inherit the match or mount of the handler's
+// request from ctx.
+//
+// The path may include a query. If the handler returns an error, the
+// response written by the error handler is returned along with the error.
+// An error is also returned if the request is invalid, with a nil Response.
+func (mux *ServeMux) Do(ctx context.Context, method, path string, body io.Reader) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+
+	if err != nil {
+		return nil, fmt.Errorf("webmux: %w", err)
+	}
+
+	req.RequestURI = path
+	req.RemoteAddr = "127.0.0.1:0"
+
+	return mux.do(req)
+}
+
+// do serves req in-process, returning the response and any handler error.
+func (mux *ServeMux) do(req *http.Request) (*Response, error) {
+	req = req.WithContext(subContext{req.Context()})
+
+	buf := newResponseBuffer()
+	err := mux.serve(buf, req, true)
+
+	return &Response{StatusCode: buf.code, Header: buf.header, Body: buf.body.Bytes()}, err
+}
+
+// subContext is the context of a request served by do. It hides the values
+// describing the request of any handler calling Do, like its match, mount
+// and route observer, so they are not mistaken for those of the new request.
+// The transaction, Server-Timing metrics and batch of the caller are kept.
+type subContext struct {
+	context.Context
+}
+
+// Value returns nil for the keys of values describing a single request.
+func (ctx subContext) Value(key any) any {
+	switch key {
+	case muxKey, storeKey, mountKey, notFoundKey, observerKey, geoKey, deviceKey:
+		return nil
+	}
+
+	return ctx.Context.Value(key)
+}
@@ -0,0 +1,138 @@
+package webmux
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Delegation selects how a download is delegated to a fronting proxy.
+type Delegation int
+
+const (
+	// DelegateNone serves the download from the application.
+	DelegateNone Delegation = iota
+
+	// DelegateXAccelRedirect delegates the download to nginx using the X-Accel-Redirect header.
+	DelegateXAccelRedirect
+
+	// DelegateXSendfile delegates the download to Apache or lighttpd using the X-Sendfile header.
+	DelegateXSendfile
+)
+
+// DownloadOptions configures ServeDownload.
+type DownloadOptions struct {
+	// RateLimit limits the transfer rate of each download in bytes per second.
+	// Zero means unlimited.
+	RateLimit int64
+
+	// Delegate delegates sending the file to a fronting proxy, in which case
+	// the content is not read and DelegatePath is sent to the proxy.
+	Delegate Delegation
+
+	// DelegatePath is the internal path or location of the file known to the proxy.
+	DelegatePath string
+}
+
+// ServeDownload replies to the request with content as a file attachment named name.
+//
+// Range requests are supported so that interrupted downloads can be resumed,
+// and conditional requests are handled using modtime, like [http.ServeContent].
+// A nil opts is equivalent to a zero DownloadOptions.
+func ServeDownload(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+
+	switch opts.Delegate {
+	case DelegateXAccelRedirect:
+		w.Header().Set("X-Accel-Redirect", opts.DelegatePath)
+
+		if opts.RateLimit > 0 {
+			w.Header().Set("X-Accel-Limit-Rate", strconv.FormatInt(opts.RateLimit, 10))
+		}
+
+		return nil
+	case DelegateXSendfile:
+		w.Header().Set("X-Sendfile", opts.DelegatePath)
+
+		return nil
+	}
+
+	if opts.RateLimit > 0 {
+		w = &throttledWriter{
+			ResponseWriter: w,
+			ctx:            r.Context(),
+			rate:           opts.RateLimit,
+		}
+	}
+
+	http.ServeContent(w, r, name, modtime, content)
+
+	return nil
+}
+
+// throttledWriter limits the rate of writes to an [http.ResponseWriter].
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	rate    int64 // bytes per second
+	start   time.Time
+	written int64
+}
+
+// Write writes p in chunks, sleeping as needed to stay below the rate limit.
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = Now(t.ctx)
+	}
+
+	// Write in chunks of about a tenth of a second worth of data
+	chunk := int(max(t.rate/10, 1))
+	n := 0
+
+	for n < len(p) {
+		end := min(n+chunk, len(p))
+
+		m, err := t.ResponseWriter.Write(p[n:end])
+		n += m
+		t.written += int64(m)
+
+		if err != nil {
+			return n, err
+		}
+
+		due := t.start.Add(time.Duration(t.written * int64(time.Second) / t.rate))
+
+		if err := sleepContext(t.ctx, due.Sub(Now(t.ctx))); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (t *throttledWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// sleepContext pauses for at least d according to the clock of ctx, or until
+// ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clockFrom(ctx).After(d):
+		return nil
+	}
+}
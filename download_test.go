@@ -0,0 +1,43 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServeDownload(t *testing.T) {
+	content := strings.NewReader("0123456789")
+
+	t.Run("range", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/report.txt", nil)
+		r.Header.Set("Range", "bytes=4-")
+		w := httptest.NewRecorder()
+
+		err := webmux.ServeDownload(w, r, "report.txt", time.Time{}, content, &webmux.DownloadOptions{RateLimit: 100})
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "attachment; filename=report.txt", w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "456789", w.Body.String())
+	})
+
+	t.Run("x-accel-redirect", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/report.txt", nil)
+		w := httptest.NewRecorder()
+
+		err := webmux.ServeDownload(w, r, "report.txt", time.Time{}, nil, &webmux.DownloadOptions{
+			Delegate:     webmux.DelegateXAccelRedirect,
+			DelegatePath: "/protected/report.txt",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/protected/report.txt", w.Header().Get("X-Accel-Redirect"))
+		assert.Equal(t, "", w.Body.String())
+	})
+}
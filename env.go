@@ -0,0 +1,72 @@
+package webmux
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SetEnv sets the environment of mux, like "dev", "test" or "prod", which
+// decides whether the routes registered with Env are registered. The
+// environment is empty by default, so such routes are left out unless it is
+// set:
+//
+//	mux := webmux.New()
+//	mux.SetEnv(os.Getenv("APP_ENV"))
+//
+// SetEnv panics if called after a route was registered with Env, since the
+// environment is only evaluated when routes are registered.
+func (mux *ServeMux) SetEnv(env string) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if mux.envEvaluated {
+		panic(fmt.Sprintf("webmux: SetEnv(%q) after registration of routes with Env", env))
+	}
+
+	mux.env = env
+}
+
+// Env returns a RouteOption registering the route only if the environment of
+// the mux, see SetEnv, is one of envs, for routes which must not exist in
+// production, like test fixtures and debug pages:
+//
+//	mux.Handle(http.MethodPost, "/fixtures/reset", resetFixtures, webmux.Env("dev", "test"))
+//	mux.Mount("/debug", debugMux, webmux.Env("dev"))
+//
+// The environment is evaluated once, when the route is registered, so the
+// route costs nothing in other environments: it is not matched, listed by
+// Routes, or served with a 404. When Env is given several times, the route
+// is registered in the environments common to all of them.
+//
+// The handlers of such routes are still compiled into the program. Code which
+// must not ship in production builds belongs in files with build constraints.
+func Env(envs ...string) RouteOption {
+	if len(envs) == 0 {
+		panic("webmux: no environments")
+	}
+
+	envs = slices.Clone(envs)
+
+	return func(cfg *routeConfig) {
+		if cfg.envs == nil {
+			cfg.envs = envs
+			return
+		}
+
+		cfg.envs = slices.DeleteFunc(slices.Clone(cfg.envs), func(env string) bool {
+			return !slices.Contains(envs, env)
+		})
+	}
+}
+
+// envAllowed returns true if routes registered with cfg exist in the
+// environment of mux. It must be called with the lock of mux held.
+func (mux *ServeMux) envAllowed(cfg *routeConfig) bool {
+	if cfg.envs == nil {
+		return true
+	}
+
+	mux.envEvaluated = true
+
+	return slices.Contains(cfg.envs, mux.env)
+}
@@ -0,0 +1,57 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestEnv(t *testing.T) {
+	register := func(env string) *webmux.ServeMux {
+		debug := webmux.New()
+		debug.Handle(http.MethodGet, "/vars", newTestHandler("vars"))
+
+		mux := webmux.New()
+		mux.SetEnv(env)
+		mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+		mux.Handle(http.MethodPost, "/fixtures/reset", newTestHandler("reset"), webmux.Env("dev", "test"))
+		mux.Handle(http.MethodGet, "/fixtures/seed", newTestHandler("seed"), webmux.Env("dev", "test"), webmux.Env("test", "ci"))
+		mux.Mount("/debug", debug, webmux.Env("dev"))
+
+		return mux
+	}
+
+	tests := []struct {
+		env    string
+		method string
+		target string
+		code   int
+	}{
+		{"dev", http.MethodPost, "/fixtures/reset", http.StatusOK},
+		{"dev", http.MethodGet, "/fixtures/seed", http.StatusNotFound},
+		{"dev", http.MethodGet, "/debug/vars", http.StatusOK},
+		{"test", http.MethodPost, "/fixtures/reset", http.StatusOK},
+		{"test", http.MethodGet, "/fixtures/seed", http.StatusOK},
+		{"test", http.MethodGet, "/debug/vars", http.StatusNotFound},
+		{"prod", http.MethodPost, "/fixtures/reset", http.StatusNotFound},
+		{"prod", http.MethodGet, "/users", http.StatusOK},
+		{"", http.MethodGet, "/debug/vars", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env+" "+tt.method+" "+tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			register(tt.env).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+
+	assert.Equal(t, 1, len(register("prod").Routes()))
+
+	mux := register("dev")
+	assert.Panics(t, func() { mux.SetEnv("prod") })
+	assert.Panics(t, func() { webmux.Env() })
+}
@@ -7,6 +7,16 @@ import (
 )
 
 // ErrorHandler handles errors that arise while handling http requests.
+//
+// When a ServeMux calls the error handler for a request matching a route,
+// including errors returned by handlers and requests for a method the route
+// does not handle, the MuxMatch is available with FromContext, so error
+// responses and reports can include the route pattern:
+//
+//	match, ok := webmux.FromContext(r.Context())
+//
+// For requests which do not match any route, err is a *NotFoundError with
+// the path looked up and the pattern of the nearest route.
 type ErrorHandler interface {
 	ErrorHTTP(w http.ResponseWriter, r *http.Request, err error)
 }
@@ -27,24 +37,73 @@ func StatusErrorHandler() ErrorHandler {
 }
 
 // StatusError replies to a request with an appropriate status code and HTTP status text.
+// If err is an [HTTPError] its status code is used, ErrNotFound results in a
+// 404 Not Found, and ErrInvalidParam in a 400 Bad Request. Errors of
+// disconnected clients result in StatusClientClosedRequest, see
+// IsClientDisconnect. Server errors are logged, with the ID of the request
+// if it has one, see RequestID.
 func StatusError(w http.ResponseWriter, r *http.Request, err error) {
+	code := errorStatus(w, r, err)
+
+	if code >= http.StatusInternalServerError {
+		logError(r, err)
+	}
+
+	writeError(w, code)
+}
+
+// errorStatus returns the status code of the error response to err, setting
+// the Allow header for 405 Method Not Allowed responses.
+func errorStatus(w http.ResponseWriter, r *http.Request, err error) int {
+	if allow, ok := allowedMethods(err); ok {
+		w.Header().Add("Allow", allow)
+		return http.StatusMethodNotAllowed
+	}
+
+	var notFound *NotFoundError
+
+	if errors.As(err, &notFound) || errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+
+	// The route matched, but has no handler for the method
 	if errors.Is(err, ErrMuxNotFound) {
 		match, ok := FromContext(r.Context())
 
 		if !ok {
-			writeError(w, http.StatusNotFound)
-			return
+			return http.StatusNotFound
 		}
 
-		w.Header().Add("Allow", match.Methods().String())
-		writeError(w, http.StatusMethodNotAllowed)
+		w.Header().Add("Allow", match.Allow())
+
+		return http.StatusMethodNotAllowed
+	}
+
+	if errors.Is(err, ErrInvalidParam) {
+		return http.StatusBadRequest
+	}
+
+	if IsClientDisconnect(r, err) {
+		return StatusClientClosedRequest
+	}
+
+	var httpErr *HTTPError
 
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
+
+// logError logs the server error err of r, with the ID of r if it has one.
+func logError(r *http.Request, err error) {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		log.Printf("mux error: request %s: %s", id, err.Error())
 		return
 	}
 
 	log.Printf("mux error: %s", err.Error())
-
-	writeError(w, http.StatusInternalServerError)
 }
 
 // writeError calls [http.Error] with the [http.StatusText] for code and code.
@@ -0,0 +1,33 @@
+package webmux
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an error with an associated HTTP status code.
+// The default error handler responds with Code instead of a 500 Internal
+// Server Error when a handler returns an HTTPError.
+type HTTPError struct {
+	Code int   // HTTP status code
+	Err  error // underlying error, may be nil
+}
+
+// NewHTTPError returns a new HTTPError for code wrapping err.
+func NewHTTPError(code int, err error) *HTTPError {
+	return &HTTPError{Code: code, Err: err}
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%d %s", e.Code, http.StatusText(e.Code))
+	}
+
+	return fmt.Sprintf("%d %s: %s", e.Code, http.StatusText(e.Code), e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
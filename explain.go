@@ -0,0 +1,145 @@
+package webmux
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Explanation describes how a ServeMux routes a request, see Explain.
+type Explanation struct {
+	// Host is the host of the matched route as given to Host, or empty if the
+	// route was registered without a host.
+	Host string
+
+	// Pattern is the pattern of the matched route, or empty if no route
+	// matches the request.
+	Pattern string
+
+	// Params maps the param names of Pattern to their values.
+	Params map[string]string
+
+	// Allow is the value of the Allow header for the matched route.
+	Allow string
+
+	// Handler is the handler serving the request, or nil if no handler is
+	// registered for the method of the request.
+	Handler Handler
+
+	// Middleware are the names of the middleware wrapping Handler, outermost
+	// first, like the chains of RouteInfo.
+	Middleware []string
+
+	// Steps are the decisions taken to route the request, in order, like
+	// "host *.example.com matches acme.example.com, no route for path /about".
+	Steps []string
+}
+
+// Explain returns how mux routes r without serving it, listing the hosts
+// and paths considered along with the route and handler chosen. It is
+// meant for debugging routing tables, and is much slower than dispatching
+// the request.
+//
+// Predicates of conditional handlers are evaluated against r. Requests to a
+// mounted mux are explained up to the Mount route.
+func (mux *ServeMux) Explain(r *http.Request) Explanation {
+	var ex Explanation
+
+	trace := func(step string) {
+		ex.Steps = append(ex.Steps, step)
+	}
+
+	if !mux.hostAllowed(r) {
+		trace(fmt.Sprintf("host %s is not allowed", requestHost(r)))
+		return ex
+	}
+
+	mux.mu.RLock()
+	match, host := mux.route(r, cleanPath(r.URL.EscapedPath()), &MuxMatch{}, trace)
+	mw := mux.middleware
+	mux.mu.RUnlock()
+
+	if match == nil {
+		return ex
+	}
+
+	now := mux.now()
+
+	if match.expired(now) {
+		trace(fmt.Sprintf("route %s expired", match.pattern))
+		return ex
+	}
+
+	if match.priority != 0 {
+		trace(fmt.Sprintf("route %s has priority %d", match.pattern, match.priority))
+	}
+
+	ex.Host = host
+	ex.Pattern = match.pattern
+	ex.Allow = match.Allow()
+	ex.Params = make(map[string]string, len(match.params))
+
+	for i, name := range match.params {
+		ex.Params[name] = match.values[i]
+	}
+
+	method := r.Method
+	h, _, _ := match.handlerFor(r, method, now, nil)
+
+	if h == nil && method == http.MethodHead {
+		method = http.MethodGet
+		h, _, _ = match.handlerFor(r, method, now, nil)
+	}
+
+	switch {
+	case h == nil:
+		trace(fmt.Sprintf("no handler for method %s, allowed methods are %s", r.Method, ex.Allow))
+	case match.conditionalFor(r, method, now):
+		trace(fmt.Sprintf("conditional handler for method %s", method))
+	default:
+		trace(fmt.Sprintf("handler for method %s", method))
+	}
+
+	ex.Handler = h
+
+	if h == nil {
+		return ex
+	}
+
+	for _, m := range mw {
+		ex.Middleware = append(ex.Middleware, MiddlewareName(m))
+	}
+
+	ex.Middleware = append(ex.Middleware, match.middlewareFor(r, method, now)...)
+
+	if len(ex.Middleware) > 0 {
+		trace(fmt.Sprintf("handler wrapped by %s", strings.Join(ex.Middleware, ", ")))
+	}
+
+	return ex
+}
+
+// middlewareFor returns the names of the route middleware of the handler
+// returned by handlerFor.
+func (m *MuxMatch) middlewareFor(r *http.Request, method string, now time.Time) []string {
+	for _, c := range m.conditional[method] {
+		if (c.expires.IsZero() || now.Before(c.expires)) && c.match(r) {
+			return c.middleware
+		}
+	}
+
+	return m.muxEntry.middleware[method]
+}
+
+// conditionalFor returns true if a handler registered with predicates for
+// method is satisfied by r, see handlerFor.
+func (m *MuxMatch) conditionalFor(r *http.Request, method string, now time.Time) bool {
+	for _, c := range m.conditional[method] {
+		if (c.expires.IsZero() || now.Before(c.expires)) && c.match(r) {
+			return true
+		}
+	}
+
+	return false
+}
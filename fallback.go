@@ -0,0 +1,59 @@
+package webmux
+
+import (
+	"context"
+	"net/http"
+)
+
+// HandleNotFound registers the handler called for requests which do not match
+// any route, instead of returning ErrMuxNotFound to the error handler. This
+// renders custom 404 pages without replacing the error handler:
+//
+//	mux.HandleNotFound(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		return webmux.Encode(w, r, http.StatusNotFound, notFoundBody)
+//	}))
+//
+// The handler runs through the middleware added with Use, and the response
+// status defaults to 404 Not Found if the handler does not write one. There
+// is no MuxMatch in the request context, but the NotFoundError describing
+// the lookup is available with NotFoundFromContext.
+func (mux *ServeMux) HandleNotFound(h Handler) {
+	if h == nil {
+		panic("webmux: nil handler")
+	}
+
+	mux.notFoundHandler = h
+}
+
+// HandleMethodNotAllowed registers the handler called for requests matching a
+// route which does not handle the request method, instead of returning
+// ErrMuxNotFound to the error handler.
+//
+// The handler runs through the middleware added with Use, with the MuxMatch of
+// the route in the request context. The Allow header is set to the methods of
+// the route, and the response status defaults to 405 Method Not Allowed if the
+// handler does not write one.
+func (mux *ServeMux) HandleMethodNotAllowed(h Handler) {
+	if h == nil {
+		panic("webmux: nil handler")
+	}
+
+	mux.methodNotAllowedHandler = h
+}
+
+// serveFallback serves r with the not found or method not allowed handler h,
+// with match in the context, defaulting the response status to code.
+func (mux *ServeMux) serveFallback(w http.ResponseWriter, r *http.Request, h Handler, match *MuxMatch, code int) error {
+	ctx, release := newStoreContext(r, mux.clock)
+	defer release()
+
+	r = r.WithContext(context.WithValue(ctx, muxKey, match))
+	sw := &StatusWriter{ResponseWriter: w, Code: code}
+	err := mux.chain(h).ServeHTTPErr(sw, r)
+
+	if err == nil && sw.Status() == 0 {
+		sw.WriteHeader(code)
+	}
+
+	return err
+}
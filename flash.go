@@ -0,0 +1,147 @@
+package webmux
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrFlashTooLarge is returned by AddFlash and SaveForm when the data does not fit in a cookie.
+var ErrFlashTooLarge = errors.New("webmux: flash messages too large")
+
+// Flash cookie settings.
+const (
+	flashCookie   = "_flash"
+	formCookie    = "_flash_form"
+	maxFlashBytes = 4000
+)
+
+// flashKey is the per-request store key of the flash messages added to a response.
+type flashKey struct{}
+
+// Flash is a one-shot message shown to the user on the next page, such as
+// "Profile updated" after a form is submitted.
+type Flash struct {
+	Kind    string `json:"kind,omitempty"` // kind of message, like "success" or "error"
+	Message string `json:"message"`
+}
+
+// AddFlash adds a flash message to the response, to be shown on the next
+// request, typically after the redirect of a POST/redirect/GET flow:
+//
+//	webmux.AddFlash(w, r, "success", "Profile updated")
+//	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+//
+// Messages are stored in a cookie, which must be set before the response
+// header is written. Several messages may be added to the same response of a
+// request dispatched by ServeMux. The cookie is neither encrypted nor signed, so messages
+// must not contain secrets and must be escaped when rendered, as
+// [html/template] does.
+func AddFlash(w http.ResponseWriter, r *http.Request, kind, message string) error {
+	flashes, _ := Get[[]Flash](r.Context(), flashKey{})
+	flashes = append(flashes, Flash{Kind: kind, Message: message})
+
+	if err := setOnceCookie(w, r, flashCookie, flashes); err != nil {
+		return err
+	}
+
+	Set(r.Context(), flashKey{}, flashes)
+
+	return nil
+}
+
+// Flashes returns the flash messages added by the previous response, and
+// deletes them so they are only shown once. The messages are typically passed
+// to a template:
+//
+//	data.Flashes = webmux.Flashes(w, r)
+//
+//	{{range .Flashes}}<p class="flash {{.Kind}}">{{.Message}}</p>{{end}}
+//
+// Invalid flash cookies are ignored. Flashes must be called before the
+// response header is written.
+func Flashes(w http.ResponseWriter, r *http.Request) []Flash {
+	var flashes []Flash
+
+	if !readOnceCookie(w, r, flashCookie, &flashes) {
+		return nil
+	}
+
+	return flashes
+}
+
+// setOnceCookie sets the cookie name to v encoded as JSON, replacing any value
+// set earlier in the response. It is read once with readOnceCookie.
+func setOnceCookie(w http.ResponseWriter, r *http.Request, name string, v any) error {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(b)
+
+	if len(value) > maxFlashBytes {
+		return ErrFlashTooLarge
+	}
+
+	deleteSetCookie(w.Header(), name)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Secure:   RequestScheme(r) == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+// readOnceCookie decodes the cookie name set with setOnceCookie into v and
+// deletes the cookie, returning false if it is missing or invalid.
+func readOnceCookie(w http.ResponseWriter, r *http.Request, name string, v any) bool {
+	c, err := r.Cookie(name)
+
+	if err != nil {
+		return false
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   RequestScheme(r) == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	b, err := base64.RawURLEncoding.DecodeString(c.Value)
+
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(b, v) == nil
+}
+
+// deleteSetCookie removes the Set-Cookie headers for the cookie name from h.
+func deleteSetCookie(h http.Header, name string) {
+	cookies := h["Set-Cookie"][:0]
+
+	for _, c := range h["Set-Cookie"] {
+		if !strings.HasPrefix(c, name+"=") {
+			cookies = append(cookies, c)
+		}
+	}
+
+	if len(cookies) == 0 {
+		h.Del("Set-Cookie")
+		return
+	}
+
+	h["Set-Cookie"] = cookies
+}
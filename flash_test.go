@@ -0,0 +1,74 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestFlash(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodPost, "/profile", func(w http.ResponseWriter, r *http.Request) error {
+		if err := webmux.AddFlash(w, r, "success", "Profile updated"); err != nil {
+			return err
+		}
+
+		if err := webmux.AddFlash(w, r, "info", "Check your email"); err != nil {
+			return err
+		}
+
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+
+		return nil
+	})
+	mux.HandleFunc(http.MethodGet, "/profile", func(w http.ResponseWriter, r *http.Request) error {
+		for _, f := range webmux.Flashes(w, r) {
+			w.Write([]byte(f.Kind + ": " + f.Message + "\n"))
+		}
+
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/profile", nil))
+
+	cookies := w.Result().Cookies()
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, 1, len(cookies))
+	assert.True(t, cookies[0].HttpOnly)
+
+	r := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	r.AddCookie(cookies[0])
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, "success: Profile updated\ninfo: Check your email\n", w.Body.String())
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+
+	// Without the cookie, no messages are shown
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile", nil))
+
+	assert.Equal(t, "", w.Body.String())
+
+	// Tampered cookies are ignored
+	r = httptest.NewRequest(http.MethodGet, "/profile", nil)
+	r.AddCookie(&http.Cookie{Name: cookies[0].Name, Value: "!!!"})
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, "", w.Body.String())
+
+	// Messages which do not fit in a cookie are rejected
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	err := webmux.AddFlash(httptest.NewRecorder(), r, "error", strings.Repeat("x", 5000))
+
+	assert.IsError(t, err, webmux.ErrFlashTooLarge)
+}
@@ -0,0 +1,162 @@
+package webmux
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// FieldError is a validation error of a form field. Validators return a
+// FieldError, or several joined with [errors.Join], so that Form.AddErrors
+// can display them next to their fields.
+type FieldError struct {
+	Field   string // name of the form field
+	Message string // message shown to the user
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Form holds submitted form values and their validation errors, for
+// re-displaying a form with the values the user entered and the errors next
+// to each field.
+//
+// With the POST/redirect/GET flow, an invalid form is saved with SaveForm
+// before redirecting back to the form page, which loads it with LoadForm:
+//
+//	form, err := webmux.NewForm(r)
+//	if err != nil {
+//		return err
+//	}
+//
+//	if form.AddErrors(validate(form.Values)) {
+//		if err := webmux.SaveForm(w, r, form, "password"); err != nil {
+//			return err
+//		}
+//
+//		http.Redirect(w, r, "/signup", http.StatusSeeOther)
+//		return nil
+//	}
+//
+// The template of the form page then uses the values and errors:
+//
+//	<input name="email" value="{{.Form.Get "email"}}">
+//	{{with .Form.Error "email"}}<p class="error">{{.}}</p>{{end}}
+type Form struct {
+	Values url.Values          `json:"values"`
+	Errors map[string][]string `json:"errors,omitempty"` // field to error messages
+}
+
+// NewForm returns a Form with the submitted values of r: the body of POST,
+// PUT, and PATCH requests, or the query of other requests. If the body cannot
+// be parsed a 400 Bad Request [HTTPError] is returned.
+func NewForm(r *http.Request) (*Form, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	values := r.PostForm
+
+	if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch {
+		values = r.URL.Query()
+	}
+
+	return &Form{Values: values}, nil
+}
+
+// Get returns the first value of field, or "" if there is none.
+func (f *Form) Get(field string) string {
+	if f == nil {
+		return ""
+	}
+
+	return f.Values.Get(field)
+}
+
+// Error returns the first error message of field, or "" if it is valid.
+func (f *Form) Error(field string) string {
+	if f == nil || len(f.Errors[field]) == 0 {
+		return ""
+	}
+
+	return f.Errors[field][0]
+}
+
+// AddError adds an error message for field.
+func (f *Form) AddError(field, message string) {
+	if f.Errors == nil {
+		f.Errors = make(map[string][]string)
+	}
+
+	f.Errors[field] = append(f.Errors[field], message)
+}
+
+// AddErrors adds the messages of every *FieldError wrapped by err, including
+// errors joined with [errors.Join], returning true if there was any.
+func (f *Form) AddErrors(err error) bool {
+	found := false
+
+	walkErrors(err, func(err error) {
+		if fieldErr, ok := err.(*FieldError); ok {
+			f.AddError(fieldErr.Field, fieldErr.Message)
+			found = true
+		}
+	})
+
+	return found
+}
+
+// Valid returns true if the form has no errors.
+func (f *Form) Valid() bool {
+	return f == nil || len(f.Errors) == 0
+}
+
+// walkErrors calls fn for err and every error it wraps.
+func walkErrors(err error, fn func(error)) {
+	if err == nil {
+		return
+	}
+
+	fn(err)
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		walkErrors(u.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, err := range u.Unwrap() {
+			walkErrors(err, fn)
+		}
+	}
+}
+
+// SaveForm saves form in a one-shot cookie, to be loaded with LoadForm on the
+// next request. The fields in omit, such as passwords, are not saved. Like
+// flash messages the cookie is neither encrypted nor signed, and it must be
+// set before the response header is written. If the form does not fit in a
+// cookie, ErrFlashTooLarge is returned.
+func SaveForm(w http.ResponseWriter, r *http.Request, form *Form, omit ...string) error {
+	saved := &Form{Values: make(url.Values, len(form.Values)), Errors: form.Errors}
+
+	for k, v := range form.Values {
+		if !slices.Contains(omit, k) {
+			saved.Values[k] = v
+		}
+	}
+
+	return setOnceCookie(w, r, formCookie, saved)
+}
+
+// LoadForm returns the form saved with SaveForm by the previous response, and
+// deletes it so it is only shown once. If there is no saved form, an empty
+// form is returned, so templates can use it unconditionally.
+func LoadForm(w http.ResponseWriter, r *http.Request) *Form {
+	var form Form
+
+	if !readOnceCookie(w, r, formCookie, &form) || form.Values == nil {
+		return &Form{Values: url.Values{}}
+	}
+
+	return &form
+}
@@ -0,0 +1,84 @@
+package webmux_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestForm(t *testing.T) {
+	validate := func(values url.Values) error {
+		var errs []error
+
+		if !strings.Contains(values.Get("email"), "@") {
+			errs = append(errs, &webmux.FieldError{Field: "email", Message: "is invalid"})
+		}
+
+		if len(values.Get("password")) < 8 {
+			errs = append(errs, fmt.Errorf("validate: %w", &webmux.FieldError{Field: "password", Message: "is too short"}))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodPost, "/signup", func(w http.ResponseWriter, r *http.Request) error {
+		form, err := webmux.NewForm(r)
+
+		if err != nil {
+			return err
+		}
+
+		if form.AddErrors(validate(form.Values)) {
+			if err := webmux.SaveForm(w, r, form, "password"); err != nil {
+				return err
+			}
+
+			http.Redirect(w, r, "/signup", http.StatusSeeOther)
+
+			return nil
+		}
+
+		return nil
+	})
+	mux.HandleFunc(http.MethodGet, "/signup", func(w http.ResponseWriter, r *http.Request) error {
+		form := webmux.LoadForm(w, r)
+
+		fmt.Fprintf(w, "email=%q password=%q valid=%t email error=%q password error=%q",
+			form.Get("email"), form.Get("password"), form.Valid(), form.Error("email"), form.Error("password"))
+
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader("email=matt&password=secret"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/signup", nil)
+
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, `email="matt" password="" valid=false email error="is invalid" password error="is too short"`, w.Body.String())
+
+	// Without a saved form, an empty form is loaded
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/signup", nil))
+
+	assert.Equal(t, `email="" password="" valid=true email error="" password error=""`, w.Body.String())
+}
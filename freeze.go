@@ -0,0 +1,56 @@
+package webmux
+
+// Freeze marks the routes of mux as final. Any later attempt to register a
+// route panics, which catches routes accidentally registered after the server
+// has started.
+//
+// Because the routes can no longer change, Freeze also compiles a lookup
+// table for patterns without parameters, so that requests for them are
+// matched without walking the routing tree, and wraps the handlers with the
+// middleware added with Use after they were registered.
+//
+// Freeze is best called before mux starts serving requests, but is safe to
+// call while it does.
+func (mux *ServeMux) Freeze() {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if mux.frozen {
+		return
+	}
+
+	// Middleware added with Use since registration are applied per request
+	mux.root.precompose(mux.middleware)
+
+	for _, h := range mux.hosts {
+		h.root.precompose(mux.middleware)
+	}
+
+	mux.static = make(map[string]*muxEntry)
+	mux.root.collectStatic("", mux.static)
+	mux.frozen = true
+}
+
+// Frozen reports whether Freeze has been called.
+func (mux *ServeMux) Frozen() bool {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	return mux.frozen
+}
+
+// collectStatic adds the entries reachable from n through literal segments
+// only to static, keyed by their path. The path of n is prefix.
+func (n *node) collectStatic(prefix string, static map[string]*muxEntry) {
+	if n.entry != nil && prefix != "" {
+		static[prefix] = n.entry
+	}
+
+	for segment, child := range n.children {
+		if segment == ":" || segment == "*" {
+			continue
+		}
+
+		child.collectStatic(prefix+"/"+segment, static)
+	}
+}
@@ -0,0 +1,121 @@
+package webmux
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrGeoBlocked is returned by the GeoBlock middleware for requests from blocked regions.
+var ErrGeoBlocked = errors.New("webmux: region blocked")
+
+// A GeoResolver determines the geographic regions a request originates from.
+// Implementations typically look up the client IP in a database such as
+// MaxMind GeoIP, or trust a header set by a CDN.
+type GeoResolver interface {
+	// Regions returns region codes for r, such as the ISO 3166-1 country code
+	// "DE" and broader regions like "EU". Regions returns nil if unknown.
+	Regions(r *http.Request) []string
+}
+
+// The GeoResolverFunc type is an adapter to allow the use of ordinary functions
+// as geo resolvers.
+type GeoResolverFunc func(r *http.Request) []string
+
+// Regions calls f(r).
+func (f GeoResolverFunc) Regions(r *http.Request) []string {
+	return f(r)
+}
+
+// euCountries are the ISO 3166-1 alpha-2 codes of the member states of the European Union.
+var euCountries = []string{
+	"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU",
+	"IE", "IT", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK",
+}
+
+// HeaderGeoResolver returns a GeoResolver that reads the country code of the
+// client from header, as set by CDNs like Cloudflare ("CF-IPCountry") and
+// CloudFront ("CloudFront-Viewer-Country"). Countries in the European Union
+// also resolve to the region "EU".
+//
+// The header must only be trusted if it is set by a proxy in front of the server.
+func HeaderGeoResolver(header string) GeoResolver {
+	return GeoResolverFunc(func(r *http.Request) []string {
+		country := strings.ToUpper(strings.TrimSpace(r.Header.Get(header)))
+
+		if country == "" {
+			return nil
+		}
+
+		if slices.Contains(euCountries, country) {
+			return []string{country, "EU"}
+		}
+
+		return []string{country}
+	})
+}
+
+// SetGeoResolver sets the resolver used by the Geo predicate and GeoRegions.
+func (mux *ServeMux) SetGeoResolver(resolver GeoResolver) {
+	mux.geo = resolver
+}
+
+// geoState lazily resolves and caches the regions of a request.
+type geoState struct {
+	resolver GeoResolver
+	once     sync.Once
+	regions  []string
+}
+
+// GeoRegions returns the regions r originates from, as determined by the
+// GeoResolver of the mux serving r. The regions are resolved at most once per
+// request. GeoRegions returns nil if the mux has no GeoResolver.
+func GeoRegions(r *http.Request) []string {
+	s, ok := r.Context().Value(geoKey).(*geoState)
+
+	if !ok {
+		return nil
+	}
+
+	s.once.Do(func() {
+		s.regions = s.resolver.Regions(r)
+	})
+
+	return s.regions
+}
+
+// Geo returns a Predicate satisfied by requests originating from any of regions.
+// It is typically used with When to route requests from some regions to
+// a different handler:
+//
+//	mux.Handle(http.MethodGet, "/checkout", euCheckout, webmux.When(webmux.Geo("EU")))
+//	mux.Handle(http.MethodGet, "/checkout", checkout)
+func Geo(regions ...string) Predicate {
+	return func(r *http.Request) bool {
+		for _, region := range GeoRegions(r) {
+			if slices.Contains(regions, region) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// GeoBlock returns a middleware that rejects requests originating from any of
+// regions with a 451 Unavailable For Legal Reasons [HTTPError] wrapping ErrGeoBlocked.
+func GeoBlock(regions ...string) Middleware {
+	blocked := Geo(regions...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if blocked(r) {
+				return NewHTTPError(http.StatusUnavailableForLegalReasons, ErrGeoBlocked)
+			}
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package webmux
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// gRPC-Web content types. The "-text" variants base64 encode the body.
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+	grpcContentType        = "application/grpc"
+)
+
+// grpcWebTrailerFlag marks a gRPC-Web frame as carrying trailers rather than a message.
+const grpcWebTrailerFlag byte = 0x80
+
+// ErrNotGRPCWeb is returned by GRPCWebBridge when the request is not a
+// gRPC-Web request, wrapped in a 415 Unsupported Media Type [HTTPError].
+var ErrNotGRPCWeb = errors.New("webmux: not a grpc-web request")
+
+// GRPCWebBridge is a Handler that translates gRPC-Web requests from browser
+// clients into gRPC requests to a backend, and translates the responses back.
+// Unary and server streaming calls are supported.
+//
+// The bridge is typically mounted on a wildcard pattern such as "/rpc/*". When
+// the matched pattern ends in a wildcard the captured value is used as the
+// gRPC method path, so a request for "/rpc/pkg.Service/Method" is forwarded
+// as "/pkg.Service/Method". Otherwise the request path is forwarded unchanged.
+//
+// If the request context has a deadline, such as one set by timeout
+// middleware, the remaining time is sent to the backend in the grpc-timeout
+// header and the upstream request is canceled at the deadline.
+//
+// gRPC requires HTTP/2, so Transport must be able to speak HTTP/2 to Backend.
+type GRPCWebBridge struct {
+	// Backend is the base URL of the gRPC server.
+	Backend *url.URL
+
+	// Transport is used to perform the upstream gRPC request.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+// NewGRPCWebBridge returns a new GRPCWebBridge forwarding to backend using transport.
+func NewGRPCWebBridge(backend *url.URL, transport http.RoundTripper) *GRPCWebBridge {
+	return &GRPCWebBridge{
+		Backend:   backend,
+		Transport: transport,
+	}
+}
+
+// ServeHTTPErr implements Handler by bridging the gRPC-Web request r to the backend.
+func (b *GRPCWebBridge) ServeHTTPErr(w http.ResponseWriter, r *http.Request) error {
+	contentType := r.Header.Get("Content-Type")
+
+	if !strings.HasPrefix(contentType, grpcWebContentType) {
+		return NewHTTPError(http.StatusUnsupportedMediaType, ErrNotGRPCWeb)
+	}
+
+	text := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+	var body io.Reader = r.Body
+
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+
+	target := *b.Backend
+	target.Path = singleJoiningSlash(target.Path, grpcMethodPath(r))
+	target.RawQuery = ""
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target.String(), body)
+
+	if err != nil {
+		return fmt.Errorf("webmux: grpc-web request: %w", err)
+	}
+
+	copyHeader(req.Header, r.Header)
+	removeHopHeaders(req.Header)
+	req.Header.Del("Content-Length")
+	req.Header.Del("X-Grpc-Web")
+	req.Header.Set("Content-Type", grpcContentType+grpcContentSubtype(contentType))
+	req.Header.Set("Te", "trailers")
+	req.ProtoMajor, req.ProtoMinor = 2, 0
+
+	// Propagate the remaining budget unless the client asked for less
+	if budget, ok := Budget(r.Context()); ok {
+		if budget <= 0 {
+			return NewHTTPError(http.StatusGatewayTimeout, ErrBudgetExhausted)
+		}
+
+		if timeout, ok := parseGRPCTimeout(req.Header.Get("Grpc-Timeout")); !ok || budget < timeout {
+			req.Header.Set("Grpc-Timeout", formatGRPCTimeout(budget))
+		}
+	}
+
+	transport := b.Transport
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+
+	if err != nil {
+		return fmt.Errorf("webmux: grpc-web upstream: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	copyHeader(w.Header(), resp.Header)
+	removeHopHeaders(w.Header())
+	w.Header().Del("Content-Length")
+	w.Header().Del("Trailer")
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(resp.StatusCode)
+
+	var out io.Writer = w
+
+	if text {
+		out = &grpcWebTextWriter{w: w}
+	}
+
+	err = copyFlush(out, resp.Body)
+
+	if err == nil && len(resp.Trailer) > 0 {
+		_, err = out.Write(grpcWebTrailerFrame(resp.Trailer))
+	}
+
+	flush(w)
+
+	// The response has started, so the error handler cannot respond, and the
+	// client sees the stream end without trailers
+	if err != nil && !IsClientDisconnect(r, err) {
+		logError(r, fmt.Errorf("webmux: grpc-web response: %w", err))
+	}
+
+	return nil
+}
+
+// grpcMethodPath returns the gRPC method path for r, stripping any mount prefix
+// captured by a trailing wildcard in the matched pattern.
+func grpcMethodPath(r *http.Request) string {
+	m, ok := FromContext(r.Context())
+
+	if !ok || !strings.Contains(m.Pattern(), "/*") {
+		return r.URL.Path
+	}
+
+	return "/" + m.Param("*")
+}
+
+// grpcContentSubtype returns the codec suffix of a gRPC-Web content type, like "+proto".
+func grpcContentSubtype(contentType string) string {
+	if i := strings.IndexByte(contentType, '+'); i >= 0 {
+		return contentType[i:]
+	}
+
+	return ""
+}
+
+// grpcWebTrailerFrame encodes trailer as a gRPC-Web trailer frame.
+func grpcWebTrailerFrame(trailer http.Header) []byte {
+	keys := make([]string, 0, len(trailer))
+
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", strings.ToLower(k), v)
+		}
+	}
+
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+
+	return append(frame, buf.Bytes()...)
+}
+
+// grpcWebTextWriter base64 encodes each write independently, as permitted by
+// the gRPC-Web text protocol, so that streamed messages can be flushed promptly.
+type grpcWebTextWriter struct {
+	w io.Writer
+}
+
+// Write encodes p as padded base64 and writes it to the underlying writer.
+func (t *grpcWebTextWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(p)))
+	base64.StdEncoding.Encode(buf, p)
+
+	if _, err := t.w.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// copyFlush copies src to dst, flushing dst after every write, see flush.
+func copyFlush(dst io.Writer, src io.Reader) error {
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := src.Read(buf)
+
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+
+			flush(dst)
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// flush flushes w if it is a ResponseWriter supporting it, see
+// [http.ResponseController].
+func flush(w io.Writer) {
+	if t, ok := w.(*grpcWebTextWriter); ok {
+		w = t.w
+	}
+
+	if rw, ok := w.(http.ResponseWriter); ok {
+		http.NewResponseController(rw).Flush()
+	}
+}
+
+// Hop-by-hop headers, which must not be forwarded by proxies.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders removes hop-by-hop headers from h.
+func removeHopHeaders(h http.Header) {
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
+
+// copyHeader adds all of the values in src to dst.
+func copyHeader(dst, src http.Header) {
+	for k, vv := range src {
+		for _, v := range vv {
+			dst.Add(k, v)
+		}
+	}
+}
+
+// singleJoiningSlash joins a and b with exactly one slash between them.
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+
+	return a + b
+}
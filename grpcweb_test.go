@@ -0,0 +1,132 @@
+package webmux_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func newGRPCBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(body)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	backend.EnableHTTP2 = true
+	backend.StartTLS()
+
+	t.Cleanup(backend.Close)
+
+	return backend
+}
+
+func TestGRPCWebBridge(t *testing.T) {
+	backend := newGRPCBackend(t)
+	target, _ := url.Parse(backend.URL)
+
+	mux := webmux.New()
+	mux.Handle(http.MethodPost, "/rpc/*", webmux.NewGRPCWebBridge(target, backend.Client().Transport))
+
+	frame := []byte{0, 0, 0, 0, 2, 'h', 'i'}
+	trailer := []byte("\x80\x00\x00\x00\x10grpc-status: 0\r\n")
+
+	t.Run("binary", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/rpc/pkg.Service/Method", bytes.NewReader(frame))
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "/pkg.Service/Method", w.Header().Get("X-Path"))
+		assert.Equal(t, "application/grpc-web+proto", w.Header().Get("Content-Type"))
+		assert.Equal(t, append(frame, trailer...), w.Body.Bytes())
+	})
+
+	t.Run("text", func(t *testing.T) {
+		body := base64.StdEncoding.EncodeToString(frame)
+		r := httptest.NewRequest(http.MethodPost, "/rpc/pkg.Service/Method", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/grpc-web-text")
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		want := base64.StdEncoding.EncodeToString(frame) + base64.StdEncoding.EncodeToString(trailer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, want, w.Body.String())
+	})
+
+	t.Run("streaming through middleware", func(t *testing.T) {
+		var written int64
+
+		mux := webmux.New()
+		mux.Use(webmux.CountBytes(func(r *http.Request, n webmux.ByteCount) { written = n.Written }), webmux.ServerTiming())
+		mux.Handle(http.MethodPost, "/rpc/*", webmux.NewGRPCWebBridge(target, backend.Client().Transport))
+
+		r := httptest.NewRequest(http.MethodPost, "/rpc/pkg.Service/Method", bytes.NewReader(frame))
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, append(frame, trailer...), w.Body.Bytes())
+		assert.Equal(t, int64(len(frame)+len(trailer)), written)
+		assert.True(t, w.Flushed)
+	})
+
+	t.Run("not grpc-web", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/rpc/pkg.Service/Method", nil)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		err := mux.ServeHTTPErr(w, r)
+
+		assert.IsError(t, err, webmux.ErrNotGRPCWeb)
+
+		var httpErr *webmux.HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusUnsupportedMediaType, httpErr.Code)
+	})
+
+	t.Run("upstream error after response", func(t *testing.T) {
+		transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(io.MultiReader(bytes.NewReader(frame), iotest.ErrReader(errors.New("reset")))),
+			}, nil
+		})
+
+		bridge := webmux.NewGRPCWebBridge(target, transport)
+		r := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", nil)
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		w := httptest.NewRecorder()
+
+		assert.NoError(t, bridge.ServeHTTPErr(w, r))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, frame, w.Body.Bytes())
+	})
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,95 @@
+package webmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Header returns a RouteOption setting the response header name to value
+// before the handler is called, keeping header policy declarative:
+//
+//	mux.Handle(http.MethodGet, "/account", account,
+//		webmux.Header("X-Frame-Options", "DENY"),
+//		webmux.Header("Cache-Control", "no-store"),
+//	)
+//
+// The handler can still change or delete the header before writing the response.
+func Header(name, value string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.middleware = append(cfg.middleware, func(next Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set(name, value)
+				return next.ServeHTTPErr(w, r)
+			})
+		})
+	}
+}
+
+// Vary returns a RouteOption adding the request headers to the Vary response
+// header before the handler is called, merging them with any existing values,
+// see AddVary.
+func Vary(headers ...string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.middleware = append(cfg.middleware, func(next Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				AddVary(w.Header(), headers...)
+				return next.ServeHTTPErr(w, r)
+			})
+		})
+	}
+}
+
+// AddVary adds the request headers to the Vary header of h, skipping those
+// already present regardless of case. Middleware whose response depends on a
+// request header, like content negotiation, must add it to Vary, otherwise
+// shared caches may serve a response to requests it does not apply to.
+// Nothing is added if Vary is "*".
+func AddVary(h http.Header, headers ...string) {
+	present := make(map[string]bool)
+
+	for _, name := range VaryHeaders(h) {
+		present[name] = true
+	}
+
+	if present["*"] {
+		return
+	}
+
+	for _, name := range headers {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+
+		if name == "" || present[name] {
+			continue
+		}
+
+		h.Add("Vary", name)
+		present[name] = true
+	}
+}
+
+// VaryHeaders returns the request headers listed in the Vary header of h, in
+// canonical form and without duplicates.
+func VaryHeaders(h http.Header) []string {
+	headers := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+
+			if name != "" && !seen[name] {
+				headers = append(headers, name)
+				seen[name] = true
+			}
+		}
+	}
+
+	return headers
+}
+
+// SetVary replaces the Vary header of h with headers, overriding the values
+// added by middleware. Calling SetVary with no headers removes the Vary header.
+func SetVary(h http.Header, headers ...string) {
+	h.Del("Vary")
+	AddVary(h, headers...)
+}
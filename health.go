@@ -0,0 +1,199 @@
+package webmux
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HealthCheck is a named check of a dependency of the service, like a
+// database or a queue, reported by the endpoints registered with HandleHealth.
+type HealthCheck struct {
+	// Name is the key of the check in the report, like "postgres".
+	Name string
+
+	// Check returns an error if the dependency is unhealthy. It must return
+	// once ctx is done.
+	Check func(ctx context.Context) error
+
+	// Liveness includes the check in the liveness endpoint, for failures only
+	// a restart fixes, like a deadlocked worker. Other checks only decide
+	// readiness, since restarting does not fix an unreachable database.
+	Liveness bool
+
+	// Detail includes the error of a failing check in the report. Errors are
+	// omitted by default, since they may reveal internals like hostnames and
+	// credentials to anyone reaching the endpoints.
+	Detail bool
+}
+
+// Check statuses reported by the health endpoints.
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// healthReport is the JSON body of the health endpoints.
+type healthReport struct {
+	Status string                       `json:"status"`
+	Error  string                       `json:"error,omitempty"`
+	Checks map[string]healthCheckReport `json:"checks"`
+}
+
+// healthCheckReport is the result of a HealthCheck.
+type healthCheckReport struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HandleHealth registers health endpoints for orchestrators and load
+// balancers: a readiness endpoint at pattern, and a liveness endpoint at
+// pattern followed by "/live". A trailing slash of pattern is ignored, so
+// HandleHealth("/") registers "/" and "/live":
+//
+//	mux.HandleHealth("/healthz",
+//		webmux.HealthCheck{Name: "postgres", Check: db.PingContext},
+//		webmux.HealthCheck{Name: "worker", Check: worker.Alive, Liveness: true},
+//	)
+//
+// The readiness endpoint runs every check, and fails while the mux is not
+// ready, see SetReady and Shutdown. The liveness endpoint only runs the checks
+// marked with Liveness. Checks run concurrently with the context of the
+// request, and a check which panics fails. Both endpoints respond with the
+// aggregate status and the result of each check as JSON, with a 200 OK
+// status if all checks pass, or 503 Service Unavailable otherwise. The error
+// of a failing check is only reported for checks with Detail set, and
+// durations are measured with the clock of mux, see SetClock:
+//
+//	{"status":"fail","checks":{"postgres":{"status":"fail","error":"connection refused","duration":"2.1ms"}}}
+//
+// The endpoints are registered with AlwaysServe, so they report their status
+// while the mux is not ready. HandleHealth panics if a check has no name or
+// function, or if two checks have the same name.
+func (mux *ServeMux) HandleHealth(pattern string, checks ...HealthCheck) {
+	names := make(map[string]bool, len(checks))
+	var live []HealthCheck
+
+	for _, c := range checks {
+		if c.Name == "" || c.Check == nil {
+			panic("webmux: invalid health check")
+		}
+
+		if names[c.Name] {
+			panic(fmt.Sprintf("webmux: multiple health checks named %s", c.Name))
+		}
+
+		names[c.Name] = true
+
+		if c.Liveness {
+			live = append(live, c)
+		}
+	}
+
+	ready := HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var err error
+
+		if mux.draining.Load() {
+			err = ErrShuttingDown
+		} else if !mux.Ready() {
+			err = ErrNotReady
+		}
+
+		return mux.writeHealth(w, r, checks, err)
+	})
+
+	liveness := HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return mux.writeHealth(w, r, live, nil)
+	})
+
+	base := strings.TrimSuffix(pattern, "/")
+
+	mux.Handle(http.MethodGet, joinPattern(base, "/"), ready, AlwaysServe())
+	mux.Handle(http.MethodGet, base+"/live", liveness, AlwaysServe())
+}
+
+// writeHealth runs checks and writes the report. The report fails if err is
+// not nil, whatever the result of the checks.
+func (mux *ServeMux) writeHealth(w http.ResponseWriter, r *http.Request, checks []HealthCheck, err error) error {
+	report := healthReport{
+		Status: healthOK,
+		Checks: mux.runHealthChecks(r.Context(), checks),
+	}
+
+	if err != nil {
+		report.Status = healthFail
+		report.Error = err.Error()
+	}
+
+	for _, c := range report.Checks {
+		if c.Status != healthOK {
+			report.Status = healthFail
+		}
+	}
+
+	code := http.StatusOK
+
+	if report.Status != healthOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	return json.NewEncoder(w).Encode(report)
+}
+
+// runHealthChecks runs checks concurrently, and returns their results by name.
+func (mux *ServeMux) runHealthChecks(ctx context.Context, checks []HealthCheck) map[string]healthCheckReport {
+	results := make(map[string]healthCheckReport, len(checks))
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, c := range checks {
+		wg.Add(1)
+
+		go func(c HealthCheck) {
+			defer wg.Done()
+
+			start := mux.now()
+			err := runHealthCheck(ctx, c)
+			result := healthCheckReport{Status: healthOK, Duration: mux.now().Sub(start).String()}
+
+			if err != nil {
+				result.Status = healthFail
+			}
+
+			if err != nil && c.Detail {
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[c.Name] = result
+			mu.Unlock()
+		}(c)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// runHealthCheck runs c, turning a panic into an error, since it is not
+// running in the goroutine of the request.
+func runHealthCheck(ctx context.Context, c HealthCheck) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return c.Check(ctx)
+}
@@ -0,0 +1,137 @@
+package webmux_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+type healthReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Checks map[string]struct {
+		Status   string `json:"status"`
+		Error    string `json:"error"`
+		Duration string `json:"duration"`
+	} `json:"checks"`
+}
+
+func TestHandleHealth(t *testing.T) {
+	var dbErr error
+
+	mux := webmux.New()
+	mux.HandleHealth("/healthz",
+		webmux.HealthCheck{Name: "db", Check: func(ctx context.Context) error { return dbErr }, Detail: true},
+		webmux.HealthCheck{Name: "cache", Check: func(ctx context.Context) error { return dbErr }},
+		webmux.HealthCheck{Name: "worker", Check: func(ctx context.Context) error { return nil }, Liveness: true},
+	)
+
+	get := func(target string) (int, healthReport) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var report healthReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		return w.Code, report
+	}
+
+	code, report := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", report.Status)
+	assert.Equal(t, 3, len(report.Checks))
+
+	dbErr = errors.New("connection refused")
+
+	code, report = get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", report.Status)
+	assert.Equal(t, "connection refused", report.Checks["db"].Error)
+	assert.Equal(t, "fail", report.Checks["cache"].Status)
+	assert.Equal(t, "", report.Checks["cache"].Error)
+	assert.Equal(t, "ok", report.Checks["worker"].Status)
+
+	// Liveness ignores dependencies and readiness
+	mux.SetReady(false)
+
+	code, report = get("/healthz/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, len(report.Checks))
+
+	dbErr = nil
+
+	code, report = get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, webmux.ErrNotReady.Error(), report.Error)
+}
+
+func TestHandleHealthRoot(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		want    []string
+	}{
+		{"/", []string{"/", "/live"}},
+		{"/healthz/", []string{"/healthz", "/healthz/live"}},
+	}
+
+	for _, tc := range tests {
+		mux := webmux.New()
+		mux.HandleHealth(tc.pattern, webmux.HealthCheck{Name: "db", Check: func(ctx context.Context) error { return nil }})
+
+		var patterns []string
+
+		for _, route := range mux.Routes() {
+			patterns = append(patterns, route.Pattern)
+		}
+
+		assert.Equal(t, tc.want, patterns)
+
+		for _, target := range tc.want {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusOK, w.Code, target)
+		}
+	}
+}
+
+func TestHandleHealthPanic(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleHealth("/healthz", webmux.HealthCheck{Name: "cache", Check: func(ctx context.Context) error { panic("boom") }, Detail: true})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"panic: boom"`)
+
+	check := webmux.HealthCheck{Name: "db", Check: func(ctx context.Context) error { return nil }}
+
+	assert.Panics(t, func() { webmux.New().HandleHealth("/healthz", check, check) })
+	assert.Panics(t, func() { webmux.New().HandleHealth("/healthz", webmux.HealthCheck{Name: "db"}) })
+}
+
+func TestHandleHealthClock(t *testing.T) {
+	clock := muxtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	mux := webmux.New()
+	mux.SetClock(clock)
+	mux.HandleHealth("/healthz", webmux.HealthCheck{Name: "db", Check: func(ctx context.Context) error {
+		clock.Advance(2 * time.Millisecond)
+		return nil
+	}})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	var report healthReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "2ms", report.Checks["db"].Duration)
+}
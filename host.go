@@ -0,0 +1,190 @@
+package webmux
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrHostNotAllowed is returned by ServeMux when the Host of a request is not allowed.
+var ErrHostNotAllowed = errors.New("webmux: host not allowed")
+
+// AllowedHosts restricts mux to requests for the given hosts. Requests with
+// any other Host header are rejected with a 400 Bad Request [HTTPError]
+// wrapping ErrHostNotAllowed before they are dispatched, preventing host
+// header poisoning of generated URLs and cache keys.
+//
+// Hosts are compared case-insensitively, ignoring the port. A host starting
+// with "*." matches any subdomain, so "*.example.com" matches
+// "api.example.com" but not "example.com". Calling AllowedHosts with no hosts
+// allows any host, which is the default.
+func (mux *ServeMux) AllowedHosts(hosts ...string) {
+	allowed := make([]string, 0, len(hosts))
+
+	for _, h := range hosts {
+		allowed = append(allowed, strings.ToLower(h))
+	}
+
+	mux.allowedHosts = allowed
+}
+
+// hostAllowed returns true if the Host of r is allowed.
+func (mux *ServeMux) hostAllowed(r *http.Request) bool {
+	if len(mux.allowedHosts) == 0 {
+		return true
+	}
+
+	host := requestHost(r)
+
+	for _, allowed := range mux.allowedHosts {
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+
+			continue
+		}
+
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostRoot is the routing tree of the routes registered for a host with Host.
+type hostRoot struct {
+	host   string // as given to Host, like "api.example.com" or "*.example.com"
+	suffix string // for wildcard hosts, the suffix matched like ".example.com"
+	root   *node
+}
+
+// Host returns a RouteOption registering the route for requests to host only,
+// like "api.example.com". A host starting with "*." matches any subdomain,
+// so "*.example.com" matches "api.example.com" but not "example.com".
+// Hosts are compared case-insensitively, ignoring the port.
+//
+// Each host has its own routes. A request is matched against the routes of
+// its host, then those of the wildcard hosts matching it, with the longest
+// suffix first, and finally the routes registered without a host. The
+// routes of a host are only used if one of them matches the path, and the
+// usual rules for paths decide between them. So an exact host takes
+// precedence over a wildcard host, even when the path of the wildcard route
+// is more specific:
+//
+//	mux.Handle(http.MethodGet, "/:page", tenantPage, webmux.Host("*.example.com"))
+//	mux.Handle(http.MethodGet, "/about", about)
+//
+// Here "acme.example.com/about" is handled by tenantPage, and
+// "example.com/about" by about. Use Explain to see which route a request
+// matches and why.
+func Host(host string) RouteOption {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if host == "" || strings.Contains(host, ":") || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		panic(fmt.Sprintf("webmux: invalid host %q", host))
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.host = host
+	}
+}
+
+// requestHost returns the host of r, lower cased and without the port.
+func requestHost(r *http.Request) string {
+	host := strings.ToLower(r.Host)
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
+
+// hostTree returns the routing tree for host, adding it if it does not exist.
+// The tree of the empty host is the tree of the routes registered without a host.
+func (mux *ServeMux) hostTree(host string) *node {
+	if host == "" {
+		return mux.root
+	}
+
+	for _, h := range mux.hosts {
+		if h.host == host {
+			return h.root
+		}
+	}
+
+	h := &hostRoot{host: host, root: &node{}}
+
+	if suffix, ok := strings.CutPrefix(host, "*"); ok {
+		h.suffix = suffix
+	}
+
+	mux.hosts = append(mux.hosts, h)
+
+	// Exact hosts first, then wildcard hosts by decreasing suffix length
+	sort.SliceStable(mux.hosts, func(i, j int) bool {
+		a, b := mux.hosts[i], mux.hosts[j]
+
+		if (a.suffix == "") != (b.suffix == "") {
+			return a.suffix == ""
+		}
+
+		return len(a.suffix) > len(b.suffix)
+	})
+
+	return h.root
+}
+
+// matchHost returns true if the request host matches h.
+func (h *hostRoot) matchHost(host string) bool {
+	if h.suffix == "" {
+		return host == h.host
+	}
+
+	return strings.HasSuffix(host, h.suffix) && len(host) > len(h.suffix)
+}
+
+// route looks up the route for path in the trees of the hosts matching the
+// request host, in order of precedence, followed by the routes without a
+// host. It returns the host of the matching tree, or "" for the routes
+// without a host. If trace is not nil it is called with each decision.
+func (mux *ServeMux) route(r *http.Request, path string, match *MuxMatch, trace func(string)) (*MuxMatch, string) {
+	if len(mux.hosts) > 0 {
+		host := requestHost(r)
+
+		for _, h := range mux.hosts {
+			if !h.matchHost(host) {
+				continue
+			}
+
+			if found := mux.lookupIn(h.root, path, match); found != nil {
+				if trace != nil {
+					trace(fmt.Sprintf("host %s matches %s, path %s matches %s", h.host, host, path, found.pattern))
+				}
+
+				return found, h.host
+			}
+
+			if trace != nil {
+				trace(fmt.Sprintf("host %s matches %s, no route for path %s", h.host, host, path))
+			}
+		}
+	}
+
+	found := mux.lookupPath(path, match)
+
+	if trace != nil {
+		if found != nil {
+			trace(fmt.Sprintf("path %s matches %s", path, found.pattern))
+		} else {
+			trace(fmt.Sprintf("no route for path %s", path))
+		}
+	}
+
+	return found, ""
+}
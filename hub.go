@@ -0,0 +1,381 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors returned by Hub and HubClient.
+var (
+	ErrHubClosed    = errors.New("webmux: hub closed")
+	ErrClientClosed = errors.New("webmux: hub client closed")
+	ErrSlowConsumer = errors.New("webmux: slow consumer")
+)
+
+// Defaults for HubOptions.
+const (
+	defaultHubQueueSize    = 64
+	defaultHubWriteTimeout = 10 * time.Second
+)
+
+// A HubConn is a connection managed by a Hub, typically an upgraded websocket
+// connection. Hub does not depend on a websocket library, any library can be
+// used by adapting its connection type.
+type HubConn interface {
+	// WriteMessage writes msg to the connection, aborting if ctx is done.
+	WriteMessage(ctx context.Context, msg []byte) error
+
+	// Close closes the connection.
+	Close() error
+}
+
+// HubOptions configures a Hub.
+type HubOptions struct {
+	// QueueSize is the number of messages queued per connection. A connection
+	// whose queue is full is considered a slow consumer and is disconnected,
+	// so that it cannot hold up the other connections.
+	// If zero, a default of 64 is used.
+	QueueSize int
+
+	// WriteTimeout is the maximum time to write a single message.
+	// If zero, a default of 10 seconds is used.
+	WriteTimeout time.Duration
+}
+
+// Hub manages a set of connections, such as websocket connections, grouped
+// into rooms for broadcasting messages.
+//
+// Each connection has a bounded send queue written by its own goroutine, so
+// broadcasting never blocks on a slow connection. Connections which fall
+// behind are disconnected with ErrSlowConsumer.
+//
+// A typical websocket handler registers the upgraded connection, joins rooms,
+// and reads from the connection until it fails:
+//
+//	client, err := hub.Register(conn)
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	client.Join("room:" + match.Param("id"))
+//
+//	for {
+//		msg, err := conn.Read(ctx)
+//		if err != nil {
+//			return nil
+//		}
+//		hub.Broadcast("room:"+match.Param("id"), msg)
+//	}
+type Hub struct {
+	opts    HubOptions
+	mu      sync.Mutex
+	closed  bool
+	clients map[*HubClient]bool
+	rooms   map[string]map[*HubClient]bool
+}
+
+// NewHub returns a new Hub.
+func NewHub(opts HubOptions) *Hub {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultHubQueueSize
+	}
+
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = defaultHubWriteTimeout
+	}
+
+	return &Hub{
+		opts:    opts,
+		clients: make(map[*HubClient]bool),
+		rooms:   make(map[string]map[*HubClient]bool),
+	}
+}
+
+// Register adds conn to the hub, returning ErrHubClosed if the hub is shut down.
+// The connection is closed by the hub when the returned client is closed.
+func (h *Hub) Register(conn HubConn) (*HubClient, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &HubClient{
+		hub:    h,
+		conn:   conn,
+		queue:  make(chan []byte, h.opts.QueueSize),
+		rooms:  make(map[string]bool),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		cancel()
+		return nil, ErrHubClosed
+	}
+
+	h.clients[c] = true
+
+	go c.write()
+
+	return c, nil
+}
+
+// Broadcast queues msg to every connection in room, returning the number of
+// connections it was queued to. Slow consumers are disconnected.
+func (h *Hub) Broadcast(room string, msg []byte) int {
+	h.mu.Lock()
+	members := make([]*HubClient, 0, len(h.rooms[room]))
+
+	for c := range h.rooms[room] {
+		members = append(members, c)
+	}
+
+	h.mu.Unlock()
+
+	return sendAll(members, msg)
+}
+
+// BroadcastAll queues msg to every connection, returning the number of
+// connections it was queued to. Slow consumers are disconnected.
+func (h *Hub) BroadcastAll(msg []byte) int {
+	h.mu.Lock()
+	clients := make([]*HubClient, 0, len(h.clients))
+
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+
+	h.mu.Unlock()
+
+	return sendAll(clients, msg)
+}
+
+// sendAll sends msg to clients, returning the number of clients it was queued to.
+func sendAll(clients []*HubClient, msg []byte) int {
+	n := 0
+
+	for _, c := range clients {
+		if c.Send(msg) == nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Members returns the number of connections in room.
+func (h *Hub) Members(room string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.rooms[room])
+}
+
+// Len returns the number of connections in the hub.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.clients)
+}
+
+// Shutdown gracefully shuts down the hub. New connections are rejected, and
+// each connection is closed once its queued messages are written. If ctx is
+// done first, the remaining connections are closed immediately and the
+// context's error is returned.
+//
+// Shutdown is typically called alongside [http.Server.Shutdown], which does
+// not track hijacked connections like websockets.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*HubClient, 0, len(h.clients))
+
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.Close()
+	}
+
+	for _, c := range clients {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			for _, c := range clients {
+				c.abort(ErrHubClosed)
+			}
+
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// remove removes c from the hub and its rooms.
+func (h *Hub) remove(c *HubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, c)
+
+	for room := range c.rooms {
+		h.leave(c, room)
+	}
+}
+
+// leave removes c from room, deleting the room if it is empty.
+// The caller must hold h.mu.
+func (h *Hub) leave(c *HubClient, room string) {
+	delete(h.rooms[room], c)
+
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// HubClient is a connection registered with a Hub.
+type HubClient struct {
+	hub    *Hub
+	conn   HubConn
+	queue  chan []byte
+	rooms  map[string]bool // guarded by hub.mu
+	ctx    context.Context // canceled to abort writing
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the connection is closed
+
+	mu      sync.Mutex
+	closing bool
+	err     error
+}
+
+// Join adds the client to room.
+func (c *HubClient) Join(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	if _, ok := c.hub.clients[c]; !ok {
+		return
+	}
+
+	if c.hub.rooms[room] == nil {
+		c.hub.rooms[room] = make(map[*HubClient]bool)
+	}
+
+	c.hub.rooms[room][c] = true
+	c.rooms[room] = true
+}
+
+// Leave removes the client from room.
+func (c *HubClient) Leave(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	delete(c.rooms, room)
+	c.hub.leave(c, room)
+}
+
+// Send queues msg to be written to the client without blocking. If the queue
+// is full the client is disconnected and ErrSlowConsumer is returned.
+func (c *HubClient) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closing {
+		return ErrClientClosed
+	}
+
+	select {
+	case c.queue <- msg:
+		return nil
+	default:
+	}
+
+	c.closeLocked(ErrSlowConsumer)
+	c.cancel()
+
+	return ErrSlowConsumer
+}
+
+// Close closes the client once its queued messages are written.
+func (c *HubClient) Close() {
+	c.mu.Lock()
+	c.closeLocked(nil)
+	c.mu.Unlock()
+}
+
+// abort closes the client immediately with err, discarding queued messages.
+func (c *HubClient) abort(err error) {
+	c.mu.Lock()
+	c.closeLocked(err)
+	c.mu.Unlock()
+
+	c.cancel()
+}
+
+// closeLocked stops accepting messages. The caller must hold c.mu.
+func (c *HubClient) closeLocked(err error) {
+	if c.closing {
+		return
+	}
+
+	c.closing = true
+	c.err = err
+	close(c.queue)
+}
+
+// Done returns a channel that is closed when the connection has been closed.
+func (c *HubClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the client was closed, such as ErrSlowConsumer or a
+// write error, or nil if it is open or was closed with Close.
+func (c *HubClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// write writes queued messages to the connection until the queue is closed
+// or writing is aborted.
+func (c *HubClient) write() {
+	defer func() {
+		c.cancel()
+		c.conn.Close()
+		c.hub.remove(c)
+		close(c.done)
+	}()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-c.queue:
+			if !ok {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(c.ctx, c.hub.opts.WriteTimeout)
+			err := c.conn.WriteMessage(ctx, msg)
+			cancel()
+
+			if err != nil {
+				c.mu.Lock()
+				c.closeLocked(err)
+				c.mu.Unlock()
+
+				return
+			}
+		}
+	}
+}
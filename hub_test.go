@@ -0,0 +1,100 @@
+package webmux_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+// hubConn is a HubConn recording messages, optionally blocking writes until unblocked.
+type hubConn struct {
+	mu      sync.Mutex
+	msgs    []string
+	closed  bool
+	unblock chan struct{}
+}
+
+func (c *hubConn) WriteMessage(ctx context.Context, msg []byte) error {
+	if c.unblock != nil {
+		select {
+		case <-c.unblock:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.msgs = append(c.msgs, string(msg))
+
+	return nil
+}
+
+func (c *hubConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	return nil
+}
+
+func (c *hubConn) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.msgs...)
+}
+
+func TestHub(t *testing.T) {
+	hub := webmux.NewHub(webmux.HubOptions{QueueSize: 4})
+
+	a, b, slow := &hubConn{}, &hubConn{}, &hubConn{unblock: make(chan struct{})}
+
+	ca, err := hub.Register(a)
+	assert.NoError(t, err)
+	cb, err := hub.Register(b)
+	assert.NoError(t, err)
+	cslow, err := hub.Register(slow)
+	assert.NoError(t, err)
+
+	ca.Join("lobby")
+	cb.Join("lobby")
+	cb.Join("other")
+	cslow.Join("lobby")
+	cslow.Join("slow")
+
+	assert.Equal(t, 3, hub.Members("lobby"))
+
+	// At most one message is being written while the queue fills up
+	for i := 0; i < 6; i++ {
+		hub.Broadcast("slow", []byte("x"))
+	}
+
+	<-cslow.Done()
+	assert.IsError(t, cslow.Err(), webmux.ErrSlowConsumer)
+	assert.Equal(t, 2, hub.Members("lobby"))
+	assert.Equal(t, 0, hub.Members("slow"))
+
+	hub.Broadcast("lobby", []byte("1"))
+	hub.Broadcast("other", []byte("2"))
+	cb.Leave("lobby")
+	hub.Broadcast("lobby", []byte("3"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, hub.Shutdown(ctx))
+	assert.Equal(t, []string{"1", "3"}, a.messages())
+	assert.Equal(t, []string{"1", "2"}, b.messages())
+	assert.True(t, a.closed && b.closed && slow.closed)
+	assert.Equal(t, 0, hub.Len())
+
+	_, err = hub.Register(&hubConn{})
+	assert.IsError(t, err, webmux.ErrHubClosed)
+}
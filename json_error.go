@@ -0,0 +1,124 @@
+package webmux
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// jsonError is the body of error responses rendered by JSONErrorHandler.
+type jsonError struct {
+	Error jsonErrorBody `json:"error"`
+}
+
+type jsonErrorBody struct {
+	Code    string           `json:"code"`             // status text in snake case, like "not_found"
+	Message string           `json:"message"`          // description of the error
+	Fields  []jsonFieldError `json:"fields,omitempty"` // field errors of client errors
+}
+
+type jsonFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// JSONErrorHandler returns an error handler rendering errors as JSON:
+//
+//	{"error":{"code":"not_found","message":"Not Found"}}
+//
+// Status codes are chosen like StatusError, and the code is the status text
+// in snake case. The message of client errors is the message of the error,
+// or of the error wrapped by an [HTTPError]. Server errors are logged, and
+// their details are only included in the message if debug is true, since
+// they may expose internal information.
+//
+// Client errors wrapping any *FieldError, like those of ValidateBody, list
+// them in the fields of the error:
+//
+//	{"error":{"code":"bad_request","message":"...","fields":[{"field":"$.age","message":"expected integer, got true"}]}}
+func JSONErrorHandler(debug bool) ErrorHandler {
+	return LocalizedJSONErrorHandler(debug, nil)
+}
+
+// LocalizedJSONErrorHandler is like JSONErrorHandler, translating the
+// messages of errors and their fields with messages in the language
+// negotiated from the Accept-Language header, see Messages.
+func LocalizedJSONErrorHandler(debug bool, messages Messages) ErrorHandler {
+	return ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		code := errorStatus(w, r, err)
+
+		if code >= http.StatusInternalServerError {
+			logError(r, err)
+		}
+
+		l := messages.localizer(w, r)
+
+		writeJSONError(w, code, l.translate(errorMessage(err, code, debug)), l.fields(errorFields(err, code)))
+	})
+}
+
+// errorMessage returns the message describing err to clients, which is the
+// status text for server errors unless debug is true.
+func errorMessage(err error, code int, debug bool) string {
+	if code >= http.StatusInternalServerError && !debug {
+		return http.StatusText(code)
+	}
+
+	if errors.Is(err, ErrMuxNotFound) {
+		return http.StatusText(code)
+	}
+
+	var httpErr *HTTPError
+
+	if !errors.As(err, &httpErr) {
+		return err.Error()
+	}
+
+	if httpErr.Err == nil {
+		return http.StatusText(code)
+	}
+
+	return httpErr.Err.Error()
+}
+
+// errorFields returns the field errors wrapped by err if it is a client error.
+func errorFields(err error, code int) []jsonFieldError {
+	if code >= http.StatusInternalServerError {
+		return nil
+	}
+
+	var fields []jsonFieldError
+
+	walkErrors(err, func(err error) {
+		if fieldErr, ok := err.(*FieldError); ok {
+			fields = append(fields, jsonFieldError{Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+	})
+
+	return fields
+}
+
+// writeJSONError writes a JSON error response with code, message and fields.
+func writeJSONError(w http.ResponseWriter, code int, message string, fields []jsonFieldError) {
+	body, _ := json.Marshal(jsonError{Error: jsonErrorBody{Code: errorCode(code), Message: message, Fields: fields}})
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	w.Write(append(body, '\n'))
+}
+
+// errorCode returns the status text of code in snake case, like "not_found".
+func errorCode(code int) string {
+	text := http.StatusText(code)
+
+	if text == "" {
+		return "error"
+	}
+
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+
+	return strings.ToLower(text)
+}
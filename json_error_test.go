@@ -0,0 +1,82 @@
+package webmux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestJSONErrorHandler(t *testing.T) {
+	newMux := func(debug bool) *webmux.ServeMux {
+		mux := webmux.New()
+		mux.HandleError(webmux.JSONErrorHandler(debug))
+		mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+			match, _ := webmux.FromContext(r.Context())
+
+			if _, err := match.ParamInt("id"); err != nil {
+				return webmux.NewHTTPError(http.StatusUnprocessableEntity, errors.New("id must be a number"))
+			}
+
+			return errors.New("database password is hunter2")
+		})
+
+		return mux
+	}
+
+	tests := []struct {
+		debug bool
+		path  string
+		code  int
+		body  string
+	}{
+		{false, "/missing", http.StatusNotFound, `{"error":{"code":"not_found","message":"Not Found"}}`},
+		{false, "/users/abc", http.StatusUnprocessableEntity, `{"error":{"code":"unprocessable_entity","message":"id must be a number"}}`},
+		{false, "/users/1", http.StatusInternalServerError, `{"error":{"code":"internal_server_error","message":"Internal Server Error"}}`},
+		{true, "/users/1", http.StatusInternalServerError, `{"error":{"code":"internal_server_error","message":"database password is hunter2"}}`},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		newMux(tt.debug).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, tt.body+"\n", w.Body.String())
+	}
+}
+
+func TestLocalizedJSONErrorHandler(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleError(webmux.LocalizedJSONErrorHandler(false, webmux.Messages{
+		"fr": {"invalid id": "identifiant invalide", "Internal Server Error": "Erreur interne du serveur"},
+	}))
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		return webmux.NewHTTPError(http.StatusBadRequest, errors.New("invalid id"))
+	})
+	mux.HandleFunc(http.MethodGet, "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database is down")
+	})
+
+	tests := []struct {
+		path, acceptLanguage, body string
+	}{
+		{"/users/x", "fr", `{"error":{"code":"bad_request","message":"identifiant invalide"}}` + "\n"},
+		{"/users/x", "en", `{"error":{"code":"bad_request","message":"invalid id"}}` + "\n"},
+		{"/fail", "fr-FR", `{"error":{"code":"internal_server_error","message":"Erreur interne du serveur"}}` + "\n"},
+		{"/missing", "fr", `{"error":{"code":"not_found","message":"Not Found"}}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Accept-Language", tt.acceptLanguage)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, tt.body, w.Body.String(), tt.path)
+	}
+}
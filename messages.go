@@ -0,0 +1,82 @@
+package webmux
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Messages is a catalog of translated error messages, mapping language tags,
+// like "fr" or "pt-BR", to translations of English messages, keyed by the
+// English message:
+//
+//	webmux.Messages{
+//		"fr": {
+//			"Not Found":     "Introuvable",
+//			"invalid email": "adresse e-mail invalide",
+//		},
+//	}
+//
+// Status texts, like "Not Found", are the messages of errors without a more
+// specific message and the titles of HTML error pages, see ErrorPage.
+// The error handlers configured with Messages render errors in the language
+// negotiated from the Accept-Language header of the request, and in English
+// if no language of the catalog is acceptable or a message has no
+// translation. The codes of JSON errors, like "not_found", are never
+// translated, so clients can rely on them.
+type Messages map[string]map[string]string
+
+// localizer translates the messages of a single response.
+type localizer struct {
+	messages map[string]string // translations of the negotiated language, nil if there is none
+}
+
+// localizer returns the localizer for the response to r, setting the
+// Content-Language and Vary headers of w if m is not empty.
+func (m Messages) localizer(w http.ResponseWriter, r *http.Request) localizer {
+	if len(m) == 0 {
+		return localizer{}
+	}
+
+	AddVary(w.Header(), "Accept-Language")
+
+	offers := make([]string, 0, len(m))
+
+	for tag := range m {
+		offers = append(offers, tag)
+	}
+
+	// Map iteration order is random, so ties are broken by tag
+	sort.Strings(offers)
+
+	tag := negotiateLanguage(r.Header.Get("Accept-Language"), offers)
+
+	if tag == "" {
+		return localizer{}
+	}
+
+	w.Header().Set("Content-Language", tag)
+
+	return localizer{messages: m[tag]}
+}
+
+// translate returns the translation of message, or message if it has none.
+func (l localizer) translate(message string) string {
+	if translated, ok := l.messages[message]; ok {
+		return translated
+	}
+
+	return message
+}
+
+// fields returns fields with their messages translated.
+func (l localizer) fields(fields []jsonFieldError) []jsonFieldError {
+	if l.messages == nil {
+		return fields
+	}
+
+	for i := range fields {
+		fields[i].Message = l.translate(fields[i].Message)
+	}
+
+	return fields
+}
@@ -0,0 +1,54 @@
+package webmux
+
+// WithMeta returns a RouteOption attaching the metadata key and value to the
+// handler, which middleware can read from the MuxMatch with Meta. This keeps
+// route properties like required authorization scopes or rate limit tiers
+// next to the registration instead of in a parallel route table:
+//
+//	mux.Handle(http.MethodGet, "/reports", reports, webmux.WithMeta("scope", "reports:read"))
+//
+// Metadata belongs to the handler registered with the option, so the
+// handlers of other methods of the same pattern have their own metadata.
+// If key is given more than once the last value is used.
+func WithMeta(key, value string) RouteOption {
+	return func(cfg *routeConfig) {
+		if cfg.meta == nil {
+			cfg.meta = make(map[string]string)
+		}
+
+		cfg.meta[key] = value
+	}
+}
+
+// Meta returns the value of the metadata key attached with WithMeta to the
+// handler serving the request, or false if there is none.
+// For requests dispatched to a mounted mux, the metadata of the mounted route
+// takes precedence over the metadata of the Mount registration.
+func (m *MuxMatch) Meta(key string) (string, bool) {
+	v, ok := m.meta[key]
+
+	return v, ok
+}
+
+// mergeMeta returns the metadata of parent overridden by the metadata of child.
+func mergeMeta(parent, child map[string]string) map[string]string {
+	if len(parent) == 0 {
+		return child
+	}
+
+	if len(child) == 0 {
+		return parent
+	}
+
+	merged := make(map[string]string, len(parent)+len(child))
+
+	for k, v := range parent {
+		merged[k] = v
+	}
+
+	for k, v := range child {
+		merged[k] = v
+	}
+
+	return merged
+}
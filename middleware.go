@@ -0,0 +1,172 @@
+package webmux
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+)
+
+// Middleware wraps a Handler to add behavior before or after it is called,
+// such as authentication, logging, or setting headers.
+type Middleware func(next Handler) Handler
+
+// Use appends middleware to the chain applied to every handler matched by
+// mux, including handlers registered before Use is called. Middleware are
+// applied in the order they were added, so the first is the outermost and
+// sees the request first:
+//
+//	mux.Use(logging, auth)
+//
+// Middleware run after a route is matched, so the MuxMatch is available
+// with FromContext, and before any route specific middleware. Requests which
+// do not match a route are handled by the error handler without calling the
+// middleware, unless handlers were registered with HandleNotFound or
+// HandleMethodNotAllowed, which run through the middleware.
+//
+// Middleware wrap each handler once, when it is registered, and the handlers
+// they return serve many requests concurrently. Middleware added after
+// handlers were registered wrap them on every request until Freeze is called,
+// so Use is best called before registering routes. Use is safe to call while
+// mux serves requests, which see the middleware added before they were
+// routed.
+func (mux *ServeMux) Use(mw ...Middleware) {
+	for _, m := range mw {
+		if m == nil {
+			panic("webmux: nil middleware")
+		}
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	// The slice is copied, since requests being served may hold the current one
+	n := len(mux.middleware)
+	mux.middleware = append(mux.middleware[:n:n], mw...)
+}
+
+// Named returns mw with name as its name in the middleware chains listed by
+// Routes and Explain, see MiddlewareName. Middleware are otherwise named
+// after their function, which is not telling for middleware configured at
+// runtime:
+//
+//	mux.Use(webmux.Named("ratelimit", limiter.Middleware))
+func Named(name string, mw Middleware) Middleware {
+	if mw == nil {
+		panic("webmux: nil middleware")
+	}
+
+	return func(next Handler) Handler {
+		if probe, ok := next.(*nameProbe); ok {
+			probe.name = name
+			return probe
+		}
+
+		return mw(next)
+	}
+}
+
+// nameProbe is the handler passed to the middleware returned by Named to get
+// their name.
+type nameProbe struct {
+	name string
+}
+
+func (*nameProbe) ServeHTTPErr(http.ResponseWriter, *http.Request) error {
+	return nil
+}
+
+// namedFunc is the code pointer shared by the middleware returned by Named.
+var namedFunc = reflect.ValueOf(Named("", func(next Handler) Handler { return next })).Pointer()
+
+// chain applies the middleware of mux to h.
+func (mux *ServeMux) chain(h Handler) Handler {
+	mux.mu.RLock()
+	mw := mux.middleware
+	mux.mu.RUnlock()
+
+	return chain(mw, h)
+}
+
+// chain applies mw to h, the first being the outermost.
+func chain(mw []Middleware, h Handler) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+
+	return h
+}
+
+// precompose wraps the handlers of e with mw, the middleware of the mux, so
+// that requests are dispatched to the chains built once at registration
+// instead of wrapping the handler on every request. Middleware added with
+// Use afterwards are applied per request until Freeze precomposes again.
+func (e *muxEntry) precompose(mw []Middleware) {
+	e.chained = make(map[string]Handler, len(e.handlers))
+
+	for method, h := range e.handlers {
+		e.chained[method] = chain(mw, h)
+	}
+
+	// The slices may be shared with the entry being replaced
+	for method, handlers := range e.conditional {
+		handlers = slices.Clone(handlers)
+
+		for i := range handlers {
+			handlers[i].chained = chain(mw, handlers[i].handler)
+		}
+
+		e.conditional[method] = handlers
+	}
+
+	e.chainDepth = len(mw)
+}
+
+// chainFor returns h wrapped by mw, the middleware of the mux, which is
+// chained if it was precomposed with all of mw.
+func (e *muxEntry) chainFor(h, chained Handler, mw []Middleware) Handler {
+	if chained != nil && e.chainDepth == len(mw) {
+		return chained
+	}
+
+	return chain(mw, h)
+}
+
+// precompose precomposes the entries of n and its descendants, see
+// muxEntry.precompose.
+func (n *node) precompose(mw []Middleware) {
+	// Entries are copied on write, since requests being served may hold them
+	if n.entry != nil && n.entry.chainDepth != len(mw) {
+		entry := n.entry.clone()
+		entry.precompose(mw)
+		n.entry = entry
+	}
+
+	for _, child := range n.children {
+		child.precompose(mw)
+	}
+
+	for _, c := range n.constrained {
+		c.node.precompose(mw)
+	}
+}
+
+// WithMiddleware returns a RouteOption applying middleware to the handler of a
+// single registration, for behavior like authentication or rate limiting which
+// only a few routes need:
+//
+//	mux.Handle(http.MethodPost, "/admin/users", createUser, webmux.WithMiddleware(auth))
+//
+// Route middleware run after the middleware added with Use, in the order
+// they are given, so the first is the outermost. When a registration covers
+// several methods, the middleware wrap each of their handlers.
+func WithMiddleware(mw ...Middleware) RouteOption {
+	for _, m := range mw {
+		if m == nil {
+			panic("webmux: nil middleware")
+		}
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.middleware = append(cfg.middleware, mw...)
+	}
+}
@@ -0,0 +1,196 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// mountState is the state of a request dispatched to a mounted ServeMux.
+type mountState struct {
+	mux    *ServeMux // the mounted mux
+	parent *MuxMatch // the match of the mount in the parent mux
+	path   string    // the path to route in the mounted mux
+	mount  *mount
+}
+
+// mount is a ServeMux mounted in another ServeMux.
+type mount struct {
+	sub     *ServeMux
+	entries sync.Map // [2]string{parent, child} patterns to *composedEntry
+}
+
+// composedEntry is the entry of a mounted route, composed from the entries
+// of the mount and of the route. Registrations replace entries, so it is
+// valid as long as both are current.
+type composedEntry struct {
+	parent, child *muxEntry
+	entry         *muxEntry
+}
+
+// Mount registers sub to handle all requests for prefix and the paths below
+// it. The mounted mux routes the remaining path, so routes registered as
+// "/users/:id" in sub serve "/api/users/:id" when sub is mounted at "/api":
+//
+//	api := webmux.New()
+//	api.Handle(http.MethodGet, "/users/:id", getUser)
+//
+//	mux.Mount("/api", api)
+//
+// Handlers of the mounted mux see a single MuxMatch, whose pattern is the
+// composed pattern "/api/users/:id", and whose params include those of both
+// the prefix and the route, so metrics and logs need not know about the
+// mount. The prefix may contain params, as in "/orgs/:org". Requests for
+// methods the mounted route does not handle fail with a 405 Method Not
+// Allowed listing the methods of the mounted route.
+//
+// The middleware of mux run before the middleware of sub. Errors of sub are
+// returned to mux, and handled by the error handler of mux.
+func (mux *ServeMux) Mount(prefix string, sub *ServeMux, opts ...RouteOption) {
+	if sub == nil {
+		panic("webmux: nil mux")
+	}
+
+	if sub == mux {
+		panic("webmux: mux mounted in itself")
+	}
+
+	prefix = strings.TrimSuffix(cleanPath(mux.normalizePattern(prefix)), "/")
+
+	if strings.Contains(prefix, "*") {
+		panic("webmux: invalid mount prefix " + prefix)
+	}
+
+	m := &mount{sub: sub}
+
+	if prefix != "" {
+		mux.register(AnyMethod(), prefix, m, opts)
+	}
+
+	mux.register(AnyMethod(), prefix+"/*", m, opts)
+}
+
+// ServeHTTPErr dispatches r to the mounted mux. It implements Handler.
+func (m *mount) ServeHTTPErr(w http.ResponseWriter, r *http.Request) error {
+	parent, ok := FromContext(r.Context())
+
+	if !ok {
+		return m.sub.ServeHTTPErr(w, r)
+	}
+
+	path := "/"
+
+	if strings.HasSuffix(parent.Pattern(), "/*") {
+		path = "/" + parent.RawParam("*")
+	}
+
+	state := &mountState{
+		mux:    m.sub,
+		parent: &MuxMatch{muxEntry: parent.muxEntry, values: append([]string(nil), parent.values...), raw: append([]string(nil), parent.raw...), meta: parent.meta},
+		path:   path,
+		mount:  m,
+	}
+
+	return m.sub.ServeHTTPErr(w, r.WithContext(context.WithValue(r.Context(), mountKey, state)))
+}
+
+// mounted returns the mount state of r if it was dispatched to mux by Mount.
+func (mux *ServeMux) mounted(r *http.Request) *mountState {
+	state, ok := r.Context().Value(mountKey).(*mountState)
+
+	if !ok || state.mux != mux {
+		return nil
+	}
+
+	return state
+}
+
+// compose returns the match combining the mount in the parent mux with the
+// match of the mounted mux.
+func (s *mountState) compose(match *MuxMatch) *MuxMatch {
+	parent := s.parent.muxEntry
+	n := len(parent.params)
+
+	// The wildcard capturing the remaining path is replaced by the route params
+	if strings.HasSuffix(parent.pattern, "/*") {
+		n--
+	}
+
+	key := [2]string{parent.pattern, match.pattern}
+	cached, ok := s.mount.entries.Load(key)
+
+	if !ok || cached.(*composedEntry).parent != parent || cached.(*composedEntry).child != match.muxEntry {
+		composed := *match.muxEntry
+		composed.pattern = joinPattern(strings.TrimSuffix(parent.pattern, "/*"), match.pattern)
+		composed.params = append(append([]string(nil), parent.params[:n]...), match.params...)
+
+		cached = &composedEntry{parent: parent, child: match.muxEntry, entry: &composed}
+		s.mount.entries.Store(key, cached)
+	}
+
+	values := make([]string, 0, n+len(match.values))
+	values = append(values, s.parent.values[:n]...)
+	values = append(values, match.values...)
+
+	var raw []string
+
+	if len(s.parent.raw) > 0 || len(match.raw) > 0 {
+		raw = make([]string, 0, len(values))
+		raw = append(raw, rawValues(s.parent)[:n]...)
+		raw = append(raw, rawValues(match)...)
+	}
+
+	return &MuxMatch{
+		muxEntry:      cached.(*composedEntry).entry,
+		values:        values,
+		raw:           raw,
+		meta:          mergeMeta(s.parent.meta, match.meta),
+		noAutoOptions: match.noAutoOptions,
+	}
+}
+
+// rawValues returns the values of m as escaped in the request path.
+func rawValues(m *MuxMatch) []string {
+	if len(m.raw) == 0 {
+		return m.values
+	}
+
+	return m.raw
+}
+
+// joinPattern joins the mount prefix and the pattern of a mounted route.
+func joinPattern(prefix, pattern string) string {
+	if pattern == "/" && prefix != "" {
+		return prefix
+	}
+
+	return prefix + pattern
+}
+
+// allowError is returned by a mounted ServeMux when the route does not handle
+// the request method, since the match in the context is that of the mount.
+type allowError struct {
+	allow string
+}
+
+func (e *allowError) Error() string {
+	return ErrMuxNotFound.Error()
+}
+
+func (e *allowError) Unwrap() error {
+	return ErrMuxNotFound
+}
+
+// allowedMethods returns the methods allowed by the route of err if it is a
+// method mismatch in a mounted ServeMux.
+func allowedMethods(err error) (string, bool) {
+	var allowErr *allowError
+
+	if errors.As(err, &allowErr) {
+		return allowErr.allow, true
+	}
+
+	return "", false
+}
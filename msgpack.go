@@ -0,0 +1,361 @@
+package webmux
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// errMsgpackExt is returned when decoding MessagePack extension types, which are not supported.
+var errMsgpackExt = errors.New("msgpack: extension types are not supported")
+
+// MsgpackCodec is a Codec for MessagePack.
+// Values are converted using their JSON representation, so json struct tags
+// and [json.Marshaler] implementations are honored. Extension types are not supported.
+type MsgpackCodec struct {
+	// MaxDepth limits the nesting of decoded arrays and maps. If zero,
+	// DefaultMaxCodecDepth is used.
+	MaxDepth int
+}
+
+// Decode implements Codec.
+func (c MsgpackCodec) Decode(r io.Reader, v any) error {
+	tree, err := decodeMsgpack(bufio.NewReader(r), codecDepth(c.MaxDepth))
+
+	if err != nil {
+		return err
+	}
+
+	return fromJSONValue(tree, v)
+}
+
+// Encode implements Codec.
+func (MsgpackCodec) Encode(w io.Writer, v any) error {
+	tree, err := toJSONValue(v)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := appendMsgpack(nil, tree)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+// appendMsgpack appends the MessagePack encoding of the JSON value v to b.
+func appendMsgpack(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+
+		return append(b, 0xc2), nil
+	case json.Number:
+		kind, i, u, f, err := parseNumber(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case numberInt:
+			if i >= 0 {
+				return appendMsgpackUint(b, uint64(i)), nil
+			}
+
+			return appendMsgpackInt(b, i), nil
+		case numberUint:
+			return appendMsgpackUint(b, u), nil
+		}
+
+		b = append(b, 0xcb)
+
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case string:
+		n := len(v)
+
+		switch {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+
+		return append(b, v...), nil
+	case []any:
+		n := len(v)
+
+		switch {
+		case n < 16:
+			b = append(b, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+		}
+
+		var err error
+
+		for _, e := range v {
+			if b, err = appendMsgpack(b, e); err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	case map[string]any:
+		n := len(v)
+
+		switch {
+		case n < 16:
+			b = append(b, 0x80|byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+		}
+
+		var err error
+
+		for _, k := range sortedKeys(v) {
+			if b, err = appendMsgpack(b, k); err != nil {
+				return nil, err
+			}
+
+			if b, err = appendMsgpack(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+}
+
+// appendMsgpackUint appends the smallest MessagePack encoding of u to b.
+func appendMsgpackUint(b []byte, u uint64) []byte {
+	switch {
+	case u < 128:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u))
+	}
+
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+}
+
+// appendMsgpackInt appends the smallest MessagePack encoding of the negative integer i to b.
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+// decodeMsgpack decodes a single MessagePack value from r, nesting arrays
+// and maps at most depth levels.
+func decodeMsgpack(r *bufio.Reader, depth int) (any, error) {
+	c, err := r.ReadByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return decodeMsgpackMap(r, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return decodeMsgpackArray(r, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return readString(r, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readUint(r, 1<<(c-0xc4))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return readBytes(r, int(n))
+	case 0xca:
+		n, err := readUint(r, 4)
+
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := readUint(r, 8)
+
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return readUint(r, 1<<(c-0xcc))
+	case 0xd0:
+		n, err := readUint(r, 1)
+
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := readUint(r, 2)
+
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := readUint(r, 4)
+
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := readUint(r, 8)
+
+		return int64(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readUint(r, 1<<(c-0xd9))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return readString(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := readUint(r, 2<<(c-0xdc))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeMsgpackArray(r, int(n), depth)
+	case 0xde, 0xdf:
+		n, err := readUint(r, 2<<(c-0xde))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeMsgpackMap(r, int(n), depth)
+	}
+
+	return nil, errMsgpackExt
+}
+
+// decodeMsgpackArray decodes n MessagePack values from r.
+func decodeMsgpackArray(r *bufio.Reader, n, depth int) ([]any, error) {
+	if depth == 0 {
+		return nil, errCodecDepth("msgpack")
+	}
+
+	a := make([]any, 0, min(n, 1024))
+
+	for i := 0; i < n; i++ {
+		v, err := decodeMsgpack(r, depth-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		a = append(a, v)
+	}
+
+	return a, nil
+}
+
+// decodeMsgpackMap decodes n MessagePack key value pairs from r.
+func decodeMsgpackMap(r *bufio.Reader, n, depth int) (map[string]any, error) {
+	if depth == 0 {
+		return nil, errCodecDepth("msgpack")
+	}
+
+	m := make(map[string]any, min(n, 1024))
+
+	for i := 0; i < n; i++ {
+		k, err := decodeMsgpack(r, depth-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := decodeMsgpack(r, depth-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		m[mapKey(k)] = v
+	}
+
+	return m, nil
+}
+
+// mapKey returns the JSON object key for a decoded map key.
+func mapKey(k any) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(k)
+}
+
+// readUint reads a big endian unsigned integer of size bytes from r.
+func readUint(r io.Reader, size int) (uint64, error) {
+	var buf [8]byte
+
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// readBytes reads exactly n bytes from r.
+func readBytes(r io.Reader, n int) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, int64(n)))
+
+	if err == nil && len(b) < n {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return b, err
+}
+
+// readString reads a string of n bytes from r.
+func readString(r io.Reader, n int) (string, error) {
+	b, err := readBytes(r, n)
+
+	return string(b), err
+}
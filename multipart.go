@@ -0,0 +1,250 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+)
+
+// Default limits of MultipartForm.
+const (
+	DefaultMaxFormValueBytes = 1 << 20
+	DefaultMaxFormValues     = 1000
+)
+
+// FilePart is a file of a multipart form, read from the request body as it
+// is streamed.
+type FilePart struct {
+	io.Reader
+	Field       string // name of the form field
+	Filename    string // name of the file on the client, as sent by the client
+	ContentType string // Content-Type of the part, as sent by the client
+	Header      textproto.MIMEHeader
+}
+
+// FileStore stores the files of multipart forms, like a directory or an
+// object storage bucket. StoreFile reads the file and returns a reference
+// to the stored file, like its path or key, which becomes the value of the
+// field in the form.
+type FileStore interface {
+	StoreFile(ctx context.Context, file *FilePart) (string, error)
+}
+
+// The FileStoreFunc type is an adapter to allow functions to be used as
+// file stores.
+type FileStoreFunc func(ctx context.Context, file *FilePart) (string, error)
+
+// StoreFile calls f(ctx, file).
+func (f FileStoreFunc) StoreFile(ctx context.Context, file *FilePart) (string, error) {
+	return f(ctx, file)
+}
+
+// MultipartForm reads multipart/form-data request bodies part by part,
+// streaming each file to the FileStore of its field instead of buffering it
+// in memory or temporary files like [http.Request.ParseMultipartForm]:
+//
+//	uploads := &webmux.MultipartForm{MaxFileBytes: 100 << 20}
+//	uploads.HandleFile("video", videoStore)
+//
+//	func upload(w http.ResponseWriter, r *http.Request) error {
+//		form, err := uploads.Parse(r)
+//		if err != nil {
+//			return err
+//		}
+//
+//		key := form.Get("video") // returned by videoStore
+//		// ...
+//	}
+//
+// The other values are collected in the returned Form, so they can be
+// validated and re-displayed like those of NewForm. A MultipartForm must
+// not be modified once used.
+type MultipartForm struct {
+	// MaxValueBytes limits the size of each value which is not a file. If
+	// zero, DefaultMaxFormValueBytes is used.
+	MaxValueBytes int64
+
+	// MaxValues limits the number of parts. If zero, DefaultMaxFormValues
+	// is used.
+	MaxValues int
+
+	// MaxFileBytes limits the size of each file. Zero means no limit.
+	MaxFileBytes int64
+
+	// Store stores the files of fields without a FileStore registered with
+	// HandleFile. If nil, such files are rejected.
+	Store FileStore
+
+	files map[string]FileStore // field to store
+}
+
+// HandleFile registers the store of the files of field.
+func (m *MultipartForm) HandleFile(field string, store FileStore) {
+	if store == nil {
+		panic("webmux: nil file store")
+	}
+
+	if m.files == nil {
+		m.files = make(map[string]FileStore)
+	}
+
+	m.files[field] = store
+}
+
+// Parse reads the multipart body of r, storing its files and returning a
+// Form with the values of the other fields and the references to the stored
+// files. Bodies which are not multipart/form-data are rejected with a 415
+// Unsupported Media Type [HTTPError], and values, files or parts exceeding
+// the limits with a 413 Request Entity Too Large wrapping ErrBodyTooLarge.
+// Malformed bodies and files of fields without a store result in a 400 Bad
+// Request. Errors of the stores are returned as is, and the files stored
+// before an error are not removed.
+func (m *MultipartForm) Parse(r *http.Request) (*Form, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		return nil, NewHTTPError(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+	}
+
+	mr, err := r.MultipartReader()
+
+	if err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	maxValueBytes, maxValues := m.MaxValueBytes, m.MaxValues
+
+	if maxValueBytes <= 0 {
+		maxValueBytes = DefaultMaxFormValueBytes
+	}
+
+	if maxValues <= 0 {
+		maxValues = DefaultMaxFormValues
+	}
+
+	form := &Form{Values: url.Values{}}
+
+	for n := 0; ; n++ {
+		part, err := mr.NextPart()
+
+		if errors.Is(err, io.EOF) {
+			return form, nil
+		}
+
+		if err != nil {
+			return nil, NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		if n == maxValues {
+			part.Close()
+			return nil, NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: more than %d parts", ErrBodyTooLarge, maxValues))
+		}
+
+		field := part.FormName()
+
+		if field == "" {
+			part.Close()
+			continue
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxValueBytes+1))
+			part.Close()
+
+			if err != nil {
+				return nil, NewHTTPError(http.StatusBadRequest, err)
+			}
+
+			if int64(len(value)) > maxValueBytes {
+				return nil, NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: value of %s exceeds %d bytes", ErrBodyTooLarge, field, maxValueBytes))
+			}
+
+			form.Values.Add(field, string(value))
+
+			continue
+		}
+
+		ref, err := m.storeFile(r.Context(), part)
+		part.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		form.Values.Add(field, ref)
+	}
+}
+
+// storeFile streams the file of part to the store of its field.
+func (m *MultipartForm) storeFile(ctx context.Context, part *multipart.Part) (string, error) {
+	field := part.FormName()
+	store, ok := m.files[field]
+
+	if !ok {
+		store = m.Store
+	}
+
+	if store == nil {
+		return "", NewHTTPError(http.StatusBadRequest, fmt.Errorf("webmux: unexpected file for field %s", field))
+	}
+
+	file := &FilePart{
+		Reader:      part,
+		Field:       field,
+		Filename:    part.FileName(),
+		ContentType: part.Header.Get("Content-Type"),
+		Header:      part.Header,
+	}
+
+	var limited *limitedFile
+
+	if m.MaxFileBytes > 0 {
+		limited = &limitedFile{r: part, n: m.MaxFileBytes}
+		file.Reader = limited
+	}
+
+	ref, err := store.StoreFile(ctx, file)
+
+	// The store may wrap the error of the reader, or ignore it
+	if limited != nil && limited.exceeded {
+		return "", NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: file of %s exceeds %d bytes", ErrBodyTooLarge, field, m.MaxFileBytes))
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+// limitedFile is a reader failing once more than n bytes are read.
+type limitedFile struct {
+	r        io.Reader
+	n        int64 // remaining bytes
+	exceeded bool
+}
+
+func (l *limitedFile) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+
+	if int64(n) > l.n {
+		l.exceeded = true
+		return int(l.n), ErrBodyTooLarge
+	}
+
+	l.n -= int64(n)
+
+	return n, err
+}
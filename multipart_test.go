@@ -0,0 +1,104 @@
+package webmux_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		assert.NoError(t, mw.WriteField(name, value))
+	}
+
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".txt")
+		assert.NoError(t, err)
+		fw.Write([]byte(content))
+	}
+
+	assert.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return r
+}
+
+func TestMultipartForm(t *testing.T) {
+	stored := map[string]string{}
+
+	store := webmux.FileStoreFunc(func(ctx context.Context, file *webmux.FilePart) (string, error) {
+		data, err := io.ReadAll(file)
+
+		if err != nil {
+			return "", err
+		}
+
+		key := "uploads/" + file.Filename
+		stored[key] = string(data)
+
+		return key, nil
+	})
+
+	m := &webmux.MultipartForm{MaxFileBytes: 16, MaxValueBytes: 8}
+	m.HandleFile("avatar", store)
+
+	form, err := m.Parse(newMultipartRequest(t, map[string]string{"name": "Ann"}, map[string]string{"avatar": "PNG data"}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Ann", form.Get("name"))
+	assert.Equal(t, "uploads/avatar.txt", form.Get("avatar"))
+	assert.Equal(t, "PNG data", stored["uploads/avatar.txt"])
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		files  map[string]string
+		code   int
+	}{
+		{"file too large", nil, map[string]string{"avatar": strings.Repeat("x", 17)}, http.StatusRequestEntityTooLarge},
+		{"value too large", map[string]string{"name": strings.Repeat("x", 9)}, nil, http.StatusRequestEntityTooLarge},
+		{"unexpected file", nil, map[string]string{"resume": "PDF"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Parse(newMultipartRequest(t, tt.fields, tt.files))
+
+			var httpErr *webmux.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, tt.code, httpErr.Code)
+		})
+	}
+
+	t.Run("default store", func(t *testing.T) {
+		m := &webmux.MultipartForm{Store: store}
+		form, err := m.Parse(newMultipartRequest(t, nil, map[string]string{"resume": "PDF"}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "uploads/resume.txt", form.Get("resume"))
+	})
+
+	t.Run("not multipart", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("name=Ann"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		_, err := m.Parse(r)
+
+		assert.IsError(t, err, webmux.ErrUnsupportedMediaType)
+	})
+}
@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ErrMuxNotFound is returned by ServeMux when a matching handler was not found.
@@ -14,8 +18,19 @@ var ErrMuxNotFound = errors.New("mux match not found")
 // ctxKey is an unexported type to prevent collisions.
 type ctxKey int
 
-// muxKey is the key for MuxMatch values in contexts.
-var muxKey ctxKey
+// Context keys for values stored by the mux.
+const (
+	muxKey      ctxKey = iota // key for MuxMatch values
+	geoKey                    // key for geoState values
+	deviceKey                 // key for DeviceProfile values
+	txKey                     // key for Tx values
+	timingKey                 // key for timingState values
+	batchKey                  // key marking batch sub-requests
+	mountKey                  // key for mountState values
+	storeKey                  // key for requestStore values
+	observerKey               // key for route observers, see ObserveRoute
+	notFoundKey               // key for NotFoundError values
+)
 
 // ServeMux is an HTTP request multiplexer.
 // It matches the method and URL of each incoming request against a list of
@@ -29,58 +44,134 @@ var muxKey ctxKey
 //   - Wildcards of the form "/users/*" match any string.
 //   - Named groups of the form "/users/:id" match any string like wildcards,
 //     but assign a name that can be used to lookup the matched segment.
+//   - Named groups may be constrained by a regular expression, as in
+//     "/users/:id(\d+)", which must match the whole segment.
 //
 // Placeholders may only appear between slashes, as in "/users/:id/profile",
-// or as the last path segment, as in "/images/*".
+// or as the last path segment, as in "/images/*". A wildcard may be followed
+// by more segments, as in "/files/*path/meta", in which case it captures as
+// many segments as possible while the rest of the pattern still matches.
 //
 // Requests are matched by first looking for an exact match, then falling back
 // to pattern matches. Thus the pattern "/users/new" would win over "/users/:id".
-// The weight of named and un-named parameters is the same.
+// The weight of named and un-named parameters is the same. Named groups with a
+// constraint are tried before those without, in the order they were
+// registered, so "/posts/:id(\d+)" wins over "/posts/:slug" for "/posts/1".
+// A constraint may not contain a slash.
+//
+// Request paths are cleaned before matching, so "//users" and "/a/../users"
+// match "/users", see SetRedirectCleanPath.
+//
+// Paths are split into segments before decoding them, so an escaped slash as
+// in "/users/john%2Fdoe" is part of a segment, and matches "/users/:id" with
+// an id of "john/doe". MuxMatch.RawParam returns the escaped values.
 //
 // More specific matches are prioritized over less specific matches. For example,
 // if both "/users" and "/users/:id" are registered, a request for "/users/1"
-// would match "/users/:id".
+// would match "/users/:id". The Priority route option overrides these rules
+// for overlapping patterns.
 //
 // If multiple routes are registered for the same method and pattern, even if
 // the parameter names are different, ServeMux will panic.
 //
 // [URL Pattern API]: https://developer.mozilla.org/en-US/docs/Web/API/URL_Pattern_API
 type ServeMux struct {
-	errHandler ErrorHandler
-	pool       *sync.Pool
-	root       *node
+	errHandler              ErrorHandler
+	geo                     GeoResolver
+	aliases                 map[string]*aliasSet // pattern to localized aliases
+	notReady                atomic.Bool
+	frozen                  bool
+	notFound                notFoundCounter
+	probes                  *ProbeOptions
+	allowedHosts            []string
+	middleware              []Middleware
+	notFoundHandler         Handler
+	methodNotAllowedHandler Handler
+	optionsHandler          Handler
+	noAutoOptions           bool
+	clock                   Clock
+	engine                  PatternEngine        // see SetPatternEngine, nil for the syntax of webmux
+	source                  string               // contribution being applied by Apply, if any
+	static                  map[string]*muxEntry // path to entries without parameters, built by Freeze
+	pool                    *sync.Pool
+	mu                      sync.RWMutex // guards the routing trees, see Handle
+	root                    *node
+	hosts                   []*hostRoot                      // trees of the routes registered with Host, in order of precedence
+	priorities              bool                             // some routes have a priority, see Priority
+	redirectCleanPath       bool                             // see SetRedirectCleanPath
+	disconnect              func(r *http.Request, err error) // see HandleDisconnect
+	methodOverride          *MethodOverrideOptions           // see SetMethodOverride
+	env                     string                           // see SetEnv
+	timeout                 time.Duration                    // see SetTimeout
+	inFlight                atomic.Int64                     // requests being served, see Shutdown
+	draining                atomic.Bool                      // see Shutdown
+	idle                    chan struct{}                    // signaled when the last request completes while draining
+	maxParams               atomic.Int32                     // most params of a route, to size the values of matches
+	envEvaluated            bool                             // routes were registered with Env
 }
 
 // New allocates and returns a new ServeMux ready for use.
 func New() *ServeMux {
-	return &ServeMux{
+	mux := &ServeMux{
 		errHandler: StatusErrorHandler(),
-		pool: &sync.Pool{
-			New: func() any {
-				return new(MuxMatch)
-			},
+		root:       &node{},
+		idle:       make(chan struct{}, 1),
+	}
+
+	// Matches are recycled with their values, which are sized for the route
+	// with the most params, so matching does not allocate once the pool is warm
+	mux.pool = &sync.Pool{
+		New: func() any {
+			return &MuxMatch{values: make([]string, 0, mux.maxParams.Load())}
 		},
-		root: &node{},
 	}
+
+	return mux
 }
 
 // Handle registers the handler for the given method and pattern.
-// If a handler already exists for method and pattern, Handle panics.
-func (mux *ServeMux) Handle(method, pattern string, handler Handler) {
-	mux.HandleMethods(Methods(method), pattern, handler)
+// If a handler already exists for method and pattern, Handle panics, unless
+// the handler is conditional (see When).
+//
+// Routes may be registered while mux is serving requests, unless it is
+// frozen. Requests dispatched before the registration completes use the
+// previous routes. See Unhandle for removing routes.
+func (mux *ServeMux) Handle(method, pattern string, handler Handler, opts ...RouteOption) {
+	mux.HandleMethods(Methods(method), pattern, handler, opts...)
+}
+
+// HandlePattern registers the handler for a pattern combining the method and
+// path, like "GET /users/:id", as accepted by [http.ServeMux] since Go 1.22.
+// A pattern without a method, like "/users/:id", matches any method.
+// Host names in patterns are not supported, HandlePattern panics if the path
+// does not start with a slash.
+func (mux *ServeMux) HandlePattern(pattern string, handler Handler, opts ...RouteOption) {
+	methods := AnyMethod()
+	path := pattern
+
+	if method, rest, ok := strings.Cut(pattern, " "); ok && !strings.HasPrefix(method, "/") {
+		methods = Methods(method)
+		path = strings.TrimLeft(rest, " \t")
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		panic(fmt.Sprintf("webmux: invalid pattern %q", pattern))
+	}
+
+	mux.HandleMethods(methods, path, handler, opts...)
 }
 
 // HandleFunc registers the handler function for the given method and pattern.
-func (mux *ServeMux) HandleFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request) error) {
+func (mux *ServeMux) HandleFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request) error, opts ...RouteOption) {
 	if handler == nil {
 		panic("webmux: nil handler")
 	}
 
-	mux.HandleMethods(Methods(method), pattern, HandlerFunc(handler))
+	mux.HandleMethods(Methods(method), pattern, HandlerFunc(handler), opts...)
 }
 
-// Handle registers the handler for the given methods and pattern.
-func (mux *ServeMux) HandleMethods(methods MethodSet, pattern string, handler Handler) {
+// HandleMethods registers the handler for the given methods and pattern.
+func (mux *ServeMux) HandleMethods(methods MethodSet, pattern string, handler Handler, opts ...RouteOption) {
 	if len(methods) == 0 {
 		panic("webmux: empty method set")
 	}
@@ -92,9 +183,41 @@ func (mux *ServeMux) HandleMethods(methods MethodSet, pattern string, handler Ha
 		panic("webmux: nil handler")
 	}
 
+	mux.register(methods, mux.normalizePattern(pattern), handler, opts)
+}
+
+// register registers handler for methods and pattern, which is in the syntax
+// of webmux, with the route options opts.
+func (mux *ServeMux) register(methods MethodSet, pattern string, handler Handler, opts []RouteOption) {
+	cfg := newRouteConfig(opts)
+
+	for locale, alias := range cfg.aliases {
+		cfg.aliases[locale] = mux.normalizePattern(alias)
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if !mux.envAllowed(cfg) {
+		return
+	}
+
+	mux.handle(methods, pattern, handler, cfg)
+
+	if len(cfg.aliases) > 0 {
+		mux.registerAliases(methods, pattern, handler, cfg)
+	}
+}
+
+// handle registers handler for methods and pattern with the route configuration cfg.
+func (mux *ServeMux) handle(methods MethodSet, pattern string, handler Handler, cfg *routeConfig) {
+	if mux.frozen {
+		panic(fmt.Sprintf("webmux: registration of %s after Freeze", pattern))
+	}
+
 	path := cleanPath(pattern)
 	params := make([]string, 0)
-	current := mux.root
+	current := mux.hostTree(cfg.host)
 
 	for path != "" {
 		head, tail := shiftPath(path)
@@ -103,9 +226,21 @@ func (mux *ServeMux) HandleMethods(methods MethodSet, pattern string, handler Ha
 			break
 		}
 
-		if head[0] == ':' || head[0] == '*' {
+		if head[0] == ':' {
+			name, expr := splitParam(head)
+			params = append(params, name)
+
+			if expr != "" {
+				current = current.constrainedChild(expr, true)
+				path = tail
+
+				continue
+			}
+
+			head = ":"
+		} else if head[0] == '*' {
 			params = append(params, head[1:])
-			head = string(head[0])
+			head = "*"
 		}
 
 		next, ok := current.children[head]
@@ -119,6 +254,11 @@ func (mux *ServeMux) HandleMethods(methods MethodSet, pattern string, handler Ha
 		path = tail
 	}
 
+	if n := int32(len(params)); n > mux.maxParams.Load() {
+		mux.maxParams.Store(n)
+	}
+
+	// Entries are copied on write, since requests being served may use them
 	entry := current.entry
 
 	if entry == nil {
@@ -127,22 +267,46 @@ func (mux *ServeMux) HandleMethods(methods MethodSet, pattern string, handler Ha
 			params:  params,
 			methods: Methods(http.MethodOptions),
 		}
+	} else {
+		entry = entry.clone()
+	}
+
+	if cfg.alwaysServe {
+		entry.alwaysServe = true
+	}
+
+	if cfg.priority != 0 {
+		entry.setPriority(cfg)
+		mux.priorities = true
+	}
+
+	handler = cfg.wrap(handler)
+	cfg.source = mux.source
+
+	if cfg.ttl > 0 || entry.expiring {
+		cfg.now = mux.now()
+	}
 
-		current.entry = entry
+	if cfg.ttl > 0 {
+		cfg.expires = cfg.now.Add(cfg.ttl)
+		entry.expiring = true
 	}
 
 	for _, method := range methods {
-		entry.setHandler(method, handler)
+		entry.setHandler(method, handler, cfg)
 	}
+
+	entry.precompose(mux.middleware)
+	current.entry = entry
 }
 
 // HandleMethodsFunc registers the handler function for the given methods and pattern.
-func (mux *ServeMux) HandleMethodsFunc(methods MethodSet, pattern string, handler func(http.ResponseWriter, *http.Request) error) {
+func (mux *ServeMux) HandleMethodsFunc(methods MethodSet, pattern string, handler func(http.ResponseWriter, *http.Request) error, opts ...RouteOption) {
 	if handler == nil {
 		panic("webmux: nil handler")
 	}
 
-	mux.HandleMethods(methods, pattern, HandlerFunc(handler))
+	mux.HandleMethods(methods, pattern, HandlerFunc(handler), opts...)
 }
 
 // HandleError registers the error handler for mux.
@@ -155,27 +319,99 @@ func (mux *ServeMux) HandleErrorFunc(errHandler ErrorHandlerFunc) {
 	mux.errHandler = ErrorHandlerFunc(errHandler)
 }
 
-// Lookup finds the handlers matching the URL of r.
+// Lookup finds the handlers matching the host and URL of r.
 func (mux *ServeMux) Lookup(r *http.Request) *MuxMatch {
 	match := &MuxMatch{}
 
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
 	return mux.lookup(r, match)
 }
 
+// LookupPath finds the handlers matching path. Unlike Lookup no request is
+// needed, which is useful for validating paths before dispatching them.
+// Only the routes registered without a host are considered. The path is
+// escaped like [url.URL.EscapedPath], and its segments are decoded after
+// splitting it, see MuxMatch.Param. The path is cleaned like request paths,
+// see SetRedirectCleanPath.
+func (mux *ServeMux) LookupPath(path string) *MuxMatch {
+	match := &MuxMatch{}
+
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	return mux.lookupPath(cleanPath(path), match)
+}
+
 func (mux *ServeMux) lookup(r *http.Request, match *MuxMatch) *MuxMatch {
-	path := r.URL.Path
+	found, _ := mux.route(r, cleanPath(r.URL.EscapedPath()), match, nil)
+
+	return found
+}
+
+func (mux *ServeMux) lookupPath(path string, match *MuxMatch) *MuxMatch {
+	// Fast path for exact matches once frozen, unless a route may take priority
+	if entry, ok := mux.static[path]; ok && !mux.priorities {
+		match.noAutoOptions = mux.noAutoOptions
+		match.muxEntry = entry
+		return match
+	}
+
+	return mux.lookupIn(mux.root, path, match)
+}
+
+// lookupIn finds the handlers matching path in the tree root.
+// The path is escaped, as by [url.URL.EscapedPath], and the values of the
+// params are decoded once the route is found.
+func (mux *ServeMux) lookupIn(root *node, path string, match *MuxMatch) *MuxMatch {
+	var found *MuxMatch
+
+	if !mux.priorities {
+		found = mux.lookupTree(root, path, match)
+	} else {
+		// n is taken before lookupTree appends the values of its route.
+		n := len(match.values)
+		found = lookupPriority(root, path, match, mux.lookupTree(root, path, match), n)
+	}
+
+	if found != nil {
+		found.decodeValues()
+	}
+
+	return found
+}
+
+// lookupTree finds the handlers matching path in the tree root by the usual
+// rules, ignoring the priority of routes.
+func (mux *ServeMux) lookupTree(root *node, path string, match *MuxMatch) *MuxMatch {
+	match.noAutoOptions = mux.noAutoOptions
 
 	// Fast path when there aren't any path segments
-	if path == "/" && mux.root.entry != nil {
-		match.muxEntry = mux.root.entry
+	if path == "/" && root.entry != nil {
+		match.muxEntry = root.entry
 		return match
 	}
 
-	current := mux.root
-	values := match.values
-	greedy := false
+	current, values := root.match(path, match.values)
+
+	// If the last segment has no entry there is no match
+	if current == nil || current.entry == nil {
+		return nil
+	}
+
+	match.muxEntry = current.entry
+	match.values = values
 
-	for path != "" && !greedy {
+	return match
+}
+
+// match walks the tree n along path, returning the node reached and values
+// with the values of the params appended, or nil if path does not match.
+func (n *node) match(path string, values []string) (*node, []string) {
+	current := n
+
+	for path != "" {
 		head, tail := shiftPath(path)
 
 		if head == "" {
@@ -183,9 +419,19 @@ func (mux *ServeMux) lookup(r *http.Request, match *MuxMatch) *MuxMatch {
 		}
 
 		// Get the next node matching this path segment exactly
-		next, ok := current.children[head]
+		segment := unescapeSegment(head)
+		next, ok := current.children[segment]
+
+		// If no exact match, fallback to a param with a matching constraint
+		if !ok && len(current.constrained) > 0 {
+			next, ok = current.matchConstrained(segment)
+
+			if ok {
+				values = append(values, head)
+			}
+		}
 
-		// If no exact match, fallback to a param
+		// Then to a param without constraint
 		if !ok {
 			next, ok = current.children[":"]
 
@@ -199,80 +445,243 @@ func (mux *ServeMux) lookup(r *http.Request, match *MuxMatch) *MuxMatch {
 			next, ok = current.children["*"]
 
 			if ok {
-				values = append(values, head+tail)
-				greedy = true
+				return next.matchWildcard(head+tail, values)
 			}
 		}
 
 		if !ok {
-			return nil
+			return nil, values
 		}
 
 		current = next
 		path = tail
 	}
 
-	// If the last segment has no entry there is no match
-	if current.entry == nil {
-		return nil
-	}
-
-	match.muxEntry = current.entry
-	match.values = values
-
-	return match
+	return current, values
 }
 
 // ServeHTTPErr dispatches the request to the handler whose method and pattern
 // most closely matches the request URL, forwarding any errors.
 func (mux *ServeMux) ServeHTTPErr(w http.ResponseWriter, r *http.Request) error {
+	return mux.serve(w, r, false)
+}
+
+// serve dispatches the request like ServeHTTPErr. If handleErr is true,
+// errors are passed to the error handler before the request completes, with
+// the MuxMatch and request store of the request in the context.
+func (mux *ServeMux) serve(w http.ResponseWriter, r *http.Request, handleErr bool) error {
+	mux.inFlight.Add(1)
+	defer mux.end()
+
 	match := mux.pool.Get().(*MuxMatch)
 	match.Reset()
+
+	// A handler still running after its timeout keeps the match, see WithTimeout
+	detached := false
+
 	defer func() {
-		mux.pool.Put(match)
+		if !detached {
+			mux.pool.Put(match)
+		}
 	}()
 
-	found := mux.lookup(r, match)
+	if !mux.hostAllowed(r) {
+		return mux.fail(w, r, NewHTTPError(http.StatusBadRequest, ErrHostNotAllowed), handleErr)
+	}
+
+	path := r.URL.EscapedPath()
+	mounted := mux.mounted(r)
+
+	if mux.methodOverride != nil && mounted == nil {
+		var err error
+
+		if r, err = overrideMethod(r, mux.methodOverride); err != nil {
+			return mux.fail(w, r, err, handleErr)
+		}
+	}
+
+	if mounted != nil {
+		path = mounted.path
+	} else if clean := cleanPath(path); clean != path {
+		if mux.redirectCleanPath {
+			redirectCleanPath(w, r, clean)
+			return nil
+		}
+
+		path = clean
+	}
+
+	mux.mu.RLock()
+	found, _ := mux.route(r, path, match, nil)
+	mw := mux.middleware
+	mux.mu.RUnlock()
+
+	// The time is only needed for routes registered with WithTTL
+	var now time.Time
+
+	if found != nil && match.expiring {
+		now = mux.now()
+
+		if match.expired(now) {
+			found = nil
+		}
+	}
+
+	if observe, ok := r.Context().Value(observerKey).(func(string)); ok && found != nil {
+		if mounted != nil {
+			observe(mounted.compose(match).pattern)
+		} else {
+			observe(match.pattern)
+		}
+	}
 
 	if found == nil {
-		return ErrMuxNotFound
+		mux.detectProbe(r)
+
+		notFound := mux.notFoundError(r, path, mounted)
+		r = r.WithContext(context.WithValue(r.Context(), notFoundKey, notFound))
+
+		if mux.notFoundHandler != nil {
+			return mux.fail(w, r, mux.serveFallback(w, r, mux.notFoundHandler, nil, http.StatusNotFound), handleErr)
+		}
+
+		return mux.fail(w, r, notFound, handleErr)
+	}
+
+	// matched returns r with the match in the context, for the error handler
+	matched := func() *http.Request {
+		if mounted != nil {
+			return r.WithContext(NewContext(r.Context(), mounted.compose(match)))
+		}
+
+		return r.WithContext(NewContext(r.Context(), match))
 	}
 
-	h := match.Handler(r.Method)
+	if mux.notReady.Load() && !match.alwaysServe {
+		return mux.fail(w, matched(), NewHTTPError(http.StatusServiceUnavailable, ErrNotReady), handleErr)
+	}
+
+	if mux.draining.Load() && !match.alwaysServe {
+		return mux.fail(w, matched(), NewHTTPError(http.StatusServiceUnavailable, ErrShuttingDown), handleErr)
+	}
+
+	if mux.geo != nil {
+		r = r.WithContext(context.WithValue(r.Context(), geoKey, &geoState{resolver: mux.geo}))
+	}
+
+	h, meta, timeout := match.handlerFor(r, r.Method, now, mw)
 
 	if h == nil && r.Method == http.MethodHead {
-		h = match.Handler(http.MethodGet)
+		h, meta, timeout = match.handlerFor(r, http.MethodGet, now, mw)
 	}
 
-	if h == nil && r.Method == http.MethodOptions {
-		w.Header().Add("Allow", match.Methods().String())
+	match.meta = meta
+
+	if h == nil && r.Method == http.MethodOptions && !mux.noAutoOptions {
+		w.Header().Add("Allow", match.Allow())
+
+		if mux.optionsHandler != nil {
+			return mux.fail(w, matched(), mux.serveOptions(w, r, match, mounted), handleErr)
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 		return nil
 	}
 
+	// The handlers of the method all have predicates rejecting the request,
+	// so the route does not exist for it, see When
+	if h == nil && (match.hasHandler(r.Method) || r.Method == http.MethodHead && match.hasHandler(http.MethodGet)) {
+		return mux.fail(w, matched(), mux.notFoundError(r, path, mounted), handleErr)
+	}
+
+	if h == nil && mux.methodNotAllowedHandler != nil {
+		w.Header().Add("Allow", match.Allow())
+
+		fallbackMatch := match
+
+		if mounted != nil {
+			fallbackMatch = mounted.compose(match)
+		}
+
+		err := mux.serveFallback(w, r, mux.methodNotAllowedHandler, fallbackMatch, http.StatusMethodNotAllowed)
+
+		return mux.fail(w, matched(), err, handleErr)
+	}
+
+	if h == nil && mounted != nil {
+		return mux.fail(w, matched(), &allowError{allow: match.Allow()}, handleErr)
+	}
+
 	if h == nil {
-		return ErrMuxNotFound
+		return mux.fail(w, matched(), ErrMuxNotFound, handleErr)
+	}
+
+	ctx, release := newStoreContext(r, mux.clock)
+
+	defer func() {
+		if !detached {
+			release()
+		}
+	}()
+
+	if mounted != nil {
+		r = r.WithContext(NewContext(ctx, mounted.compose(match)))
+	} else {
+		r = r.WithContext(NewContext(ctx, match))
+	}
+
+	if timeout == inheritTimeout {
+		timeout = mux.timeout
 	}
 
-	r = r.WithContext(NewContext(r.Context(), match))
+	var err error
+
+	if timeout > 0 {
+		detached, err = serveTimeout(w, r, h, timeout)
+	} else {
+		err = h.ServeHTTPErr(w, r)
+	}
+
+	if errors.Is(err, ErrNotFound) {
+		mux.notFound.add(match.pattern)
+	}
 
-	return h.ServeHTTPErr(w, r)
+	return mux.fail(w, r, err, handleErr)
+}
+
+// fail passes err to the error handler of mux if handle is true and err is
+// not nil, and returns err. Errors of disconnected clients are passed to the
+// disconnect function instead, see HandleDisconnect.
+func (mux *ServeMux) fail(w http.ResponseWriter, r *http.Request, err error, handle bool) error {
+	if err == nil || !handle {
+		return err
+	}
+
+	if IsClientDisconnect(r, err) {
+		if mux.disconnect != nil {
+			mux.disconnect(r, err)
+		}
+
+		return err
+	}
+
+	mux.errHandler.ErrorHTTP(w, r, err)
+
+	return err
 }
 
 // ServeHttp implements [http.Handler] by dispatching the request to the handler
 // whose method and pattern most closely matches the request URL.
+// Errors are handled by the error handler of mux, see ErrorHandler.
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	err := mux.ServeHTTPErr(w, r)
-
-	if err != nil {
-		mux.errHandler.ErrorHTTP(w, r, err)
-	}
+	mux.serve(w, r, true)
 }
 
 // Type node is a single node in the routing tree.
 type node struct {
-	children map[string]*node // path segment to child node
-	entry    *muxEntry
+	children    map[string]*node   // path segment to child node
+	constrained []*constrainedNode // params with constraints, in registration order
+	entry       *muxEntry
 }
 
 // addChild adds child at path to n.
@@ -284,31 +693,151 @@ func (n *node) addChild(path string, child *node) {
 	n.children[path] = child
 }
 
+// find returns the node registered for pattern, or nil if there is none.
+func (n *node) find(pattern string) *node {
+	path := cleanPath(pattern)
+	current := n
+
+	for path != "" && current != nil {
+		head, tail := shiftPath(path)
+
+		if head == "" {
+			break
+		}
+
+		if head[0] == ':' {
+			if _, expr := splitParam(head); expr != "" {
+				current = current.constrainedChild(expr, false)
+				path = tail
+
+				continue
+			}
+		}
+
+		if head[0] == ':' || head[0] == '*' {
+			head = string(head[0])
+		}
+
+		current = current.children[head]
+		path = tail
+	}
+
+	return current
+}
+
 // muxEntry is a leaf node in the routing tree.
 // A muxEntry maps HTTP methods to handlers.
 type muxEntry struct {
-	pattern  string             // raw URL pattern
-	params   []string           // param names in the order they appear in pattern
-	handlers map[string]Handler // http Method to handler
-	methods  MethodSet          // cache of allowed HTTP methods
+	pattern     string                          // raw URL pattern
+	params      []string                        // param names in the order they appear in pattern
+	handlers    map[string]Handler              // http Method to handler
+	conditional map[string][]conditionalHandler // http Method to handlers registered with predicates
+	methods     MethodSet                       // cache of allowed HTTP methods
+	aliases     *aliasSet                       // localized aliases of pattern, if any
+	alwaysServe bool                            // served even when the mux is not ready
+	sources     map[string]string               // http Method to the contribution registering it, see Apply
+	meta        map[string]map[string]string    // http Method to the metadata of its handler, see WithMeta
+	middleware  map[string][]string             // http Method to the names of its route middleware, outermost first
+	timeouts    map[string]time.Duration        // http Method to the timeout of its handler, see WithTimeout
+	expires     map[string]time.Time            // http Method to the expiry of its handler, see WithTTL
+	expiring    bool                            // some handlers expire, see WithTTL
+	chained     map[string]Handler              // http Method to its handler wrapped by the middleware of the mux
+	chainDepth  int                             // number of middleware of the mux applied to the chained handlers
+	priority    int                             // priority of the route, see Priority
 }
 
 // setHandler sets the handler for method to handler.
-// If a handler is already registered, setHandler panics.
+// If a handler without predicates is already registered, setHandler panics.
 // If the method is "GET" and a handler is not registered for method "HEAD",
 // the handler is registered for "HEAD" as well.
-func (e *muxEntry) setHandler(method string, handler Handler) {
-	if e.handlers == nil {
-		e.handlers = make(map[string]Handler)
-	}
+func (e *muxEntry) setHandler(method string, handler Handler, cfg *routeConfig) {
+	if len(cfg.predicates) > 0 {
+		if e.conditional == nil {
+			e.conditional = make(map[string][]conditionalHandler)
+		}
+
+		e.conditional[method] = append(e.conditional[method], conditionalHandler{
+			predicates: cfg.predicates,
+			handler:    handler,
+			meta:       cfg.meta,
+			middleware: cfg.middlewareNames(),
+			timeout:    cfg.timeout,
+			expires:    cfg.expires,
+		})
+	} else {
+		if e.handlers == nil {
+			e.handlers = make(map[string]Handler)
+		}
 
-	_, ok := e.handlers[method]
+		_, ok := e.handlers[method]
 
-	if ok {
-		panic(fmt.Sprintf("web: multiple registrations for %s %s", method, e.pattern))
-	}
+		// An expired handler can be replaced, see WithTTL
+		if ok && e.expiring && e.handlerExpired(method, cfg.now) {
+			ok = false
+			delete(e.sources, method)
+			delete(e.meta, method)
+			delete(e.middleware, method)
+			delete(e.timeouts, method)
+			delete(e.chained, method)
+		}
+
+		if ok {
+			if cfg.source != "" || e.sources[method] != "" {
+				panic(fmt.Sprintf("webmux: multiple registrations for %s %s by %s, already registered by %s",
+					method, e.pattern, sourceName(cfg.source), sourceName(e.sources[method])))
+			}
 
-	e.handlers[method] = handler
+			panic(fmt.Sprintf("web: multiple registrations for %s %s", method, e.pattern))
+		}
+
+		e.handlers[method] = handler
+
+		if !cfg.expires.IsZero() {
+			if e.expires == nil {
+				e.expires = make(map[string]time.Time)
+			}
+
+			e.expires[method] = cfg.expires
+		} else {
+			delete(e.expires, method)
+		}
+
+		if cfg.source != "" {
+			if e.sources == nil {
+				e.sources = make(map[string]string)
+			}
+
+			e.sources[method] = cfg.source
+		}
+
+		if len(cfg.meta) > 0 {
+			if e.meta == nil {
+				e.meta = make(map[string]map[string]string)
+			}
+
+			e.meta[method] = cfg.meta
+		}
+
+		if names := cfg.middlewareNames(); len(names) > 0 {
+			if e.middleware == nil {
+				e.middleware = make(map[string][]string)
+			}
+
+			e.middleware[method] = names
+		} else {
+			delete(e.middleware, method)
+		}
+
+		if cfg.timeout != inheritTimeout {
+			if e.timeouts == nil {
+				e.timeouts = make(map[string]time.Duration)
+			}
+
+			e.timeouts[method] = cfg.timeout
+		} else {
+			delete(e.timeouts, method)
+		}
+	}
 
 	e.methods = e.methods.Add(method)
 
@@ -320,17 +849,65 @@ func (e *muxEntry) setHandler(method string, handler Handler) {
 // MuxMatch represents a matched handler for a given request.
 // The MuxMatch provides access to the pattern that matched and the values
 // extracted from the path for any dynamic parameters that appear in the pattern.
+//
+// The MuxMatch of a request dispatched by ServeMux is recycled once the
+// handler returns, so it must not be retained; use Clone to keep it.
 type MuxMatch struct {
 	*muxEntry
-	values []string
+	values        []string
+	raw           []string          // values as escaped in the request path, empty if none were escaped
+	meta          map[string]string // metadata of the handler serving the request
+	noAutoOptions bool              // automatic OPTIONS handling is disabled for the mux
 }
 
 // Reset clears the MuxMatch for re-use.
 func (m *MuxMatch) Reset() {
 	m.muxEntry = nil
+	m.meta = nil
 	if m.values != nil {
 		m.values = m.values[0:0]
 	}
+	if m.raw != nil {
+		m.raw = m.raw[0:0]
+	}
+}
+
+// Clone returns a copy of m which remains valid after the handler returns,
+// for goroutines processing the match of a request out of band, like
+// asynchronous logging or auditing. The route and its handlers are shared,
+// since the routes of a mux are copied on write.
+func (m *MuxMatch) Clone() *MuxMatch {
+	if m == nil {
+		return nil
+	}
+
+	return &MuxMatch{
+		muxEntry:      m.muxEntry,
+		values:        slices.Clone(m.values),
+		raw:           slices.Clone(m.raw),
+		meta:          m.meta,
+		noAutoOptions: m.noAutoOptions,
+	}
+}
+
+// decodeValues decodes the escaped values of m, keeping the escaped values
+// for RawParam.
+func (m *MuxMatch) decodeValues() {
+	m.raw = m.raw[:0]
+
+	for i, v := range m.values {
+		u := unescapeSegment(v)
+
+		if u == v {
+			continue
+		}
+
+		if len(m.raw) == 0 {
+			m.raw = append(m.raw, m.values...)
+		}
+
+		m.values[i] = u
+	}
 }
 
 // Pattern returns the URL pattern for the match.
@@ -353,15 +930,38 @@ func (m *MuxMatch) Params() []string {
 }
 
 // Param returns the parameter value for the given placeholder name.
+// The value is decoded, so "/users/john%2Fdoe" matching "/users/:id" has an
+// id of "john/doe".
 func (m *MuxMatch) Param(name string) string {
+	return m.param(m.values, name)
+}
+
+// RawParam is like Param, but returns the value as escaped in the request
+// path, like "john%2Fdoe".
+func (m *MuxMatch) RawParam(name string) string {
+	if len(m.raw) == 0 {
+		return m.param(m.values, name)
+	}
+
+	return m.param(m.raw, name)
+}
+
+// param returns the value of the parameter name from values.
+func (m *MuxMatch) param(values []string, name string) string {
 	if m.muxEntry == nil {
 		return ""
 	}
 
-	// Special case for an un-named wildcard
+	// Special case for an un-named wildcard, which may be followed by params
 	if name == "*" {
-		if len(m.values) > 0 {
-			return m.values[len(m.values)-1]
+		for i := len(m.params) - 1; i >= 0; i-- {
+			if m.params[i] == "" {
+				return values[i]
+			}
+		}
+
+		if len(values) > 0 {
+			return values[len(values)-1]
 		}
 
 		return ""
@@ -370,7 +970,7 @@ func (m *MuxMatch) Param(name string) string {
 	// With only a few params this is faster than allocating a map
 	for i, k := range m.params {
 		if k == name {
-			return m.values[i]
+			return values[i]
 		}
 	}
 
@@ -396,6 +996,45 @@ func (m *MuxMatch) Handler(method string) Handler {
 	return m.handlers[method]
 }
 
+// handlerFor returns the handler registered for method whose predicates are
+// satisfied by r, falling back to the handler registered without predicates,
+// along with the metadata and timeout of the handler. The timeout is
+// inheritTimeout unless set with WithTimeout. Handlers expired at now are
+// skipped. The handler is wrapped by mw, the middleware of the mux.
+func (m *MuxMatch) handlerFor(r *http.Request, method string, now time.Time, mw []Middleware) (Handler, map[string]string, time.Duration) {
+	if m.muxEntry == nil {
+		return nil, nil, inheritTimeout
+	}
+
+	for _, c := range m.conditional[method] {
+		if !c.expires.IsZero() && !now.Before(c.expires) {
+			continue
+		}
+
+		if c.match(r) {
+			return m.chainFor(c.handler, c.chained, mw), c.meta, c.timeout
+		}
+	}
+
+	if m.expiring && m.handlerExpired(method, now) {
+		return nil, nil, inheritTimeout
+	}
+
+	timeout, ok := m.timeouts[method]
+
+	if !ok {
+		timeout = inheritTimeout
+	}
+
+	h, ok := m.handlers[method]
+
+	if !ok {
+		return nil, m.muxEntry.meta[method], timeout
+	}
+
+	return m.chainFor(h, m.chained[method], mw), m.muxEntry.meta[method], timeout
+}
+
 // NewContext returns a new Context that carries value u.
 func NewContext(ctx context.Context, m *MuxMatch) context.Context {
 	return context.WithValue(ctx, muxKey, m)
@@ -404,5 +1043,5 @@ func NewContext(ctx context.Context, m *MuxMatch) context.Context {
 // FromContext returns the MuxMatch value stored in ctx, if any.
 func FromContext(ctx context.Context) (*MuxMatch, bool) {
 	m, ok := ctx.Value(muxKey).(*MuxMatch)
-	return m, ok
+	return m, ok && m != nil
 }
@@ -1,10 +1,16 @@
 package webmux_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -190,6 +196,1403 @@ func TestServeMuxLookupMethodSetMatching(t *testing.T) {
 	assert.Equal(t, h, match.Handler(http.MethodPost))
 }
 
+func TestServeMuxConditionalRouting(t *testing.T) {
+	mux := webmux.New()
+	mux.SetGeoResolver(webmux.HeaderGeoResolver("CF-IPCountry"))
+
+	mux.Handle(http.MethodGet, "/checkout", newTestHandler("eu"), webmux.When(webmux.Geo("EU")))
+	mux.Handle(http.MethodGet, "/checkout", newTestHandler("default"))
+	mux.Handle(http.MethodGet, "/promo", newTestHandler("us"), webmux.When(webmux.Geo("US")))
+
+	var tests = []struct {
+		name    string
+		path    string
+		country string
+		want    string
+		code    int
+	}{
+		{"matching predicate", "/checkout", "DE", "eu", http.StatusOK},
+		{"fallback", "/checkout", "US", "default", http.StatusOK},
+		{"unknown region", "/checkout", "", "default", http.StatusOK},
+		{"no fallback", "/promo", "FR", "Not Found\n", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			r.Header.Set("CF-IPCountry", tc.country)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.code, w.Code)
+			assert.Equal(t, tc.want, w.Body.String())
+		})
+	}
+}
+
+func TestServeMuxAliases(t *testing.T) {
+	mux := webmux.New()
+
+	mux.Handle(http.MethodGet, "/products/:slug", newTestHandler("product"),
+		webmux.Alias("de", "/produkte/:slug"),
+		webmux.Alias("fr", "/produits/:slug"),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/produkte/tisch", nil)
+
+	match := mux.Lookup(r)
+
+	assert.NotZero(t, match)
+	assert.Equal(t, "/produkte/:slug", match.Pattern())
+	assert.Equal(t, "tisch", match.Param("slug"))
+	assert.Equal(t, "/produits/tisch", match.AliasPath("fr"))
+	assert.Equal(t, "/products/tisch", match.AliasPath("en"))
+
+	p, err := mux.Path("/products/:slug", "de", "ess tisch")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/produkte/ess%20tisch", p)
+
+	_, err = mux.Path("/products/:slug", "de")
+
+	assert.IsError(t, err, webmux.ErrPatternValues)
+}
+
+func TestServeMuxReadiness(t *testing.T) {
+	mux := webmux.New()
+
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	mux.Handle(http.MethodGet, "/healthz", newTestHandler("ok"), webmux.AlwaysServe())
+
+	mux.SetReady(false)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mux.SetReady(true)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServeMuxFreeze(t *testing.T) {
+	mux := webmux.New()
+
+	mux.Handle(http.MethodGet, "/users/new", newTestHandler("new"))
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("show"))
+
+	mux.Freeze()
+
+	for path, want := range map[string]string{
+		"/users/new":  "/users/new",
+		"/users/new/": "/users/new",
+		"/users/1":    "/users/:id",
+	} {
+		match := mux.Lookup(httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.NotZero(t, match)
+		assert.Equal(t, want, match.Pattern())
+	}
+
+	assert.Panics(t, func() {
+		mux.Handle(http.MethodGet, "/late", newTestHandler("late"))
+	})
+}
+
+func TestServeMuxFreezeConcurrent(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("show"))
+	mux.Use(func(next webmux.Handler) webmux.Handler { return next })
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				for _, path := range []string{"/users", "/users/1"} {
+					w := httptest.NewRecorder()
+					mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+					assert.Equal(t, http.StatusOK, w.Code)
+				}
+
+				mux.Frozen()
+			}
+		}()
+	}
+
+	mux.Freeze()
+	wg.Wait()
+
+	assert.True(t, mux.Frozen())
+}
+
+func TestServeMuxNotFoundCounts(t *testing.T) {
+	mux := webmux.New()
+
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("load user: %w", webmux.ErrNotFound)
+	})
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+strconv.Itoa(i), nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, map[string]int64{"/users/:id": 3}, mux.NotFoundCounts())
+}
+
+func TestServeMuxDetectProbes(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/.env", newTestHandler("honest"))
+
+	probes := make([]string, 0)
+
+	mux.DetectProbes(webmux.ProbeOptions{
+		Alert: func(r *http.Request, category string) {
+			probes = append(probes, category+" "+r.URL.Path)
+		},
+	})
+
+	for _, path := range []string{"/.env", "/wp-admin/setup.php", "/about", "/.git/config", "/static/../../etc/passwd"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, []string{"wordpress /wp-admin/setup.php", "secrets /.git/config", "traversal /static/../../etc/passwd"}, probes)
+}
+
+func TestServeMuxAllowedHosts(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/", newTestHandler("home"))
+	mux.AllowedHosts("example.com", "*.example.org")
+
+	for host, want := range map[string]int{
+		"example.com":      http.StatusOK,
+		"EXAMPLE.com:8080": http.StatusOK,
+		"api.example.org":  http.StatusOK,
+		"example.org":      http.StatusBadRequest,
+		"evil.com":         http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, want, w.Code, host)
+	}
+}
+
+func TestServeMuxHeaderPresets(t *testing.T) {
+	mux := webmux.New()
+
+	mux.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Cache-Control", "private")
+		w.Header().Add("Vary", "Cookie")
+		return nil
+	},
+		webmux.Header("X-Frame-Options", "DENY"),
+		webmux.Header("Cache-Control", "no-store"),
+		webmux.Vary("Accept", "accept-language"),
+		webmux.Vary("Accept"),
+	)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "private", w.Header().Get("Cache-Control"))
+	assert.Equal(t, []string{"Accept", "Accept-Language", "Cookie"}, w.Header().Values("Vary"))
+}
+
+func TestServeMuxDo(t *testing.T) {
+	mux := webmux.New()
+
+	mux.HandleFunc(http.MethodPost, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		m, _ := webmux.FromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Query", r.URL.Query().Get("q"))
+		_, err := fmt.Fprintf(w, "%s:%s", m.Param("id"), body)
+		return err
+	})
+	mux.HandleFunc(http.MethodGet, "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return webmux.NewHTTPError(http.StatusConflict, nil)
+	})
+
+	resp, err := mux.Do(context.Background(), http.MethodPost, "/users/1?q=x", strings.NewReader("body"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "x", resp.Header.Get("X-Query"))
+	assert.Equal(t, "1:body", string(resp.Body))
+
+	resp, err = mux.Do(context.Background(), http.MethodGet, "/fail", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = mux.Do(context.Background(), http.MethodGet, "/missing", nil)
+	assert.IsError(t, err, webmux.ErrMuxNotFound)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServeMuxDoMounted(t *testing.T) {
+	sub := webmux.New()
+	sub.HandleFunc(http.MethodGet, "/items/:id", func(w http.ResponseWriter, r *http.Request) error {
+		m, _ := webmux.FromContext(r.Context())
+		_, err := w.Write([]byte(m.Pattern() + "=" + m.Param("id")))
+		return err
+	})
+	sub.HandleFunc(http.MethodGet, "/proxy", func(w http.ResponseWriter, r *http.Request) error {
+		resp, err := sub.Do(r.Context(), http.MethodGet, "/items/1", nil)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(resp.Body)
+		return err
+	})
+
+	mux := webmux.New()
+	mux.Mount("/api", sub)
+
+	var patterns []string
+
+	r := httptest.NewRequest(http.MethodGet, "/api/proxy", nil)
+	r = webmux.ObserveRoute(r, func(pattern string) { patterns = append(patterns, pattern) })
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/items/:id=1", w.Body.String())
+	assert.Equal(t, []string{"/api/*", "/api/proxy"}, patterns)
+}
+
+func TestServeMuxUse(t *testing.T) {
+	mux := webmux.New()
+	calls := make([]string, 0)
+
+	trace := func(name string) webmux.Middleware {
+		return func(next webmux.Handler) webmux.Handler {
+			return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				m, _ := webmux.FromContext(r.Context())
+				calls = append(calls, name+" "+m.Pattern())
+				return next.ServeHTTPErr(w, r)
+			})
+		}
+	}
+
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+	mux.Use(trace("a"), trace("b"))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, "user", w.Body.String())
+	assert.Equal(t, []string{"a /users/:id", "b /users/:id"}, calls)
+}
+
+func TestServeMuxScheme(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/.well-known/acme-challenge/:token", newTestHandler("acme"), webmux.SchemeHTTP())
+	mux.Handle(http.MethodGet, "/account", newTestHandler("account"), webmux.SchemeHTTPS())
+
+	for target, want := range map[string]int{
+		"http://example.com/.well-known/acme-challenge/x":  http.StatusOK,
+		"https://example.com/.well-known/acme-challenge/x": http.StatusNotFound,
+		"http://example.com/account":                       http.StatusNotFound,
+		"https://example.com/account":                      http.StatusOK,
+	} {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.URL.Scheme = ""
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, want, w.Code, target)
+	}
+}
+
+func TestServeMuxAllowCIDR(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/internal", newTestHandler("internal"), webmux.AllowCIDR("10.0.0.0/8"), webmux.AllowCIDR("::1/128"))
+
+	for addr, want := range map[string]int{
+		"10.1.2.3:1234":        http.StatusOK,
+		"[::1]:1234":           http.StatusOK,
+		"[::ffff:10.0.0.1]:80": http.StatusOK,
+		"192.168.1.1:1234":     http.StatusForbidden,
+		"invalid":              http.StatusForbidden,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/internal", nil)
+		r.RemoteAddr = addr
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, want, w.Code, addr)
+	}
+
+	assert.Panics(t, func() { webmux.AllowCIDR("10.0.0.0") })
+}
+
+func TestServeMuxMount(t *testing.T) {
+	var pattern, params string
+
+	record := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		match, _ := webmux.FromContext(r.Context())
+		pattern = match.Pattern()
+		params = strings.Join(match.Params(), ",") + "=" + match.Param("org") + "," + match.Param("id")
+
+		return nil
+	})
+
+	api := webmux.New()
+	api.Handle(http.MethodGet, "/", record)
+	api.Handle(http.MethodGet, "/users/:id", record)
+	api.Handle(http.MethodPut, "/users/:id", record)
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/orgs/:org/health", newTestHandler("health"))
+	mux.Mount("/orgs/:org", api)
+
+	tests := []struct {
+		method, path    string
+		code            int
+		pattern, params string
+		allow           string
+	}{
+		{http.MethodGet, "/orgs/acme/users/1", http.StatusOK, "/orgs/:org/users/:id", "org,id=acme,1", ""},
+		{http.MethodGet, "/orgs/acme", http.StatusOK, "/orgs/:org", "org=acme,", ""},
+		{http.MethodGet, "/orgs/acme/health", http.StatusOK, "", "", ""},
+		{http.MethodDelete, "/orgs/acme/users/1", http.StatusMethodNotAllowed, "", "", "OPTIONS, GET, HEAD, PUT"},
+		{http.MethodGet, "/orgs/acme/missing", http.StatusNotFound, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		pattern, params = "", ""
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		assert.Equal(t, tt.pattern, pattern, tt.path)
+		assert.Equal(t, tt.params, params, tt.path)
+		assert.Equal(t, tt.allow, w.Header().Get("Allow"), tt.path)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/orgs/acme/users/1", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "OPTIONS, GET, HEAD, PUT", w.Header().Get("Allow"))
+
+	// Routes registered after serving replace the composed entry
+	api.Handle(http.MethodPost, "/users/:id", record)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orgs/acme/users/2", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/orgs/:org/users/:id", pattern)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/orgs/acme/users/1", nil))
+
+	assert.Equal(t, "OPTIONS, GET, HEAD, PUT, POST", w.Header().Get("Allow"))
+}
+
+func TestServeMuxConstraints(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/posts/new", newTestHandler("new"))
+	mux.Handle(http.MethodGet, `/posts/:id(\d+)`, newTestHandler("id"))
+	mux.Handle(http.MethodGet, "/posts/:slug([a-z-]+)", newTestHandler("slug"))
+	mux.Handle(http.MethodGet, "/posts/:other", newTestHandler("other"))
+	mux.Handle(http.MethodGet, `/users/:id(\d+)/posts/:slug([a-z-]+)`, newTestHandler("user post"))
+
+	tests := []struct {
+		path, body string
+		code       int
+	}{
+		{"/posts/new", "new", http.StatusOK},
+		{"/posts/123", "id", http.StatusOK},
+		{"/posts/hello-world", "slug", http.StatusOK},
+		{"/posts/Hello_World", "other", http.StatusOK},
+		{"/users/1/posts/hello", "user post", http.StatusOK},
+		{"/users/one/posts/hello", "", http.StatusNotFound},
+		{"/users/1/posts/123", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		assert.Equal(t, tt.code, w.Code, tt.path)
+
+		if tt.code == http.StatusOK {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+
+	match := mux.LookupPath("/users/1/posts/hello")
+
+	assert.Equal(t, []string{"id", "slug"}, match.Params())
+	assert.Equal(t, "1", match.Param("id"))
+
+	var patterns []string
+
+	mux.Walk(func(method, pattern string, handler webmux.Handler) error {
+		patterns = append(patterns, pattern)
+		return nil
+	})
+
+	assert.Equal(t, []string{"/posts/new", `/posts/:id(\d+)`, "/posts/:slug([a-z-]+)", "/posts/:other", `/users/:id(\d+)/posts/:slug([a-z-]+)`}, patterns)
+
+	assert.Panics(t, func() { mux.Handle(http.MethodGet, "/bad/:id([)", newTestHandler("bad")) })
+	assert.Panics(t, func() { mux.Handle(http.MethodGet, `/posts/:id(\d+)`, newTestHandler("again")) })
+}
+
+func TestServeMuxStore(t *testing.T) {
+	type userKey struct{}
+
+	mux := webmux.New()
+	mux.Use(func(next webmux.Handler) webmux.Handler {
+		return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			assert.True(t, webmux.Set(r.Context(), userKey{}, "matt"))
+
+			return next.ServeHTTPErr(w, r)
+		})
+	})
+
+	api := webmux.New()
+	api.HandleFunc(http.MethodGet, "/user", func(w http.ResponseWriter, r *http.Request) error {
+		user, ok := webmux.Get[string](r.Context(), userKey{})
+		assert.True(t, ok)
+
+		_, ok = webmux.Get[int](r.Context(), userKey{})
+		assert.False(t, ok)
+
+		_, err := io.WriteString(w, user)
+
+		return err
+	})
+
+	mux.Mount("/api", api)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user", nil))
+
+		assert.Equal(t, "matt", w.Body.String())
+	}
+
+	assert.False(t, webmux.Set(context.Background(), userKey{}, "matt"))
+
+	_, ok := webmux.Get[string](context.Background(), userKey{})
+	assert.False(t, ok)
+}
+
+func TestMuxMatchTypedParams(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodGet, "/users/:id/:flag/:uuid", func(w http.ResponseWriter, r *http.Request) error {
+		match, _ := webmux.FromContext(r.Context())
+
+		id, err := match.ParamInt("id")
+
+		if err != nil {
+			return err
+		}
+
+		id64, err := match.ParamInt64("id")
+
+		if err != nil {
+			return err
+		}
+
+		flag, err := match.ParamBool("flag")
+
+		if err != nil {
+			return err
+		}
+
+		uuid, err := match.ParamUUID("uuid")
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, id, id64, flag, uuid)
+
+		return nil
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42/true/6BA7B810-9DAD-11D1-80B4-00C04FD430C8", http.StatusOK, "42 42 true 6ba7b810-9dad-11d1-80b4-00c04fd430c8\n"},
+		{"/users/abc/true/6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusBadRequest, ""},
+		{"/users/1/maybe/6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusBadRequest, ""},
+		{"/users/1/false/6ba7b810-9dad-11d1-80b4-00c04fd430cz", http.StatusBadRequest, ""},
+		{"/users/1/false/6ba7b8109dad11d180b400c04fd430c8", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		assert.Equal(t, tt.code, w.Code, tt.path)
+
+		if tt.code == http.StatusOK {
+			assert.Equal(t, tt.body, w.Body.String())
+		}
+	}
+
+	match := mux.LookupPath("/users/abc/true/x")
+	_, err := match.ParamInt("id")
+
+	var paramErr *webmux.ParamError
+
+	assert.True(t, errors.As(err, &paramErr))
+	assert.Equal(t, "id", paramErr.Name)
+	assert.Equal(t, "abc", paramErr.Value)
+	assert.True(t, errors.Is(err, webmux.ErrInvalidParam))
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestServeMuxHandleNotFound(t *testing.T) {
+	var calls []string
+
+	mux := webmux.New()
+	mux.Use(func(next webmux.Handler) webmux.Handler {
+		return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			calls = append(calls, r.URL.Path)
+			return next.ServeHTTPErr(w, r)
+		})
+	})
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+	mux.HandleNotFound(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, ok := webmux.FromContext(r.Context())
+		assert.False(t, ok)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(w, `{"error":"not found"}`)
+
+		return err
+	}))
+	mux.HandleMethodNotAllowed(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		match, _ := webmux.FromContext(r.Context())
+		assert.Equal(t, "/users/:id", match.Pattern())
+
+		return nil
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"error":"not found"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "OPTIONS, GET, HEAD", w.Header().Get("Allow"))
+	assert.Equal(t, []string{"/missing", "/users/1"}, calls)
+}
+
+func TestServeMuxNotFoundError(t *testing.T) {
+	var notFound *webmux.NotFoundError
+
+	api := webmux.New()
+	api.Handle(http.MethodGet, "/orders/:id", newTestHandler("order"))
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/", newTestHandler("home"))
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+	mux.Handle(http.MethodGet, "/users/:id/posts/:post", newTestHandler("post"))
+	mux.Mount("/api", api)
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		assert.True(t, errors.As(err, &notFound))
+		assert.IsError(t, err, webmux.ErrMuxNotFound)
+
+		if fromContext, ok := webmux.NotFoundFromContext(r.Context()); ok {
+			assert.Equal(t, notFound, fromContext)
+		}
+
+		webmux.StatusError(w, r, err)
+	})
+
+	tests := []struct {
+		target  string
+		path    string
+		nearest string
+	}{
+		{"/missing", "/missing", ""},
+		{"/users/42/postz", "/users/42/postz", "/users/:id"},
+		{"/users/42/posts/1/comments", "/users/42/posts/1/comments", "/users/:id/posts/:post"},
+		{"/users//42/../43/x", "/users/43/x", "/users/:id"},
+		{"/api/orders/1/items", "/api/orders/1/items", "/api/orders/:id"},
+		{"/api/customers", "/api/customers", "/api/*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			notFound = nil
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Equal(t, &webmux.NotFoundError{Path: tt.path, Nearest: tt.nearest}, notFound)
+		})
+	}
+
+	assert.Equal(t, "mux match not found: no route for /users/42/postz, nearest is /users/:id",
+		(&webmux.NotFoundError{Path: "/users/42/postz", Nearest: "/users/:id"}).Error())
+
+	mux.HandleNotFound(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		notFound, ok := webmux.NotFoundFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "/users/:id", notFound.Nearest)
+
+		return nil
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1/x", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestQuery(t *testing.T) {
+	mux := webmux.New()
+	mux.Use(webmux.Normalize(webmux.NormalizeOptions{SingleQuery: []string{"page"}}))
+	mux.HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) error {
+		query := webmux.Query(r.Context())
+
+		assert.Equal(t, []string{"2"}, query["page"])
+		assert.Equal(t, "name", query.Get("sort"))
+
+		_, err := io.WriteString(w, query.Encode())
+
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?page=2&page=2&sort=name", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "page=2&sort=name", w.Body.String())
+	assert.Zero(t, webmux.Query(context.Background()))
+}
+
+func TestServeMuxOptions(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	mux.SetOptionsHandler(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		match, _ := webmux.FromContext(r.Context())
+
+		w.Header().Set("Access-Control-Allow-Methods", match.Allow())
+		w.WriteHeader(http.StatusOK)
+
+		return nil
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "OPTIONS, GET, HEAD", w.Header().Get("Allow"))
+	assert.Equal(t, "OPTIONS, GET, HEAD", w.Header().Get("Access-Control-Allow-Methods"))
+
+	mux.SetAutoOptions(false)
+	mux.HandleMethodNotAllowed(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+	assert.Equal(t, "GET, HEAD", mux.LookupPath("/users").Allow())
+}
+
+func TestServeMuxRoutes(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	mux.Handle(http.MethodPost, "/users", newTestHandler("create"))
+	mux.HandleFunc(http.MethodGet, "/users/:id", getTestUser)
+	mux.Handle(http.MethodGet, "/events", webmux.NewSSEHub(webmux.SSEHubOptions{}), webmux.When(func(r *http.Request) bool { return true }))
+
+	routes := mux.Routes()
+
+	assert.Equal(t, 3, len(routes))
+
+	assert.Equal(t, "/events", routes[0].Pattern)
+	assert.Equal(t, webmux.MethodSet{"GET"}, routes[0].Methods)
+	assert.Equal(t, 0, len(routes[0].Handlers))
+
+	assert.Equal(t, "/users", routes[1].Pattern)
+	assert.Equal(t, webmux.MethodSet{"GET", "POST"}, routes[1].Methods)
+
+	assert.Equal(t, "/users/:id", routes[2].Pattern)
+	assert.Equal(t, []string{"id"}, routes[2].Params)
+	assert.Equal(t, "go.destructure.dev/webmux_test.getTestUser", webmux.HandlerName(routes[2].Handlers[http.MethodGet]))
+
+	assert.Equal(t, "*webmux.SSEHub", webmux.HandlerName(webmux.NewSSEHub(webmux.SSEHubOptions{})))
+}
+
+func getTestUser(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func TestServeMuxHandlePattern(t *testing.T) {
+	mux := webmux.New()
+	mux.HandlePattern("GET /users/:id", newTestHandler("user"))
+	mux.HandlePattern("POST  /users", newTestHandler("create"))
+	mux.HandlePattern("/any", newTestHandler("any"))
+
+	tests := []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/users/1", http.StatusOK},
+		{http.MethodPost, "/users", http.StatusOK},
+		{http.MethodDelete, "/any", http.StatusOK},
+		{http.MethodGet, "/missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		assert.Equal(t, tt.code, w.Code, tt.method+" "+tt.path)
+	}
+
+	assert.Panics(t, func() { mux.HandlePattern("GET example.com/", newTestHandler("host")) })
+}
+
+func TestServeMuxStats(t *testing.T) {
+	h := newTestHandler("")
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/", h)
+	mux.Handle(http.MethodGet, "/users", h)
+	mux.Handle(http.MethodPost, "/users", h)
+	mux.Handle(http.MethodGet, "/users/:id(\\d+)", h)
+	mux.Handle(http.MethodGet, "/users/:name/posts/:post", h)
+	mux.Handle(http.MethodGet, "/static/*", h)
+
+	s := mux.Stats()
+	assert.Equal(t, 8, s.Nodes)
+	assert.Equal(t, 5, s.Entries)
+	assert.Equal(t, 6, s.Handlers)
+	assert.Equal(t, 4, s.MaxDepth)
+	assert.Equal(t, 4, s.Params)
+	assert.Equal(t, 1, s.Constrained)
+	assert.Equal(t, 2, s.MaxParams)
+	assert.Equal(t, 0, s.Static)
+	assert.True(t, s.Bytes > 0)
+
+	before := s.Bytes
+	mux.Freeze()
+
+	s = mux.Stats()
+	assert.Equal(t, 1, s.Static)
+	assert.True(t, s.Bytes > before)
+}
+
+func TestServeMuxWithMiddleware(t *testing.T) {
+	mux := webmux.New()
+	calls := make([]string, 0)
+
+	trace := func(name string) webmux.Middleware {
+		return func(next webmux.Handler) webmux.Handler {
+			return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				calls = append(calls, name)
+				return next.ServeHTTPErr(w, r)
+			})
+		}
+	}
+
+	mux.Use(trace("global"))
+	mux.Handle(http.MethodGet, "/admin", newTestHandler("admin"), webmux.WithMiddleware(trace("a"), trace("b")))
+	mux.Handle(http.MethodGet, "/public", newTestHandler("public"))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	assert.Equal(t, "admin", w.Body.String())
+	assert.Equal(t, []string{"global", "a", "b"}, calls)
+
+	calls = calls[:0]
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+	assert.Equal(t, []string{"global"}, calls)
+}
+
+func TestServeMuxMiddlewareChain(t *testing.T) {
+	auth := func(next webmux.Handler) webmux.Handler {
+		return next
+	}
+
+	mux := webmux.New()
+	mux.Use(webmux.Recover(), webmux.Named("ratelimit", auth))
+	mux.Handle(http.MethodGet, "/admin", newTestHandler("admin"),
+		webmux.AllowCIDR("10.0.0.0/8"),
+		webmux.WithMiddleware(auth),
+		webmux.Header("Cache-Control", "no-store"),
+	)
+	mux.Handle(http.MethodPost, "/admin", newTestHandler("beta"), webmux.When(func(r *http.Request) bool { return r.Header.Get("X-Beta") == "1" }), webmux.WithMiddleware(webmux.Named("beta", auth)))
+	mux.Handle(http.MethodPost, "/admin", newTestHandler("admin"))
+
+	global := []string{"go.destructure.dev/webmux.Recover", "ratelimit"}
+	admin := append(slices.Clone(global),
+		"go.destructure.dev/webmux.allowNetworks",
+		"go.destructure.dev/webmux_test.TestServeMuxMiddlewareChain",
+		"go.destructure.dev/webmux.Header",
+	)
+
+	routes := mux.Routes()
+	assert.Equal(t, 1, len(routes))
+	assert.Equal(t, map[string][]string{http.MethodGet: admin, http.MethodPost: global}, routes[0].Middleware)
+
+	ex := mux.Explain(httptest.NewRequest(http.MethodGet, "/admin", nil))
+	assert.Equal(t, admin, ex.Middleware)
+	assert.Equal(t, "handler wrapped by "+strings.Join(admin, ", "), ex.Steps[len(ex.Steps)-1])
+
+	r := httptest.NewRequest(http.MethodPost, "/admin", nil)
+	r.Header.Set("X-Beta", "1")
+	assert.Equal(t, append(slices.Clone(global), "beta"), mux.Explain(r).Middleware)
+
+	assert.Equal(t, "", webmux.MiddlewareName(nil))
+}
+
+func TestServeMuxPrecomposedMiddleware(t *testing.T) {
+	wraps := 0
+	calls := make([]string, 0)
+
+	trace := func(name string) webmux.Middleware {
+		return func(next webmux.Handler) webmux.Handler {
+			wraps++
+
+			return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				calls = append(calls, name)
+				return next.ServeHTTPErr(w, r)
+			})
+		}
+	}
+
+	mux := webmux.New()
+	mux.Use(trace("a"))
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+
+	serve := func() {
+		calls = calls[:0]
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	}
+
+	serve()
+	serve()
+	assert.Equal(t, 1, wraps)
+	assert.Equal(t, []string{"a"}, calls)
+
+	// Middleware added after registration are applied per request until Freeze
+	mux.Use(trace("b"))
+
+	serve()
+	serve()
+	assert.Equal(t, 5, wraps)
+	assert.Equal(t, []string{"a", "b"}, calls)
+
+	mux.Freeze()
+	wraps = 0
+
+	serve()
+	serve()
+	assert.Equal(t, 0, wraps)
+	assert.Equal(t, []string{"a", "b"}, calls)
+}
+
+func TestServeMuxUseConcurrent(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+				assert.Equal(t, http.StatusOK, w.Code)
+				mux.Routes()
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		mux.Use(func(next webmux.Handler) webmux.Handler { return next })
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 50, len(mux.Routes()[0].Middleware[http.MethodGet]))
+}
+
+func TestServeMuxMiddlewareAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+
+	h := newTestHandler("ok")
+	plain := webmux.New()
+	plain.Handle(http.MethodGet, "/users/:id", h)
+
+	wrapped := webmux.New()
+	wrapped.Use(passthrough, passthrough)
+	wrapped.Handle(http.MethodGet, "/users/:id", h)
+
+	allocs := func(mux *webmux.ServeMux) float64 {
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		w := httptest.NewRecorder()
+
+		return testing.AllocsPerRun(100, func() {
+			w.Body.Reset()
+			mux.ServeHTTP(w, r)
+		})
+	}
+
+	assert.Equal(t, allocs(plain), allocs(wrapped))
+}
+
+func TestServeMuxRouteOptions(t *testing.T) {
+	internal := webmux.Options(webmux.AllowCIDR("10.0.0.0/8"), webmux.Header("Cache-Control", "no-store"))
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/metrics", newTestHandler("metrics"), internal)
+	mux.Handle(http.MethodGet, "/debug/vars", newTestHandler("vars"), internal)
+
+	for _, path := range []string{"/metrics", "/debug/vars"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = "10.1.2.3:1234"
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), path)
+
+		r.RemoteAddr = "192.0.2.1:1234"
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+
+	assert.Panics(t, func() {
+		mux.Handle(http.MethodGet, "/nil", newTestHandler(""), nil)
+	})
+}
+
+func TestServeMuxWithMeta(t *testing.T) {
+	var scopes []string
+
+	requireScope := func(next webmux.Handler) webmux.Handler {
+		return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			m, _ := webmux.FromContext(r.Context())
+			scope, ok := m.Meta("scope")
+
+			if !ok {
+				scope = "none"
+			}
+
+			scopes = append(scopes, scope)
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+
+	mux := webmux.New()
+	mux.Use(requireScope)
+	mux.Handle(http.MethodGet, "/reports", newTestHandler("reports"), webmux.WithMeta("scope", "reports:read"))
+	mux.Handle(http.MethodPost, "/reports", newTestHandler("created"), webmux.WithMeta("scope", "reports:write"))
+	mux.Handle(http.MethodGet, "/public", newTestHandler("public"))
+
+	api := webmux.New()
+	api.Use(requireScope)
+	api.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	api.Handle(http.MethodDelete, "/users", newTestHandler("deleted"), webmux.WithMeta("scope", "users:delete"))
+	mux.Mount("/api", api, webmux.WithMeta("scope", "api"))
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/reports", nil),
+		httptest.NewRequest(http.MethodHead, "/reports", nil),
+		httptest.NewRequest(http.MethodPost, "/reports", nil),
+		httptest.NewRequest(http.MethodGet, "/public", nil),
+		httptest.NewRequest(http.MethodGet, "/api/users", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/users", nil),
+	} {
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	assert.Equal(t, []string{
+		"reports:read",
+		"reports:read",
+		"reports:write",
+		"none",
+		"api", "api",
+		"api", "users:delete",
+	}, scopes)
+
+	for _, route := range mux.Routes() {
+		if route.Pattern == "/reports" {
+			assert.Equal(t, map[string]map[string]string{
+				http.MethodGet:  {"scope": "reports:read"},
+				http.MethodPost: {"scope": "reports:write"},
+			}, route.Meta)
+		}
+	}
+}
+
+func TestServeMuxHost(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/about", newTestHandler("about"))
+	mux.Handle(http.MethodGet, "/:page", newTestHandler("tenant"), webmux.Host("*.example.com"))
+	mux.Handle(http.MethodGet, "/:page", newTestHandler("eu tenant"), webmux.Host("*.eu.example.com"))
+	mux.Handle(http.MethodGet, "/users", newTestHandler("api"), webmux.Host("API.example.com"))
+
+	for target, want := range map[string]string{
+		"http://example.com/about":             "about",
+		"http://acme.example.com/about":        "tenant",
+		"http://acme.eu.example.com/about":     "eu tenant",
+		"http://api.example.com:8080/users":    "api",
+		"http://api.example.com/about":         "tenant",
+		"http://api.example.com./users":        "api",
+		"http://other.example.org/about":       "about",
+		"http://other.example.org/users":       "",
+		"http://eu.example.com/anything":       "tenant",
+		"http://deep.acme.example.com/welcome": "tenant",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if want == "" {
+			assert.Equal(t, http.StatusNotFound, w.Code, target)
+		} else {
+			assert.Equal(t, want, w.Body.String(), target)
+		}
+	}
+
+	routes := mux.Routes()
+	assert.Equal(t, 4, len(routes))
+	assert.Equal(t, "", routes[0].Host)
+	assert.Equal(t, "api.example.com", routes[1].Host)
+	assert.Equal(t, "*.eu.example.com", routes[2].Host)
+	assert.Equal(t, "*.example.com", routes[3].Host)
+
+	assert.Panics(t, func() {
+		webmux.Host("example.com:8080")
+	})
+}
+
+func TestServeMuxExplain(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/about", newTestHandler("about"))
+	mux.Handle(http.MethodGet, "/:page", newTestHandler("tenant"), webmux.Host("*.example.com"))
+	mux.Handle(http.MethodGet, "/users", newTestHandler("api"), webmux.Host("api.example.com"))
+	mux.Handle(http.MethodGet, "/users", newTestHandler("https"), webmux.Host("api.example.com"), webmux.SchemeHTTPS())
+
+	ex := mux.Explain(httptest.NewRequest(http.MethodGet, "http://api.example.com/about", nil))
+	assert.Equal(t, "*.example.com", ex.Host)
+	assert.Equal(t, "/:page", ex.Pattern)
+	assert.Equal(t, map[string]string{"page": "about"}, ex.Params)
+	assert.Equal(t, "OPTIONS, GET, HEAD", ex.Allow)
+	assert.True(t, ex.Handler != nil)
+	assert.Equal(t, []string{
+		"host api.example.com matches api.example.com, no route for path /about",
+		"host *.example.com matches api.example.com, path /about matches /:page",
+		"handler for method GET",
+	}, ex.Steps)
+
+	ex = mux.Explain(httptest.NewRequest(http.MethodGet, "https://api.example.com/users", nil))
+	assert.Equal(t, "api.example.com", ex.Host)
+	assert.Equal(t, "conditional handler for method GET", ex.Steps[len(ex.Steps)-1])
+
+	ex = mux.Explain(httptest.NewRequest(http.MethodPost, "http://example.com/about", nil))
+	assert.Equal(t, "", ex.Host)
+	assert.Equal(t, "/about", ex.Pattern)
+	assert.True(t, ex.Handler == nil)
+	assert.Equal(t, []string{
+		"path /about matches /about",
+		"no handler for method POST, allowed methods are OPTIONS, GET, HEAD",
+	}, ex.Steps)
+
+	ex = mux.Explain(httptest.NewRequest(http.MethodGet, "http://example.com/missing", nil))
+	assert.Equal(t, "", ex.Pattern)
+	assert.Equal(t, []string{"no route for path /missing"}, ex.Steps)
+}
+
+func TestServeMuxUnhandle(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/hooks/:id", newTestHandler("get"))
+	mux.Handle(http.MethodPost, "/hooks/:id", newTestHandler("post"))
+	mux.Handle(http.MethodPost, "/hooks/:id", newTestHandler("conditional"), webmux.When(func(r *http.Request) bool { return true }))
+	mux.Handle(http.MethodGet, "/other", newTestHandler("other"), webmux.Host("example.com"))
+
+	assert.True(t, mux.Unhandle(http.MethodPost, "/hooks/:id"))
+	assert.False(t, mux.Unhandle(http.MethodPost, "/hooks/:id"))
+	assert.False(t, mux.Unhandle(http.MethodGet, "/missing/:id"))
+	assert.Equal(t, "OPTIONS, GET, HEAD", mux.LookupPath("/hooks/1").Allow())
+
+	assert.True(t, mux.Unhandle(http.MethodGet, "/hooks/:id"))
+	assert.True(t, mux.LookupPath("/hooks/1") == nil)
+	assert.Equal(t, 3, mux.Stats().Nodes)
+
+	assert.False(t, mux.Unhandle(http.MethodGet, "/other"))
+	assert.True(t, mux.Unhandle(http.MethodGet, "/other", webmux.Host("example.com")))
+	assert.Equal(t, 0, len(mux.Routes()))
+
+	mux.Freeze()
+	assert.Panics(t, func() {
+		mux.Unhandle(http.MethodGet, "/hooks/:id")
+	})
+}
+
+func TestServeMuxConcurrentRegistration(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/", newTestHandler("home"))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hooks/1", nil))
+				mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		pattern := "/hooks/" + strconv.Itoa(i)
+		mux.Handle(http.MethodGet, pattern, newTestHandler("hook"))
+		mux.Handle(http.MethodPost, pattern, newTestHandler("hook"))
+		mux.Routes()
+		mux.Unhandle(http.MethodGet, pattern)
+	}
+
+	close(done)
+	wg.Wait()
+
+	assert.Equal(t, 101, len(mux.Routes()))
+}
+
+func TestServeMuxErrorHandlerMatch(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+
+	var patterns []string
+
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		if match, ok := webmux.FromContext(r.Context()); ok {
+			patterns = append(patterns, match.Pattern())
+		} else {
+			patterns = append(patterns, "")
+		}
+
+		webmux.StatusError(w, r, err)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "OPTIONS, GET, HEAD", w.Header().Get("Allow"))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "", w.Header().Get("Allow"))
+
+	assert.Equal(t, []string{"/users/:id", "/users/:id", ""}, patterns)
+}
+
+func TestServeMuxPriority(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+	mux.Handle(http.MethodGet, "/users/me", newTestHandler("me"))
+	mux.Handle(http.MethodGet, "/users/:id/avatar", newTestHandler("avatar"), webmux.Priority(1))
+	mux.Handle(http.MethodGet, "/users/:id(\\d+)", newTestHandler("numeric"))
+	mux.Handle(http.MethodGet, "/users/:name", newTestHandler("named"), webmux.Priority(2), webmux.Host("api.example.com"))
+	mux.Handle(http.MethodGet, "/*", newTestHandler("spa"), webmux.Priority(-1))
+
+	for target, want := range map[string]string{
+		"/users/me":                      "me",
+		"/users/1":                       "numeric",
+		"/users/alice":                   "user",
+		"/users/me/avatar":               "avatar",
+		"/users/1/avatar":                "avatar",
+		"/users/1/settings":              "spa",
+		"/about":                         "spa",
+		"http://api.example.com/users/1": "named",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		assert.Equal(t, want, w.Body.String(), target)
+	}
+
+	match := mux.LookupPath("/users/me/avatar")
+	assert.Equal(t, "me", match.Param("id"))
+
+	mux.Freeze()
+	assert.Equal(t, "/users/:id/avatar", mux.LookupPath("/users/me/avatar").Pattern())
+
+	// The params of the overridden route are not kept
+	mux = webmux.New()
+	mux.Handle(http.MethodGet, "/a/:x", newTestHandler("a"))
+	mux.Handle(http.MethodGet, "/*rest", newTestHandler("rest"), webmux.Priority(1))
+
+	match = mux.LookupPath("/a/b")
+	assert.Equal(t, "/*rest", match.Pattern())
+	assert.Equal(t, "a/b", match.Param("rest"))
+	assert.Equal(t, "", match.Param("x"))
+
+	assert.Panics(t, func() {
+		mux := webmux.New()
+		mux.Handle(http.MethodGet, "/", newTestHandler("a"), webmux.Priority(1))
+		mux.Handle(http.MethodPost, "/", newTestHandler("b"), webmux.Priority(2))
+	})
+}
+
+func TestServeMuxInnerWildcard(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/files/*path/meta", newTestHandler("meta"))
+	mux.Handle(http.MethodGet, "/files/*path/versions/:version", newTestHandler("version"))
+	mux.Handle(http.MethodGet, "/files/*", newTestHandler("file"))
+	mux.Handle(http.MethodGet, "/blobs/*/raw", newTestHandler("raw"))
+
+	var tests = []struct {
+		path    string
+		pattern string
+		params  []string
+	}{
+		{"/files/a/b/c/meta", "/files/*path/meta", []string{"a/b/c"}},
+		{"/files/a/meta/meta", "/files/*path/meta", []string{"a/meta"}},
+		{"/files/a/versions/2", "/files/*path/versions/:version", []string{"a", "2"}},
+		{"/files/a/b/versions", "/files/*", []string{"a/b/versions"}},
+		{"/files/meta", "/files/*", []string{"meta"}},
+		{"/files/a/meta/", "/files/*path/meta", []string{"a"}},
+		{"/blobs/x/y/raw", "/blobs/*/raw", []string{"x/y"}},
+		{"/blobs/x/y", "", nil},
+	}
+
+	for _, tc := range tests {
+		match := mux.LookupPath(tc.path)
+
+		if tc.pattern == "" {
+			assert.True(t, match == nil, tc.path)
+			continue
+		}
+
+		assert.NotZero(t, match, tc.path)
+		assert.Equal(t, tc.pattern, match.Pattern(), tc.path)
+
+		for i, name := range match.Params() {
+			assert.Equal(t, tc.params[i], match.Param(name), tc.path)
+		}
+	}
+
+	assert.Equal(t, "x/y", mux.LookupPath("/blobs/x/y/raw").Param("*"))
+}
+
+func TestServeMuxEscapedPath(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+	mux.Handle(http.MethodGet, "/users/:id/posts", newTestHandler("posts"))
+	mux.Handle(http.MethodGet, "/café", newTestHandler("café"))
+	mux.Handle(http.MethodGet, "/files/*", newTestHandler("file"))
+
+	var tests = []struct {
+		target  string
+		pattern string
+		param   string
+		raw     string
+	}{
+		{"/users/john%2Fdoe", "/users/:id", "john/doe", "john%2Fdoe"},
+		{"/users/john%20doe/posts", "/users/:id/posts", "john doe", "john%20doe"},
+		{"/users/100%25", "/users/:id", "100%", "100%25"},
+		{"/users/1", "/users/:id", "1", "1"},
+		{"/caf%C3%A9", "/café", "", ""},
+		{"/files/a%2Fb/c", "/files/*", "a/b/c", "a%2Fb/c"},
+	}
+
+	for _, tc := range tests {
+		match := mux.Lookup(httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+		assert.NotZero(t, match, tc.target)
+		assert.Equal(t, tc.pattern, match.Pattern(), tc.target)
+
+		if len(match.Params()) > 0 {
+			name := match.Params()[0]
+			if name == "" {
+				name = "*"
+			}
+
+			assert.Equal(t, tc.param, match.Param(name), tc.target)
+			assert.Equal(t, tc.raw, match.RawParam(name), tc.target)
+		}
+	}
+
+	sub := webmux.New()
+	sub.HandleFunc(http.MethodGet, "/objects/:key", func(w http.ResponseWriter, r *http.Request) error {
+		m, _ := webmux.FromContext(r.Context())
+		_, err := w.Write([]byte(m.Param("key") + " " + m.RawParam("key")))
+		return err
+	})
+	mux.Mount("/bucket", sub)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bucket/objects/a%2Fb", nil))
+
+	assert.Equal(t, "a/b a%2Fb", w.Body.String())
+}
+
 func ExampleHandleFunc() {
 	mux := webmux.New()
 
@@ -235,3 +1638,238 @@ func BenchmarkLookupBasic(b *testing.B) {
 
 	_ = blackhole
 }
+
+// passthrough is a middleware which calls the next handler without
+// allocating.
+func passthrough(next webmux.Handler) webmux.Handler {
+	return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return next.ServeHTTPErr(w, r)
+	})
+}
+
+func BenchmarkServeMiddleware(b *testing.B) {
+	h := newTestHandler("ok")
+
+	bench := func(b *testing.B, mux *webmux.ServeMux) {
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		w := httptest.NewRecorder()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			w.Body.Reset()
+			mux.ServeHTTP(w, r)
+		}
+	}
+
+	b.Run("none", func(b *testing.B) {
+		mux := webmux.New()
+		mux.Handle(http.MethodGet, "/users/:id", h)
+
+		bench(b, mux)
+	})
+
+	b.Run("precomposed", func(b *testing.B) {
+		mux := webmux.New()
+		mux.Use(passthrough, passthrough)
+		mux.Handle(http.MethodGet, "/users/:id", h)
+
+		bench(b, mux)
+	})
+
+	// Middleware added after registration without Freeze
+	b.Run("per-request", func(b *testing.B) {
+		mux := webmux.New()
+		mux.Handle(http.MethodGet, "/users/:id", h)
+		mux.Use(passthrough, passthrough)
+
+		bench(b, mux)
+	})
+}
+
+// paramRoute returns a pattern with n params, and a path matching it.
+func paramRoute(n int) (string, string) {
+	var pattern, path strings.Builder
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&pattern, "/s%d/:p%d", i, i)
+		fmt.Fprintf(&path, "/s%d/%d", i, i)
+	}
+
+	return pattern.String(), path.String()
+}
+
+func TestServeMuxParamsAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+
+	allocs := func(n int) float64 {
+		pattern, path := paramRoute(n)
+		last := fmt.Sprintf("p%d", n-1)
+
+		mux := webmux.New()
+		mux.HandleFunc(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) error {
+			match, _ := webmux.FromContext(r.Context())
+			_, err := io.WriteString(w, match.Param(last))
+
+			return err
+		})
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		return testing.AllocsPerRun(100, func() {
+			w.Body.Reset()
+			mux.ServeHTTP(w, r)
+		})
+	}
+
+	// The values of params are kept by the recycled matches
+	assert.Equal(t, allocs(1), allocs(20))
+}
+
+func BenchmarkServeParams(b *testing.B) {
+	for _, n := range []int{1, 5, 10, 20} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			pattern, path := paramRoute(n)
+			last := fmt.Sprintf("p%d", n-1)
+
+			mux := webmux.New()
+			mux.HandleFunc(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request) error {
+				match, _ := webmux.FromContext(r.Context())
+				_ = match.Param(last)
+
+				return nil
+			})
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				r := httptest.NewRequest(http.MethodGet, path, nil)
+				w := httptest.NewRecorder()
+
+				for pb.Next() {
+					mux.ServeHTTP(w, r)
+				}
+			})
+		})
+	}
+}
+
+func TestServeMuxCleanPath(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+	mux.Handle(http.MethodPost, "/users", newTestHandler("create"))
+
+	var tests = []struct {
+		target  string
+		pattern string
+	}{
+		{"//users", "/users"},
+		{"/users//1", "/users/:id"},
+		{"/a/../users/./1", "/users/:id"},
+		{"/../users", "/users"},
+		{"/users/%2E%2E", "/users/:id"},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		assert.Equal(t, http.StatusOK, w.Code, tc.target)
+
+		match := mux.Lookup(httptest.NewRequest(http.MethodGet, tc.target, nil))
+		assert.NotZero(t, match, tc.target)
+		assert.Equal(t, tc.pattern, match.Pattern(), tc.target)
+	}
+
+	assert.Equal(t, "/users/:id", mux.LookupPath("/users//1").Pattern())
+
+	mux.SetRedirectCleanPath(true)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "//users/./1?page=2", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/users/1?page=2", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/x/../users", nil))
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "/users", w.Header().Get("Location"))
+}
+
+func TestMuxMatchClone(t *testing.T) {
+	var clones []*webmux.MuxMatch
+
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodGet, "/users/:id/files/*", func(w http.ResponseWriter, r *http.Request) error {
+		match, _ := webmux.FromContext(r.Context())
+		clones = append(clones, match.Clone())
+
+		return nil
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/a%20b/files/x", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2/files/y/z", nil))
+	mux.Unhandle(http.MethodGet, "/users/:id/files/*")
+
+	assert.Equal(t, 2, len(clones))
+	assert.Equal(t, "/users/:id/files/*", clones[0].Pattern())
+	assert.Equal(t, "a b", clones[0].Param("id"))
+	assert.Equal(t, "a%20b", clones[0].RawParam("id"))
+	assert.Equal(t, "x", clones[0].Param("*"))
+	assert.Equal(t, "2", clones[1].Param("id"))
+	assert.Equal(t, "y/z", clones[1].Param("*"))
+	assert.NotZero(t, clones[0].Handler(http.MethodGet))
+
+	var nilMatch *webmux.MuxMatch
+	assert.Zero(t, nilMatch.Clone())
+}
+
+func TestObserveRoute(t *testing.T) {
+	api := webmux.New()
+	api.HandleFunc(http.MethodGet, "/orders/:id", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	mux.Mount("/api", api)
+
+	tests := []struct {
+		method   string
+		target   string
+		code     int
+		patterns []string
+	}{
+		{http.MethodGet, "/users/1", http.StatusOK, []string{"/users/:id"}},
+		{http.MethodDelete, "/users/1", http.StatusMethodNotAllowed, []string{"/users/:id"}},
+		{http.MethodGet, "/missing", http.StatusNotFound, nil},
+		{http.MethodGet, "/api/orders/2", http.StatusOK, []string{"/api/*", "/api/orders/:id"}},
+		{http.MethodGet, "/api/missing", http.StatusNotFound, []string{"/api/*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			var patterns []string
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			mux.ServeHTTP(w, webmux.ObserveRoute(r, func(pattern string) {
+				patterns = append(patterns, pattern)
+			}))
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, tt.patterns, patterns)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package muxtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.destructure.dev/webmux"
+)
+
+var _ webmux.Clock = (*Clock)(nil)
+
+// Clock is a fake [webmux.Clock] whose time only changes when advanced,
+// so tests of time-dependent behavior are deterministic:
+//
+//	clock := muxtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	mux.SetClock(clock)
+//	// ...
+//	clock.Advance(time.Minute)
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []clockWaiter
+}
+
+// clockWaiter is a channel returned by After, waiting for the clock to reach at.
+type clockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock returns a new Clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the time of the clock once it has been
+// advanced by at least d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, clockWaiter{at: c.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the channels returned by
+// After which are due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].at.Before(c.waiters[j].at)
+	})
+
+	n := 0
+
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			c.waiters[n] = w
+			n++
+
+			continue
+		}
+
+		w.ch <- c.now
+	}
+
+	clear(c.waiters[n:])
+	c.waiters = c.waiters[:n]
+}
+
+// Waiters returns the number of channels returned by After which have not
+// fired yet. Tests can poll it to know when a handler is blocked on the clock.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
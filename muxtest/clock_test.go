@@ -0,0 +1,33 @@
+package muxtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := muxtest.NewClock(start)
+
+	a := clock.After(time.Second)
+	b := clock.After(time.Minute)
+	assert.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), clock.Now())
+	assert.Equal(t, start.Add(30*time.Second), <-a)
+	assert.Equal(t, 1, clock.Waiters())
+
+	select {
+	case <-b:
+		t.Fatal("fired early")
+	default:
+	}
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-b)
+	assert.Equal(t, 0, clock.Waiters())
+}
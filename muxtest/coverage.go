@@ -0,0 +1,163 @@
+package muxtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"go.destructure.dev/webmux"
+)
+
+// Coverage records which routes of a mux are exercised by requests, so that
+// tests can report routes which are never hit.
+//
+// Requests are only recorded when served through the handler returned by
+// Coverage.Handler, typically by passing it to [httptest.NewServer].
+type Coverage struct {
+	mux  *webmux.ServeMux
+	mu   sync.Mutex
+	hits map[string]int // "METHOD pattern" to request count
+}
+
+// NewCoverage returns a new Coverage for the routes registered with mux.
+// Routes registered after NewCoverage is called are also tracked.
+func NewCoverage(mux *webmux.ServeMux) *Coverage {
+	return &Coverage{
+		mux:  mux,
+		hits: make(map[string]int),
+	}
+}
+
+// Handler returns an [http.Handler] that records each request before serving it with the mux.
+func (c *Coverage) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Record(r)
+		c.mux.ServeHTTP(w, r)
+	})
+}
+
+// Record records the route matched by r, if any.
+func (c *Coverage) Record(r *http.Request) {
+	match := c.mux.Lookup(r)
+
+	if match == nil {
+		return
+	}
+
+	method := r.Method
+
+	// HEAD requests are served by the GET handler unless one is registered
+	if method == http.MethodHead && match.Handler(http.MethodHead) == nil {
+		method = http.MethodGet
+	}
+
+	c.mu.Lock()
+	c.hits[routeKey(method, match.Pattern())]++
+	c.mu.Unlock()
+}
+
+// Hits returns the number of recorded requests for method and pattern.
+func (c *Coverage) Hits(method, pattern string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits[routeKey(method, pattern)]
+}
+
+// Uncovered returns the registered routes that were never hit, formatted as
+// "METHOD pattern" in a stable order.
+func (c *Coverage) Uncovered() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uncovered := make([]string, 0)
+
+	for _, key := range c.routes() {
+		if c.hits[key] == 0 {
+			uncovered = append(uncovered, key)
+		}
+	}
+
+	return uncovered
+}
+
+// routes returns the unique keys of the registered routes in Walk order.
+func (c *Coverage) routes() []string {
+	routes := make([]string, 0)
+	seen := make(map[string]bool)
+
+	c.mux.Walk(func(method, pattern string, _ webmux.Handler) error {
+		key := routeKey(method, pattern)
+
+		if !seen[key] {
+			routes = append(routes, key)
+			seen[key] = true
+		}
+
+		return nil
+	})
+
+	return routes
+}
+
+// Report writes a summary of the route coverage to w, listing any routes that were never hit.
+func (c *Coverage) Report(w io.Writer) error {
+	total := len(c.routes())
+	uncovered := c.Uncovered()
+	covered := total - len(uncovered)
+	percent := 100.0
+
+	if total > 0 {
+		percent = float64(covered) / float64(total) * 100
+	}
+
+	if _, err := fmt.Fprintf(w, "route coverage: %.1f%% of routes (%d/%d)\n", percent, covered, total); err != nil {
+		return err
+	}
+
+	sort.Strings(uncovered)
+
+	for _, route := range uncovered {
+		if _, err := fmt.Fprintf(w, "\tnot covered: %s\n", route); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunWithCoverage runs the tests with m.Run, then writes a route coverage
+// report for each of covs to stdout. It is intended to be called from TestMain:
+//
+//	var cov = muxtest.NewCoverage(newMux())
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(muxtest.RunWithCoverage(m, cov))
+//	}
+//
+// If the tests pass but strict is requested by setting the environment
+// variable MUXTEST_STRICT_COVERAGE=1, any uncovered route fails the run.
+func RunWithCoverage(m *testing.M, covs ...*Coverage) int {
+	code := m.Run()
+
+	strict := os.Getenv("MUXTEST_STRICT_COVERAGE") == "1"
+
+	for _, c := range covs {
+		c.Report(os.Stdout)
+
+		if strict && code == 0 && len(c.Uncovered()) > 0 {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+// routeKey returns the key identifying the route for method and pattern.
+func routeKey(method, pattern string) string {
+	return method + " " + pattern
+}
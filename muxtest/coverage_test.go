@@ -0,0 +1,39 @@
+package muxtest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestCoverage(t *testing.T) {
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", h)
+	mux.Handle(http.MethodPost, "/users", h)
+	mux.Handle(http.MethodGet, "/users/:id", h)
+
+	cov := muxtest.NewCoverage(mux)
+	srv := httptest.NewServer(cov.Handler())
+	defer srv.Close()
+
+	resp, err := http.Head(srv.URL + "/users/1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, 1, cov.Hits(http.MethodGet, "/users/:id"))
+	assert.Equal(t, []string{"GET /users", "POST /users"}, cov.Uncovered())
+
+	var b strings.Builder
+
+	assert.NoError(t, cov.Report(&b))
+	assert.Equal(t, "route coverage: 33.3% of routes (1/3)\n\tnot covered: GET /users\n\tnot covered: POST /users\n", b.String())
+}
@@ -0,0 +1,550 @@
+package muxtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.destructure.dev/webmux"
+)
+
+// ErrMockSpec is returned when a mock spec cannot be parsed.
+var ErrMockSpec = errors.New("muxtest: invalid mock spec")
+
+// Mock is a mux serving canned responses for the routes of a spec, so that
+// clients can be developed and tested before the handlers exist.
+//
+// Incoming requests are validated against the spec, and invalid requests are
+// rejected with a 400 response in the webmux JSON error envelope:
+//
+//	{"error":{"code":"invalid_request","message":"missing query parameter \"page\""}}
+type Mock struct {
+	mux    *webmux.ServeMux
+	mu     sync.Mutex
+	routes map[string]*mockRoute // "METHOD pattern" to route
+}
+
+// mockRoute is the expected request and canned response of a route.
+type mockRoute struct {
+	status       int
+	contentType  string
+	body         []byte
+	query        []string // required query parameters
+	headers      []string // required headers
+	bodyRequired bool
+	bodyTypes    []string // accepted request media types, any if empty
+	bodyFields   []string // required fields of a JSON request body
+}
+
+// NewMock returns a Mock for spec, which is either an OpenAPI 3 document in
+// JSON format, or a route table with one "METHOD pattern" line per route as
+// produced by RouteTable.
+//
+// For OpenAPI documents the response of each operation is the example of its
+// first success response, or a value generated from the response schema if
+// there is no example. Required parameters, the request body, and the required
+// fields of a JSON request body are validated. Routes from a route table
+// respond with 204 No Content and accept any request.
+//
+// Use Respond to override the canned response of a route.
+func NewMock(spec []byte) (*Mock, error) {
+	m := &Mock{
+		mux:    webmux.New(),
+		routes: make(map[string]*mockRoute),
+	}
+
+	trimmed := bytes.TrimSpace(spec)
+
+	var err error
+
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		err = m.parseOpenAPI(trimmed)
+	} else {
+		err = m.parseRouteTable(trimmed)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(m.routes))
+
+	for key := range m.routes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		method, pattern, _ := strings.Cut(key, " ")
+		m.mux.Handle(method, pattern, m.handler(key))
+	}
+
+	return m, nil
+}
+
+// Respond sets the canned response of the route registered for method and
+// pattern. The body is encoded as JSON unless it is a []byte, and is omitted
+// if nil.
+func (m *Mock) Respond(method, pattern string, code int, body any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	route, ok := m.routes[routeKey(method, pattern)]
+
+	if !ok {
+		return fmt.Errorf("muxtest: no mock route %s", routeKey(method, pattern))
+	}
+
+	route.status = code
+	route.contentType = ""
+	route.body = nil
+
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		route.body = b
+	default:
+		data, err := json.Marshal(b)
+
+		if err != nil {
+			return fmt.Errorf("muxtest: %w", err)
+		}
+
+		route.contentType = "application/json"
+		route.body = data
+	}
+
+	return nil
+}
+
+// Mux returns the mux serving the mock routes.
+func (m *Mock) Mux() *webmux.ServeMux {
+	return m.mux
+}
+
+// ServeHTTP implements [http.Handler].
+func (m *Mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// handler returns the handler of the route with key.
+func (m *Mock) handler(key string) webmux.Handler {
+	return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		m.mu.Lock()
+		route := *m.routes[key]
+		m.mu.Unlock()
+
+		if err := route.validate(r); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+
+			return json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]string{
+					"code":    "invalid_request",
+					"message": err.Error(),
+				},
+			})
+		}
+
+		if route.contentType != "" {
+			w.Header().Set("Content-Type", route.contentType)
+		}
+
+		w.WriteHeader(route.status)
+
+		if len(route.body) == 0 {
+			return nil
+		}
+
+		_, err := w.Write(route.body)
+
+		return err
+	})
+}
+
+// validate returns an error describing why r does not match the route.
+func (route *mockRoute) validate(r *http.Request) error {
+	query := r.URL.Query()
+
+	for _, name := range route.query {
+		if !query.Has(name) {
+			return fmt.Errorf("missing query parameter %q", name)
+		}
+	}
+
+	for _, name := range route.headers {
+		if r.Header.Get(name) == "" {
+			return fmt.Errorf("missing header %q", name)
+		}
+	}
+
+	var body bytes.Buffer
+
+	if r.Body != nil {
+		if _, err := body.ReadFrom(r.Body); err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+	}
+
+	if body.Len() == 0 {
+		if route.bodyRequired {
+			return errors.New("missing request body")
+		}
+
+		return nil
+	}
+
+	if len(route.bodyTypes) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if !acceptsMediaType(route.bodyTypes, mediaType) {
+		return fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+
+	var v any
+
+	if err := json.Unmarshal(body.Bytes(), &v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	if len(route.bodyFields) == 0 {
+		return nil
+	}
+
+	obj, ok := v.(map[string]any)
+
+	if !ok {
+		return errors.New("request body must be a JSON object")
+	}
+
+	for _, name := range route.bodyFields {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("missing body field %q", name)
+		}
+	}
+
+	return nil
+}
+
+// acceptsMediaType returns true if mediaType matches any of the accepted
+// media types, which may be wildcards like "*/*" or "image/*".
+func acceptsMediaType(accepted []string, mediaType string) bool {
+	for _, a := range accepted {
+		if a == mediaType || a == "*/*" {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRouteTable adds the routes of a route table.
+func (m *Mock) parseRouteTable(spec []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(spec))
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		method, pattern, ok := strings.Cut(text, " ")
+		pattern = strings.TrimSpace(pattern)
+
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("%w: line %d: expected \"METHOD pattern\"", ErrMockSpec, line)
+		}
+
+		m.routes[routeKey(strings.ToUpper(method), pattern)] = &mockRoute{status: http.StatusNoContent}
+	}
+
+	return scanner.Err()
+}
+
+// openAPIMethods are the operation keys of an OpenAPI path item.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// parseOpenAPI adds the routes of the operations of an OpenAPI document.
+func (m *Mock) parseOpenAPI(spec []byte) error {
+	var doc map[string]any
+
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return fmt.Errorf("%w: %w", ErrMockSpec, err)
+	}
+
+	paths, ok := doc["paths"].(map[string]any)
+
+	if !ok {
+		return fmt.Errorf("%w: missing paths", ErrMockSpec)
+	}
+
+	for path, item := range paths {
+		item := resolveRef(doc, item)
+		pattern := openAPIPattern(path)
+
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]any)
+
+			if !ok {
+				continue
+			}
+
+			route := &mockRoute{status: http.StatusNoContent}
+
+			params, _ := item["parameters"].([]any)
+			opParams, _ := op["parameters"].([]any)
+
+			for _, p := range append(params, opParams...) {
+				p := resolveRef(doc, p)
+
+				if required, _ := p["required"].(bool); !required {
+					continue
+				}
+
+				name, _ := p["name"].(string)
+
+				switch p["in"] {
+				case "query":
+					route.query = append(route.query, name)
+				case "header":
+					route.headers = append(route.headers, name)
+				}
+			}
+
+			if body := resolveRef(doc, op["requestBody"]); body != nil {
+				route.bodyRequired, _ = body["required"].(bool)
+				content, _ := body["content"].(map[string]any)
+
+				for mediaType, media := range content {
+					route.bodyTypes = append(route.bodyTypes, mediaType)
+
+					if mediaType != "application/json" {
+						continue
+					}
+
+					media, _ := media.(map[string]any)
+					schema := resolveRef(doc, media["schema"])
+					required, _ := schema["required"].([]any)
+
+					for _, name := range required {
+						if name, ok := name.(string); ok {
+							route.bodyFields = append(route.bodyFields, name)
+						}
+					}
+				}
+			}
+
+			if err := route.setOpenAPIResponse(doc, op); err != nil {
+				return fmt.Errorf("%w: %s %s: %w", ErrMockSpec, strings.ToUpper(method), path, err)
+			}
+
+			m.routes[routeKey(strings.ToUpper(method), pattern)] = route
+		}
+	}
+
+	return nil
+}
+
+// setOpenAPIResponse sets the canned response from the first success response of op.
+func (route *mockRoute) setOpenAPIResponse(doc map[string]any, op map[string]any) error {
+	responses, _ := op["responses"].(map[string]any)
+	codes := make([]string, 0, len(responses))
+
+	for code := range responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	sort.Strings(codes)
+
+	status, err := strconv.Atoi(strings.ReplaceAll(codes[0], "X", "0"))
+
+	if err != nil {
+		return fmt.Errorf("invalid response code %q", codes[0])
+	}
+
+	route.status = status
+
+	resp := resolveRef(doc, responses[codes[0]])
+	content, _ := resp["content"].(map[string]any)
+
+	if len(content) == 0 {
+		return nil
+	}
+
+	mediaType := "application/json"
+
+	if _, ok := content[mediaType]; !ok {
+		for mt := range content {
+			mediaType = mt
+			break
+		}
+	}
+
+	media, _ := content[mediaType].(map[string]any)
+	example, ok := media["example"]
+
+	if !ok {
+		examples, _ := media["examples"].(map[string]any)
+		names := make([]string, 0, len(examples))
+
+		for name := range examples {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		if len(names) > 0 {
+			example, ok = resolveRef(doc, examples[names[0]])["value"]
+		}
+	}
+
+	if !ok {
+		example = exampleFromSchema(doc, media["schema"], 0)
+	}
+
+	route.contentType = mediaType
+
+	if s, ok := example.(string); ok && !strings.HasSuffix(mediaType, "json") {
+		route.body = []byte(s)
+		return nil
+	}
+
+	route.body, err = json.Marshal(example)
+
+	return err
+}
+
+// maxExampleDepth limits the generation of examples for recursive schemas.
+const maxExampleDepth = 8
+
+// exampleFromSchema generates an example value for an OpenAPI schema.
+func exampleFromSchema(doc map[string]any, v any, depth int) any {
+	schema := resolveRef(doc, v)
+
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if schemas, ok := schema[key].([]any); ok && len(schemas) > 0 {
+			if key != "allOf" {
+				return exampleFromSchema(doc, schemas[0], depth+1)
+			}
+
+			merged := make(map[string]any)
+
+			for _, s := range schemas {
+				if obj, ok := exampleFromSchema(doc, s, depth+1).(map[string]any); ok {
+					for k, v := range obj {
+						merged[k] = v
+					}
+				}
+			}
+
+			return merged
+		}
+	}
+
+	switch schema["type"] {
+	case "string":
+		switch schema["format"] {
+		case "date-time":
+			return "1970-01-01T00:00:00Z"
+		case "date":
+			return "1970-01-01"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		}
+
+		return "string"
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []any{exampleFromSchema(doc, schema["items"], depth+1)}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	obj := make(map[string]any, len(props))
+
+	for name, prop := range props {
+		obj[name] = exampleFromSchema(doc, prop, depth+1)
+	}
+
+	return obj
+}
+
+// resolveRef returns v as an object, following a local "$ref" if present.
+func resolveRef(doc map[string]any, v any) map[string]any {
+	obj, _ := v.(map[string]any)
+
+	for i := 0; i < maxExampleDepth; i++ {
+		ref, ok := obj["$ref"].(string)
+
+		if !ok {
+			return obj
+		}
+
+		var target any = doc
+
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+			m, _ := target.(map[string]any)
+			target = m[key]
+		}
+
+		obj, _ = target.(map[string]any)
+	}
+
+	return obj
+}
+
+// openAPIPattern converts an OpenAPI path template like "/users/{id}" to a
+// webmux pattern like "/users/:id".
+func openAPIPattern(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i, s := range segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			segments[i] = ":" + s[1:len(s)-1]
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
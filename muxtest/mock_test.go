@@ -0,0 +1,109 @@
+package muxtest_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+const mockSpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/users": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+        },
+        "responses": {
+          "201": {"content": {"application/json": {"example": {"id": 1, "name": "Ada"}}}}
+        }
+      },
+      "get": {
+        "parameters": [{"name": "page", "in": "query", "required": true}],
+        "responses": {
+          "200": {"content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/User"}}}}}
+        }
+      }
+    },
+    "/users/{id}": {
+      "delete": {"responses": {"204": {"description": "deleted"}}}
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {"id": {"type": "integer"}, "name": {"type": "string", "example": "Ada"}}
+      }
+    }
+  }
+}`
+
+func TestMockOpenAPI(t *testing.T) {
+	mock, err := muxtest.NewMock([]byte(mockSpec))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{"example", http.MethodPost, "/users", "application/json", `{"name":"Ada"}`, 201, `{"id":1,"name":"Ada"}`},
+		{"missing body", http.MethodPost, "/users", "", "", 400, `{"error":{"code":"invalid_request","message":"missing request body"}}` + "\n"},
+		{"missing field", http.MethodPost, "/users", "application/json", `{}`, 400, `{"error":{"code":"invalid_request","message":"missing body field \"name\""}}` + "\n"},
+		{"wrong content type", http.MethodPost, "/users", "text/plain", `name=Ada`, 400, `{"error":{"code":"invalid_request","message":"unsupported content type \"text/plain\""}}` + "\n"},
+		{"generated", http.MethodGet, "/users?page=1", "", "", 200, `[{"id":0,"name":"Ada"}]`},
+		{"missing query", http.MethodGet, "/users", "", "", 400, `{"error":{"code":"invalid_request","message":"missing query parameter \"page\""}}` + "\n"},
+		{"no content", http.MethodDelete, "/users/1", "", "", 204, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			mock.ServeHTTP(w, r)
+
+			body, _ := io.ReadAll(w.Result().Body)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func TestMockRouteTable(t *testing.T) {
+	mock, err := muxtest.NewMock([]byte("GET /users\nGET /users/:id\n"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.Respond(http.MethodGet, "/users/:id", http.StatusOK, map[string]string{"name": "Ada"}))
+	assert.Error(t, mock.Respond(http.MethodPost, "/users", http.StatusOK, nil))
+
+	w := httptest.NewRecorder()
+	mock.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	mock.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"name":"Ada"}`, w.Body.String())
+
+	_, err = muxtest.NewMock([]byte("users"))
+	assert.IsError(t, err, muxtest.ErrMockSpec)
+}
@@ -0,0 +1,2 @@
+// Package muxtest provides utilities for testing applications using webmux.
+package muxtest
@@ -0,0 +1,305 @@
+package muxtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"go.destructure.dev/webmux"
+)
+
+// Interaction is a recorded request and the response it was served.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request recorded by a Recorder.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"` // request URI, the path and query
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response recorded by a Recorder.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Body is a recorded message body. It is encoded in JSON as a string if it is
+// valid UTF-8, otherwise as an object holding the base64 encoded body, which
+// keeps recordings of text bodies readable.
+type Body []byte
+
+// MarshalJSON implements [json.Marshaler].
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+
+	return nil
+}
+
+// Recorder is a middleware recording requests and responses to files, one
+// file per route, so that they can be served back with Replay. Recordings
+// make integration tests of downstream consumers deterministic, without the
+// real handlers and their dependencies.
+//
+// Each route is recorded to a JSON file in the directory, named after the
+// method and pattern of the route. Existing recordings of a route are
+// replaced by the first request recorded for the route.
+type Recorder struct {
+	// Redact lists headers whose values are replaced with "REDACTED" in
+	// recordings. It defaults to Authorization, Cookie, and Set-Cookie.
+	Redact []string
+
+	dir     string
+	mu      sync.Mutex
+	byRoute map[string][]Interaction // "METHOD pattern" to interactions
+}
+
+// NewRecorder returns a Recorder writing recordings to dir.
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{
+		Redact:  []string{"Authorization", "Cookie", "Set-Cookie"},
+		dir:     dir,
+		byRoute: make(map[string][]Interaction),
+	}
+}
+
+// Middleware records the requests served by next.
+//
+// When the middleware is applied to the handlers of routes, requests are
+// grouped by the matched pattern. Otherwise, for example when wrapping the
+// mux itself, requests are grouped by path.
+func (rec *Recorder) Middleware(next webmux.Handler) webmux.Handler {
+	return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var reqBody []byte
+
+		if r.Body != nil {
+			var err error
+
+			reqBody, err = io.ReadAll(r.Body)
+
+			if err != nil {
+				return fmt.Errorf("muxtest: read body: %w", err)
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+
+		pattern := r.URL.Path
+
+		if match, ok := webmux.FromContext(r.Context()); ok {
+			pattern = match.Pattern()
+		}
+
+		tee := &teeResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		err := next.ServeHTTPErr(tee, r)
+
+		in := Interaction{
+			Request: RecordedRequest{
+				Method: r.Method,
+				URL:    r.URL.RequestURI(),
+				Header: rec.redact(r.Header),
+				Body:   reqBody,
+			},
+			Response: RecordedResponse{
+				Status: tee.status,
+				Header: rec.redact(w.Header()),
+				Body:   tee.body.Bytes(),
+			},
+		}
+
+		if werr := rec.record(routeKey(r.Method, pattern), in); werr != nil && err == nil {
+			err = werr
+		}
+
+		return err
+	})
+}
+
+// redact returns a copy of h with the values of the redacted headers replaced.
+func (rec *Recorder) redact(h http.Header) http.Header {
+	h = h.Clone()
+
+	for _, name := range rec.Redact {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, "REDACTED")
+		}
+	}
+
+	return h
+}
+
+// record adds in to the interactions of the route with key and rewrites its file.
+func (rec *Recorder) record(key string, in Interaction) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.byRoute[key] = append(rec.byRoute[key], in)
+
+	data, err := json.MarshalIndent(rec.byRoute[key], "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("muxtest: %w", err)
+	}
+
+	if err := os.MkdirAll(rec.dir, 0o755); err != nil {
+		return fmt.Errorf("muxtest: %w", err)
+	}
+
+	path := filepath.Join(rec.dir, sanitizeName(key)+".json")
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("muxtest: %w", err)
+	}
+
+	return nil
+}
+
+// teeResponseWriter is a ResponseWriter that keeps a copy of the response.
+type teeResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (t *teeResponseWriter) WriteHeader(code int) {
+	if !t.wroteHeader {
+		t.status = code
+		t.wroteHeader = true
+	}
+
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *teeResponseWriter) Write(p []byte) (int, error) {
+	t.wroteHeader = true
+	t.body.Write(p)
+
+	return t.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying ResponseWriter, see [http.ResponseController].
+func (t *teeResponseWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Replay returns a handler serving the interactions recorded by a Recorder in dir.
+//
+// A request is served the response of the first recorded interaction with the
+// same method, URL, and body. If several interactions match they are served in
+// the order they were recorded, repeating the last one. Requests without a
+// matching interaction are rejected with 404 Not Found.
+func Replay(dir string) (http.Handler, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, fmt.Errorf("muxtest: %w", err)
+	}
+
+	sort.Strings(paths)
+
+	rp := &replayer{served: make(map[int]int)}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("muxtest: %w", err)
+		}
+
+		var interactions []Interaction
+
+		if err := json.Unmarshal(data, &interactions); err != nil {
+			return nil, fmt.Errorf("muxtest: %s: %w", path, err)
+		}
+
+		rp.interactions = append(rp.interactions, interactions...)
+	}
+
+	return rp, nil
+}
+
+// replayer serves recorded interactions.
+type replayer struct {
+	interactions []Interaction
+	mu           sync.Mutex
+	served       map[int]int // index of first matching interaction to times served
+}
+
+func (rp *replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+
+	matches := make([]int, 0)
+
+	for i, in := range rp.interactions {
+		if in.Request.Method == r.Method && in.Request.URL == r.URL.RequestURI() && bytes.Equal(in.Request.Body, body) {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) == 0 {
+		http.Error(w, fmt.Sprintf("muxtest: no recorded interaction for %s %s", r.Method, r.URL.RequestURI()), http.StatusNotFound)
+		return
+	}
+
+	rp.mu.Lock()
+	n := rp.served[matches[0]]
+	rp.served[matches[0]]++
+	rp.mu.Unlock()
+
+	resp := rp.interactions[matches[min(n, len(matches)-1)]].Response
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
@@ -0,0 +1,71 @@
+package muxtest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec := muxtest.NewRecorder(dir)
+	calls := 0
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users/:id", rec.Middleware(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"call":%d}`, calls)
+		return err
+	})))
+	mux.Handle(http.MethodPost, "/echo", rec.Middleware(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		_, err := io.Copy(w, r.Body)
+		return err
+	})))
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/2", nil),
+		httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("\xff\x00")),
+	} {
+		r.Header.Set("Authorization", "Bearer secret")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "GET__users__id.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"REDACTED"`)
+	assert.NotContains(t, string(data), "secret")
+
+	replay, err := muxtest.Replay(dir)
+	assert.NoError(t, err)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		replay.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	assert.Equal(t, `{"call":1}`, serve(http.MethodGet, "/users/1", "").Body.String())
+	assert.Equal(t, `{"call":2}`, serve(http.MethodGet, "/users/1", "").Body.String())
+	assert.Equal(t, `{"call":2}`, serve(http.MethodGet, "/users/1", "").Body.String())
+	assert.Equal(t, `{"call":3}`, serve(http.MethodGet, "/users/2", "").Body.String())
+	assert.Equal(t, "application/json", serve(http.MethodGet, "/users/2", "").Header().Get("Content-Type"))
+
+	w := serve(http.MethodPost, "/echo", "\xff\x00")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "\xff\x00", w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/users/3", "").Code)
+}
@@ -0,0 +1,250 @@
+package muxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.destructure.dev/webmux"
+)
+
+// ErrSchemaMismatch is returned when a response does not match its schema.
+var ErrSchemaMismatch = errors.New("muxtest: response does not match schema")
+
+// ResponseValidator validates the JSON responses of handlers against the
+// response schemas of an OpenAPI document, catching drift between the
+// handlers and the contract of the API before clients do. Since responses
+// with a schema are buffered and decoded, it is meant for development and
+// tests:
+//
+//	if dev {
+//		v, err := muxtest.NewResponseValidator(spec)
+//		// ...
+//		mux.Use(v.Middleware)
+//	}
+//
+// The middleware can also be applied to the routes being worked on with
+// [webmux.WithMiddleware].
+type ResponseValidator struct {
+	// Report is called with the mismatches, which are wrapped by
+	// ErrSchemaMismatch, after which the response is sent unchanged.
+	// If nil, the handler fails with the mismatch instead, so the error
+	// handler of the mux responds in place of the handler.
+	Report func(r *http.Request, err error)
+
+	doc  map[string]any
+	root *webmux.Schema         // the document, resolving the response schemas
+	ops  map[string]validatedOp // "METHOD pattern" to operation
+}
+
+// validatedOp is an operation of the document of a ResponseValidator.
+type validatedOp struct {
+	pointer   string         // reference to the responses of the operation
+	responses map[string]any // status code to response
+}
+
+// NewResponseValidator returns a ResponseValidator for spec, an OpenAPI 3
+// document in JSON format as accepted by NewMock.
+func NewResponseValidator(spec []byte) (*ResponseValidator, error) {
+	v := &ResponseValidator{ops: make(map[string]validatedOp)}
+
+	if err := json.Unmarshal(spec, &v.doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMockSpec, err)
+	}
+
+	root, err := webmux.ParseSchema(spec)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMockSpec, err)
+	}
+
+	v.root = root
+
+	paths, ok := v.doc["paths"].(map[string]any)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: missing paths", ErrMockSpec)
+	}
+
+	for path, item := range paths {
+		pointer := "#/paths/" + escapePointer(path)
+
+		if ref, ok := item.(map[string]any)["$ref"].(string); ok {
+			pointer = ref
+		}
+
+		item := resolveRef(v.doc, item)
+
+		for _, method := range openAPIMethods {
+			if op, ok := item[method].(map[string]any); ok {
+				responses, _ := op["responses"].(map[string]any)
+				v.ops[routeKey(strings.ToUpper(method), openAPIPattern(path))] = validatedOp{
+					pointer:   pointer + "/" + method + "/responses",
+					responses: responses,
+				}
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// Validate returns an error wrapping ErrSchemaMismatch if body is not valid
+// for the schema of the response to method and pattern with status and
+// contentType. Nil is returned for responses without a JSON schema.
+func (v *ResponseValidator) Validate(method, pattern string, status int, contentType string, body []byte) error {
+	schema, ok := v.schema(routeKey(method, pattern), status, contentType)
+
+	if !ok {
+		return nil
+	}
+
+	return v.validate(method, pattern, schema, body)
+}
+
+// Middleware validates the responses of next. Requests are looked up by the
+// matched pattern, so the middleware must be applied to the handlers of
+// routes, with Use or WithMiddleware. Responses whose route, status or media
+// type has no JSON schema are not buffered.
+func (v *ResponseValidator) Middleware(next webmux.Handler) webmux.Handler {
+	return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		match, ok := webmux.FromContext(r.Context())
+
+		if !ok || r.Method == http.MethodHead {
+			return next.ServeHTTPErr(w, r)
+		}
+
+		vw := &validatingWriter{ResponseWriter: w, v: v, key: routeKey(r.Method, match.Pattern())}
+		err := next.ServeHTTPErr(vw, r)
+
+		if vw.schema == nil {
+			return err
+		}
+
+		if mismatch := v.validate(r.Method, match.Pattern(), vw.schema, vw.body.Bytes()); mismatch != nil {
+			if v.Report == nil {
+				if err == nil {
+					err = mismatch
+				}
+
+				return err
+			}
+
+			v.Report(r, mismatch)
+		}
+
+		w.WriteHeader(vw.status)
+
+		if _, werr := w.Write(vw.body.Bytes()); werr != nil && err == nil {
+			err = werr
+		}
+
+		return err
+	})
+}
+
+// schema returns the schema of the response to the operation with key for
+// status and contentType, if it is a JSON response with a schema.
+func (v *ResponseValidator) schema(key string, status int, contentType string) (*webmux.Schema, bool) {
+	op, ok := v.ops[key]
+
+	if !ok {
+		return nil, false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil, false
+	}
+
+	code := strconv.Itoa(status)
+
+	for _, name := range []string{code, code[:1] + "XX", "default"} {
+		resp, ok := op.responses[name]
+
+		if !ok {
+			continue
+		}
+
+		pointer := op.pointer + "/" + escapePointer(name)
+
+		if ref, ok := resp.(map[string]any)["$ref"].(string); ok {
+			pointer = ref
+		}
+
+		content, _ := resolveRef(v.doc, resp)["content"].(map[string]any)
+
+		if _, ok := content[mediaType].(map[string]any); !ok {
+			mediaType = "application/json"
+		}
+
+		media, _ := content[mediaType].(map[string]any)
+
+		if media["schema"] == nil {
+			return nil, false
+		}
+
+		return v.root.Lookup(pointer + "/content/" + escapePointer(mediaType) + "/schema")
+	}
+
+	return nil, false
+}
+
+// validate validates body against schema.
+func (v *ResponseValidator) validate(method, pattern string, schema *webmux.Schema, body []byte) error {
+	if err := schema.Validate(body); err != nil {
+		return fmt.Errorf("%w: %s %s: %w", ErrSchemaMismatch, method, pattern, err)
+	}
+
+	return nil
+}
+
+// escapePointer escapes key as a JSON pointer reference token.
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// validatingWriter is a ResponseWriter buffering the responses with a schema.
+type validatingWriter struct {
+	http.ResponseWriter
+	v           *ResponseValidator
+	key         string
+	schema      *webmux.Schema // schema of the response, nil if it is not buffered
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (vw *validatingWriter) WriteHeader(code int) {
+	if vw.wroteHeader {
+		return
+	}
+
+	vw.wroteHeader = true
+	vw.status = code
+
+	if schema, ok := vw.v.schema(vw.key, code, vw.Header().Get("Content-Type")); ok {
+		vw.schema = schema
+		return
+	}
+
+	vw.ResponseWriter.WriteHeader(code)
+}
+
+func (vw *validatingWriter) Write(p []byte) (int, error) {
+	if !vw.wroteHeader {
+		vw.WriteHeader(http.StatusOK)
+	}
+
+	if vw.schema != nil {
+		return vw.body.Write(p)
+	}
+
+	return vw.ResponseWriter.Write(p)
+}
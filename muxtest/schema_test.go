@@ -0,0 +1,86 @@
+package muxtest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestResponseValidator(t *testing.T) {
+	v, err := muxtest.NewResponseValidator([]byte(mockSpec))
+	assert.NoError(t, err)
+
+	users := `[{"id": 1, "name": "Ada"}]`
+
+	mux := webmux.New()
+	mux.Use(v.Middleware)
+	mux.HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(users))
+		return err
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"valid", `[{"id": 1, "name": "Ada"}]`, http.StatusOK, ""},
+		{"missing property", `[{"id": 1}]`, http.StatusInternalServerError, `muxtest: response does not match schema: GET /users: $[0]: missing property "name"`},
+		{"wrong type", `[{"id": 1.5, "name": "Ada"}]`, http.StatusInternalServerError, `muxtest: response does not match schema: GET /users: $[0].id: expected integer, got 1.5`},
+		{"not an array", `{"name": "Ada"}`, http.StatusInternalServerError, `muxtest: response does not match schema: GET /users: $: expected array, got object`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			users = tc.body
+
+			var reported error
+
+			mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+				reported = err
+				webmux.StatusError(w, r, err)
+			})
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?page=1", nil))
+
+			assert.Equal(t, tc.wantCode, w.Code)
+
+			if tc.wantErr == "" {
+				assert.NoError(t, reported)
+				assert.True(t, json.Valid(w.Body.Bytes()))
+			} else {
+				assert.IsError(t, reported, muxtest.ErrSchemaMismatch)
+				assert.EqualError(t, reported, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("report", func(t *testing.T) {
+		users = `[{"id": "1", "name": "Ada"}]`
+
+		var reported []error
+
+		v.Report = func(r *http.Request, err error) {
+			reported = append(reported, err)
+		}
+		defer func() { v.Report = nil }()
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, users, w.Body.String())
+		assert.Equal(t, 1, len(reported))
+	})
+
+	assert.NoError(t, v.Validate(http.MethodDelete, "/users/:id", http.StatusNoContent, "", nil))
+	assert.NoError(t, v.Validate(http.MethodPost, "/users", http.StatusCreated, "text/plain", []byte("ok")))
+}
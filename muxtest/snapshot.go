@@ -0,0 +1,124 @@
+package muxtest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.destructure.dev/webmux"
+)
+
+// Snapshot compares the route table of mux to a golden file, failing t if the
+// routes have changed. This guards against routes being accidentally added,
+// removed, or changed.
+//
+// The golden file is stored in the testdata directory, named after the test.
+// To create the golden file or accept changes, run the tests with the
+// environment variable MUXTEST_UPDATE=1 to write the file. A missing golden
+// file fails t otherwise, so that a snapshot which was never committed does
+// not pass silently in CI.
+func Snapshot(t testing.TB, mux *webmux.ServeMux) {
+	t.Helper()
+
+	got := RouteTable(mux)
+	path := filepath.Join("testdata", sanitizeName(t.Name())+".routes")
+
+	if os.Getenv("MUXTEST_UPDATE") == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("muxtest: %s", err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("muxtest: %s", err)
+		}
+
+		t.Logf("muxtest: wrote route snapshot %s", path)
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("muxtest: missing route snapshot %s (run with MUXTEST_UPDATE=1 to create it)", path)
+	}
+
+	if err != nil {
+		t.Fatalf("muxtest: %s", err)
+	}
+
+	if got != string(want) {
+		t.Errorf("muxtest: routes do not match snapshot %s (run with MUXTEST_UPDATE=1 to update):\n%s", path, diffLines(string(want), got))
+	}
+}
+
+// RouteTable returns a stable textual representation of the routes registered
+// with mux, with one "METHOD pattern" line per route.
+func RouteTable(mux *webmux.ServeMux) string {
+	var b strings.Builder
+
+	seen := make(map[string]bool)
+
+	mux.Walk(func(method, pattern string, _ webmux.Handler) error {
+		key := routeKey(method, pattern)
+
+		if !seen[key] {
+			b.WriteString(key)
+			b.WriteByte('\n')
+			seen[key] = true
+		}
+
+		return nil
+	})
+
+	return b.String()
+}
+
+// diffLines returns the lines removed from want and added in got, prefixed
+// with "-" and "+" respectively.
+func diffLines(want, got string) string {
+	wantLines := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	inWant := make(map[string]bool, len(wantLines))
+	inGot := make(map[string]bool, len(gotLines))
+
+	for _, l := range wantLines {
+		inWant[l] = true
+	}
+
+	for _, l := range gotLines {
+		inGot[l] = true
+	}
+
+	var b strings.Builder
+
+	for _, l := range wantLines {
+		if !inGot[l] {
+			b.WriteString("-" + l + "\n")
+		}
+	}
+
+	for _, l := range gotLines {
+		if !inWant[l] {
+			b.WriteString("+" + l + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// sanitizeName replaces characters in a test name that are unsafe in file names.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+
+		return r
+	}, name)
+}
@@ -0,0 +1,110 @@
+package muxtest_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestRouteTable(t *testing.T) {
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users/:id", h)
+	mux.Handle(http.MethodPost, "/users", h)
+	mux.Handle(http.MethodGet, "/users", h)
+	mux.Handle(http.MethodGet, "/users/new", h)
+
+	want := "GET /users\nPOST /users\nGET /users/new\nGET /users/:id\n"
+
+	assert.Equal(t, want, muxtest.RouteTable(mux))
+}
+
+// snapshotT records the failures of Snapshot.
+type snapshotT struct {
+	testing.TB
+	name   string
+	errors []string
+	fatal  bool
+}
+
+func (t *snapshotT) Name() string { return t.name }
+
+func (t *snapshotT) Helper() {}
+
+func (t *snapshotT) Logf(format string, args ...any) {}
+
+func (t *snapshotT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *snapshotT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	t.fatal = true
+	runtime.Goexit()
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(dir) })
+
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users", h)
+
+	snapshot := func() *snapshotT {
+		st := &snapshotT{TB: t, name: "TestAPI/routes"}
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			muxtest.Snapshot(st, mux)
+		}()
+
+		<-done
+
+		return st
+	}
+
+	// A missing snapshot fails instead of being created
+	st := snapshot()
+	assert.True(t, st.fatal)
+	assert.Contains(t, st.errors[0], "missing route snapshot testdata/TestAPI_routes.routes")
+
+	_, err = os.Stat("testdata/TestAPI_routes.routes")
+	assert.True(t, os.IsNotExist(err))
+
+	t.Setenv("MUXTEST_UPDATE", "1")
+
+	st = snapshot()
+	assert.Equal(t, 0, len(st.errors))
+
+	golden, err := os.ReadFile("testdata/TestAPI_routes.routes")
+	assert.NoError(t, err)
+	assert.Equal(t, "GET /users\n", string(golden))
+
+	t.Setenv("MUXTEST_UPDATE", "")
+
+	st = snapshot()
+	assert.Equal(t, 0, len(st.errors))
+
+	mux.Handle(http.MethodPost, "/users", h)
+
+	st = snapshot()
+	assert.False(t, st.fatal)
+	assert.Equal(t, 1, len(st.errors))
+	assert.Contains(t, st.errors[0], "+POST /users\n")
+}
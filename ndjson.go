@@ -0,0 +1,104 @@
+package webmux
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// NDJSONWriter streams values to a client as newline delimited JSON.
+// Each value sent is encoded on its own line.
+//
+// By default the response is flushed after every value. Set FlushInterval to
+// batch writes, flushing at most once per interval.
+type NDJSONWriter struct {
+	// FlushInterval is the minimum time between flushes.
+	// Zero flushes after every value.
+	FlushInterval time.Duration
+
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	enc       *json.Encoder
+	ctx       context.Context
+	started   bool
+	lastFlush time.Time
+}
+
+// NDJSON returns a new NDJSONWriter writing to w.
+// The response Content-Type is set to "application/x-ndjson" unless already set.
+func NDJSON(w http.ResponseWriter) *NDJSONWriter {
+	return &NDJSONWriter{
+		w:   w,
+		rc:  http.NewResponseController(w),
+		enc: json.NewEncoder(w),
+		ctx: context.Background(),
+	}
+}
+
+// WithContext returns a shallow copy of s that stops sending once ctx is done.
+// Typically ctx is the request context, so that streaming stops when the client disconnects.
+func (s *NDJSONWriter) WithContext(ctx context.Context) *NDJSONWriter {
+	if ctx == nil {
+		panic("webmux: nil context")
+	}
+
+	s2 := *s
+	s2.ctx = ctx
+
+	return &s2
+}
+
+// Send writes v as a single line of JSON.
+// Send returns the context error without writing if the context is done.
+func (s *NDJSONWriter) Send(v any) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.start()
+	}
+
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+
+	if s.FlushInterval <= 0 || Now(s.ctx).Sub(s.lastFlush) >= s.FlushInterval {
+		return s.Flush()
+	}
+
+	return nil
+}
+
+// Flush sends any buffered data to the client.
+func (s *NDJSONWriter) Flush() error {
+	if !s.started {
+		s.start()
+	}
+
+	s.lastFlush = Now(s.ctx)
+
+	err := s.rc.Flush()
+
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+
+	return err
+}
+
+// start writes the response headers.
+func (s *NDJSONWriter) start() {
+	s.started = true
+
+	h := s.w.Header()
+
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", "application/x-ndjson")
+	}
+
+	h.Set("X-Content-Type-Options", "nosniff")
+	s.w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,30 @@
+package webmux_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestNDJSON(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+
+	s := webmux.NDJSON(w).WithContext(ctx)
+
+	assert.NoError(t, s.Send(map[string]int{"n": 1}))
+	assert.NoError(t, s.Send(map[string]int{"n": 2}))
+
+	cancel()
+
+	assert.IsError(t, s.Send(map[string]int{"n": 3}), context.Canceled)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", w.Body.String())
+}
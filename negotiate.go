@@ -0,0 +1,167 @@
+package webmux
+
+import (
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a single media range from an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses the value of an Accept header into media ranges.
+// Malformed ranges are ignored.
+func parseAccept(accept string) []acceptRange {
+	ranges := make([]acceptRange, 0, strings.Count(accept, ",")+1)
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaRange)), "/")
+
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		ar := acceptRange{typ: typ, subtype: subtype, q: 1}
+
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					ar.q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, ar)
+	}
+
+	return ranges
+}
+
+// negotiateContentType returns the offered media type best matching the Accept
+// header value accept. Ties are broken by the order of offers.
+// If accept is empty the first offer is returned. If no offer is acceptable
+// negotiateContentType returns the empty string.
+func negotiateContentType(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+
+	for _, offer := range offers {
+		typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+
+		// The most specific matching range determines the quality of the offer
+		q, specificity := 0.0, -1
+
+		for _, ar := range ranges {
+			s := -1
+
+			switch {
+			case ar.typ == typ && ar.subtype == subtype:
+				s = 2
+			case ar.typ == typ && ar.subtype == "*":
+				s = 1
+			case ar.typ == "*" && ar.subtype == "*":
+				s = 0
+			}
+
+			if s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// languageRange is a single language range from an Accept-Language header.
+type languageRange struct {
+	tag string // lowercase language tag, like "fr-ca", or "*"
+	q   float64
+}
+
+// parseAcceptLanguage parses the value of an Accept-Language header into
+// language ranges. Malformed ranges are ignored.
+func parseAcceptLanguage(accept string) []languageRange {
+	ranges := make([]languageRange, 0, strings.Count(accept, ",")+1)
+
+	for _, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if tag == "" {
+			continue
+		}
+
+		lr := languageRange{tag: tag, q: 1}
+		k, v, _ := strings.Cut(strings.TrimSpace(params), "=")
+
+		if strings.EqualFold(k, "q") {
+			if q, err := strconv.ParseFloat(v, 64); err == nil {
+				lr.q = q
+			}
+		}
+
+		ranges = append(ranges, lr)
+	}
+
+	return ranges
+}
+
+// negotiateLanguage returns the offered language tag best matching the
+// Accept-Language header value accept. A range matches the tags it is a
+// prefix of, like "fr" for "fr-CA", and as a fallback the tags that are a
+// prefix of it, like "fr" for "fr-CA". Ties are broken by the order of
+// offers. If accept is empty or no offer is acceptable negotiateLanguage
+// returns the empty string.
+func negotiateLanguage(accept string, offers []string) string {
+	ranges := parseAcceptLanguage(accept)
+	best, bestQ := "", 0.0
+
+	for _, offer := range offers {
+		tag := strings.ToLower(offer)
+
+		// The most specific matching range determines the quality of the offer
+		q, specificity := 0.0, -1
+
+		for _, lr := range ranges {
+			s := -1
+
+			switch {
+			case lr.tag == tag:
+				s = 3
+			case strings.HasPrefix(tag, lr.tag+"-"):
+				s = 2
+			case strings.HasPrefix(lr.tag, tag+"-"):
+				s = 1
+			case lr.tag == "*":
+				s = 0
+			}
+
+			if s > specificity {
+				q, specificity = lr.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,85 @@
+package webmux
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// ErrorPage is the data of the HTML template of NegotiatingErrorHandler.
+type ErrorPage struct {
+	Code    int    // HTTP status code, like 404
+	Status  string // HTTP status text, like "Not Found", translated with Messages
+	Message string // description of the error, see JSONErrorHandler
+}
+
+// NegotiatingErrorOptions configures NegotiatingErrorHandler.
+type NegotiatingErrorOptions struct {
+	// HTML is the template rendering HTML error pages, executed with an
+	// ErrorPage. If nil, errors are not rendered as HTML.
+	HTML *template.Template
+
+	// Debug includes the details of server errors in the message.
+	Debug bool
+
+	// Messages translates the status texts and messages of errors in the
+	// language negotiated from the Accept-Language header. If nil, errors
+	// are rendered in English.
+	Messages Messages
+}
+
+// NegotiatingErrorHandler returns an error handler rendering errors as JSON,
+// HTML, or plain text depending on the Accept header of the request, for
+// muxes serving both a browser UI and an API from the same routes.
+//
+// JSON responses are rendered like JSONErrorHandler, and HTML responses with
+// opts.HTML. If the client accepts any type, JSON is preferred. Status codes
+// and messages are chosen like JSONErrorHandler, and server errors are logged.
+func NegotiatingErrorHandler(opts NegotiatingErrorOptions) ErrorHandler {
+	offers := []string{"application/json", "text/plain"}
+
+	if opts.HTML != nil {
+		offers = []string{"application/json", "text/html", "text/plain"}
+	}
+
+	return ErrorHandlerFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		code := errorStatus(w, r, err)
+
+		if code >= http.StatusInternalServerError {
+			logError(r, err)
+		}
+
+		l := opts.Messages.localizer(w, r)
+		message := l.translate(errorMessage(err, code, opts.Debug))
+
+		AddVary(w.Header(), "Accept")
+
+		switch negotiateContentType(r.Header.Get("Accept"), offers) {
+		case "application/json":
+			writeJSONError(w, code, message, l.fields(errorFields(err, code)))
+		case "text/html":
+			writeHTMLError(w, opts.HTML, code, l.translate(http.StatusText(code)), message)
+		default:
+			http.Error(w, message, code)
+		}
+	})
+}
+
+// writeHTMLError writes an HTML error response rendered with tmpl, falling
+// back to plain text if the template fails.
+func writeHTMLError(w http.ResponseWriter, tmpl *template.Template, code int, status, message string) {
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, ErrorPage{Code: code, Status: status, Message: message}); err != nil {
+		log.Printf("mux error: error template: %s", err.Error())
+		http.Error(w, message, code)
+
+		return
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
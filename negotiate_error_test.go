@@ -0,0 +1,74 @@
+package webmux_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestNegotiatingErrorHandler(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleError(webmux.NegotiatingErrorHandler(webmux.NegotiatingErrorOptions{
+		HTML: template.Must(template.New("error").Parse(`<h1>{{.Code}} {{.Status}}</h1><p>{{.Message}}</p>`)),
+	}))
+
+	tests := []struct {
+		accept, contentType, body string
+	}{
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html; charset=utf-8", "<h1>404 Not Found</h1><p>Not Found</p>"},
+		{"application/json", "application/json", `{"error":{"code":"not_found","message":"Not Found"}}` + "\n"},
+		{"*/*", "application/json", `{"error":{"code":"not_found","message":"Not Found"}}` + "\n"},
+		{"text/plain", "text/plain; charset=utf-8", "Not Found\n"},
+		{"image/png", "text/plain; charset=utf-8", "Not Found\n"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		r.Header.Set("Accept", tt.accept)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"), tt.accept)
+		assert.Equal(t, tt.body, w.Body.String())
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	}
+}
+
+func TestNegotiatingErrorHandlerMessages(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleError(webmux.NegotiatingErrorHandler(webmux.NegotiatingErrorOptions{
+		HTML: template.Must(template.New("error").Parse(`<h1>{{.Status}}</h1><p>{{.Message}}</p>`)),
+		Messages: webmux.Messages{
+			"fr":    {"Not Found": "Introuvable"},
+			"pt-BR": {"Not Found": "Não encontrado"},
+		},
+	}))
+
+	tests := []struct {
+		acceptLanguage, contentLanguage, body string
+	}{
+		{"fr-CA,fr;q=0.9,en;q=0.8", "fr", "<h1>Introuvable</h1><p>Introuvable</p>"},
+		{"pt", "pt-BR", "<h1>Não encontrado</h1><p>Não encontrado</p>"},
+		{"de,en;q=0.5", "", "<h1>Not Found</h1><p>Not Found</p>"},
+		{"", "", "<h1>Not Found</h1><p>Not Found</p>"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		r.Header.Set("Accept", "text/html")
+		r.Header.Set("Accept-Language", tt.acceptLanguage)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, tt.body, w.Body.String(), tt.acceptLanguage)
+		assert.Equal(t, tt.contentLanguage, w.Header().Get("Content-Language"), tt.acceptLanguage)
+		assert.Equal(t, []string{"Accept-Language", "Accept"}, w.Header().Values("Vary"))
+	}
+}
@@ -0,0 +1,81 @@
+package webmux
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// ErrNetworkNotAllowed is returned when a request is received from a network
+// not allowed by AllowCIDR.
+var ErrNetworkNotAllowed = errors.New("webmux: client network not allowed")
+
+// AllowCIDR returns a RouteOption restricting the route to clients in the
+// given networks, in CIDR notation like "10.0.0.0/8" or "fd00::/8". Requests
+// from other clients are rejected with a 403 Forbidden [HTTPError] wrapping
+// ErrNetworkNotAllowed. This protects internal endpoints even if they are
+// mistakenly deployed on a public listener:
+//
+//	mux.Handle(http.MethodGet, "/debug/vars", vars, webmux.AllowCIDR("10.0.0.0/8", "127.0.0.0/8"))
+//
+// Networks from multiple AllowCIDR options are combined. The client address
+// is taken from r.RemoteAddr, so behind a proxy the networks must include
+// the proxy, or r.RemoteAddr must be set from a trusted header first.
+// AllowCIDR panics if a network is invalid.
+func AllowCIDR(networks ...string) RouteOption {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+
+	for _, n := range networks {
+		p, err := netip.ParsePrefix(n)
+
+		if err != nil {
+			panic(fmt.Sprintf("webmux: invalid network %q: %s", n, err))
+		}
+
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.networks = append(cfg.networks, prefixes...)
+	}
+}
+
+// allowNetworks returns a middleware rejecting clients outside of networks.
+func allowNetworks(networks []netip.Prefix) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if !networkAllowed(networks, r.RemoteAddr) {
+				return NewHTTPError(http.StatusForbidden, ErrNetworkNotAllowed)
+			}
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+}
+
+// networkAllowed returns true if the host of remoteAddr is in one of networks.
+func networkAllowed(networks []netip.Prefix, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, p := range networks {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package webmux_test
+
+const raceEnabled = false
@@ -0,0 +1,158 @@
+package webmux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+)
+
+// ErrConflictingValues is returned when a header or query parameter which
+// must have a single value is given conflicting values.
+var ErrConflictingValues = errors.New("webmux: conflicting values")
+
+// defaultSingleHeaders are the headers which must have at most one value.
+var defaultSingleHeaders = []string{
+	"Authorization",
+	"Content-Length",
+	"Content-Type",
+	"Host",
+	"X-Forwarded-Host",
+	"X-Forwarded-Proto",
+}
+
+// NormalizeOptions configures the Normalize middleware.
+type NormalizeOptions struct {
+	// SingleHeaders are the headers which must have at most one value.
+	// If nil, Authorization, Content-Length, Content-Type, Host,
+	// X-Forwarded-Host, and X-Forwarded-Proto are used.
+	SingleHeaders []string
+
+	// SingleQuery are the query parameters which must have at most one value.
+	SingleQuery []string
+}
+
+// Normalize returns a middleware which normalizes requests before they reach
+// the handler, guarding against ambiguous requests used in request smuggling
+// and parameter pollution attacks.
+//
+// Header names are canonicalized. Headers and query parameters configured as
+// single valued are collapsed to one value if all of their values are equal,
+// and rejected with a 400 Bad Request [HTTPError] wrapping
+// ErrConflictingValues otherwise. Handlers can then rely on r.Header.Get and
+// r.URL.Query().Get returning the only value.
+func Normalize(opts NormalizeOptions) Middleware {
+	if opts.SingleHeaders == nil {
+		opts.SingleHeaders = defaultSingleHeaders
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			r, err := normalizeRequest(r, opts)
+
+			if err != nil {
+				return NewHTTPError(http.StatusBadRequest, err)
+			}
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+}
+
+// normalizeRequest returns a normalized shallow copy of r.
+func normalizeRequest(r *http.Request, opts NormalizeOptions) (*http.Request, error) {
+	header := make(http.Header, len(r.Header))
+
+	for name, values := range r.Header {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		header[key] = append(header[key], values...)
+	}
+
+	for _, name := range opts.SingleHeaders {
+		v, err := HeaderValue(&http.Request{Header: header}, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if v != "" {
+			header.Set(name, v)
+		}
+	}
+
+	// The server moves the Host header to r.Host, so a remaining one conflicts
+	if v := header.Get("Host"); v != "" && r.Host != "" && v != r.Host {
+		return nil, fmt.Errorf("%w for header Host", ErrConflictingValues)
+	}
+
+	r2 := r.Clone(r.Context())
+	r2.Header = header
+
+	if len(opts.SingleQuery) == 0 {
+		return r2, nil
+	}
+
+	query := r.URL.Query()
+	changed := false
+
+	for _, name := range opts.SingleQuery {
+		v, err := QueryValue(r, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(query[name]) > 1 {
+			query.Set(name, v)
+			changed = true
+		}
+	}
+
+	if changed {
+		r2.URL.RawQuery = query.Encode()
+		setRawQuery(r2.Context(), r2.URL.RawQuery)
+	}
+
+	return r2, nil
+}
+
+// HeaderValue returns the value of the header name in r, or an error wrapping
+// ErrConflictingValues if the header has more than one distinct value.
+// Unlike r.Header.Get, which silently returns the first value, it never
+// ignores a conflicting value.
+func HeaderValue(r *http.Request, name string) (string, error) {
+	v, ok := singleValue(r.Header.Values(name))
+
+	if !ok {
+		return "", fmt.Errorf("%w for header %s", ErrConflictingValues, textproto.CanonicalMIMEHeaderKey(name))
+	}
+
+	return v, nil
+}
+
+// QueryValue returns the value of the query parameter name in r, or an error
+// wrapping ErrConflictingValues if the parameter has more than one distinct value.
+func QueryValue(r *http.Request, name string) (string, error) {
+	v, ok := singleValue(r.URL.Query()[name])
+
+	if !ok {
+		return "", fmt.Errorf("%w for query parameter %s", ErrConflictingValues, name)
+	}
+
+	return v, nil
+}
+
+// singleValue returns the value of values if they are all equal.
+func singleValue(values []string) (string, bool) {
+	if len(values) == 0 {
+		return "", true
+	}
+
+	for _, v := range values[1:] {
+		if v != values[0] {
+			return "", false
+		}
+	}
+
+	return values[0], true
+}
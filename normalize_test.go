@@ -0,0 +1,54 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestNormalize(t *testing.T) {
+	var got *http.Request
+
+	h := webmux.Normalize(webmux.NormalizeOptions{SingleQuery: []string{"id"}})(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		got = r
+		return nil
+	}))
+
+	tests := []struct {
+		name     string
+		target   string
+		header   http.Header
+		wantErr  bool
+		wantType string
+		wantID   []string
+	}{
+		{"single", "/?id=1", http.Header{"Content-Type": {"text/plain"}}, false, "text/plain", []string{"1"}},
+		{"identical duplicates", "/?id=1&id=1", http.Header{"Content-Type": {"text/plain", "text/plain"}}, false, "text/plain", []string{"1"}},
+		{"header casing", "/", http.Header{"content-type": {"text/plain"}}, false, "text/plain", nil},
+		{"conflicting header", "/", http.Header{"Content-Type": {"text/plain", "application/json"}}, true, "", nil},
+		{"conflicting query", "/?id=1&id=2", nil, true, "", nil},
+		{"conflicting host", "/", http.Header{"Host": {"evil.example"}}, true, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Header = tt.header
+			got = nil
+
+			err := h.ServeHTTPErr(httptest.NewRecorder(), r)
+
+			if tt.wantErr {
+				assert.IsError(t, err, webmux.ErrConflictingValues)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.wantType}, got.Header.Values("Content-Type"))
+			assert.Equal(t, tt.wantID, got.URL.Query()["id"])
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotFound is returned by handlers when the entity addressed by a matched
+// route does not exist, such as "/users/:id" for an unknown id. Unlike
+// ErrMuxNotFound the route itself exists, which is known as a soft 404.
+//
+// The default error handler responds with 404 Not Found, and ServeMux counts
+// soft 404s per pattern, see NotFoundCounts.
+var ErrNotFound = errors.New("webmux: entity not found")
+
+// notFoundCounter counts soft 404s per pattern.
+type notFoundCounter struct {
+	counts sync.Map // pattern to *atomic.Int64
+}
+
+// add increments the count of pattern.
+func (c *notFoundCounter) add(pattern string) {
+	v, ok := c.counts.Load(pattern)
+
+	if !ok {
+		v, _ = c.counts.LoadOrStore(pattern, new(atomic.Int64))
+	}
+
+	v.(*atomic.Int64).Add(1)
+}
+
+// NotFoundCounts returns the number of requests per pattern whose handler
+// returned ErrNotFound. Patterns with a high count may indicate broken links
+// or enumeration attempts, and are useful to export as metrics.
+func (mux *ServeMux) NotFoundCounts() map[string]int64 {
+	counts := make(map[string]int64)
+
+	mux.notFound.counts.Range(func(k, v any) bool {
+		counts[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+
+	return counts
+}
+
+// NotFoundError is the error passed to the error handler by ServeMux for
+// requests which do not match any route. It wraps ErrMuxNotFound, and since
+// there is no MuxMatch in the context of such requests, it describes what
+// was looked up, so logs say more than "not found":
+//
+//	mux match not found: no route for /users/42/postz, nearest is /users/:id
+type NotFoundError struct {
+	// Path is the request path looked up, cleaned and escaped. For a mounted
+	// mux it is the whole request path.
+	Path string
+
+	// Nearest is the pattern of the route matching the longest leading
+	// segments of Path, like "/users/:id" for "/users/42/postz", or empty if
+	// no route does. For a mounted mux it is composed with the pattern of
+	// the mount, and is the pattern of the mount if no route of the mounted
+	// mux matches.
+	Nearest string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Nearest == "" {
+		return fmt.Sprintf("%s: no route for %s", ErrMuxNotFound, e.Path)
+	}
+
+	return fmt.Sprintf("%s: no route for %s, nearest is %s", ErrMuxNotFound, e.Path, e.Nearest)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrMuxNotFound
+}
+
+// NotFoundFromContext returns the NotFoundError of a request which does not
+// match any route. It is available to the not found handler and its
+// middleware, see HandleNotFound, and to the error handler.
+func NotFoundFromContext(ctx context.Context) (*NotFoundError, bool) {
+	err, ok := ctx.Value(notFoundKey).(*NotFoundError)
+	return err, ok
+}
+
+// notFoundError returns the NotFoundError of r, whose path does not match a
+// route of mux.
+func (mux *ServeMux) notFoundError(r *http.Request, path string, mounted *mountState) *NotFoundError {
+	mux.mu.RLock()
+	nearest := mux.nearest(r, path)
+	mux.mu.RUnlock()
+
+	if mounted == nil {
+		return &NotFoundError{Path: path, Nearest: nearest}
+	}
+
+	prefix := mounted.parent.pattern
+
+	if nearest != "" {
+		nearest = joinPattern(strings.TrimSuffix(prefix, "/*"), nearest)
+	} else {
+		nearest = prefix
+	}
+
+	return &NotFoundError{Path: cleanPath(r.URL.EscapedPath()), Nearest: nearest}
+}
+
+// nearest returns the pattern of the route matching the longest leading
+// segments of path in the trees searched by route, or an empty string if
+// there is none.
+func (mux *ServeMux) nearest(r *http.Request, path string) string {
+	if len(mux.hosts) > 0 {
+		host := requestHost(r)
+
+		for _, h := range mux.hosts {
+			if h.matchHost(host) {
+				if pattern := h.root.nearest(path); pattern != "" {
+					return pattern
+				}
+			}
+		}
+	}
+
+	return mux.root.nearest(path)
+}
+
+// nearest walks the tree n along path like match, returning the pattern of
+// the last entry passed, excluding that of n itself.
+func (n *node) nearest(path string) string {
+	current := n
+	pattern := ""
+
+	for path != "" {
+		head, tail := shiftPath(path)
+
+		if head == "" {
+			break
+		}
+
+		segment := unescapeSegment(head)
+		next, ok := current.children[segment]
+
+		if !ok && len(current.constrained) > 0 {
+			next, ok = current.matchConstrained(segment)
+		}
+
+		if !ok {
+			next, ok = current.children[":"]
+		}
+
+		if !ok {
+			break
+		}
+
+		current = next
+		path = tail
+
+		if current.entry != nil {
+			pattern = current.entry.pattern
+		}
+	}
+
+	return pattern
+}
@@ -0,0 +1,67 @@
+package webmux
+
+import "net/http"
+
+// SetOptionsHandler registers the handler called for OPTIONS requests to
+// routes without an OPTIONS handler, instead of the automatic 204 No Content
+// response. The handler runs through the middleware added with Use, with the
+// Allow header already set and the MuxMatch of the route in the request
+// context. This is useful for answering CORS preflight requests.
+func (mux *ServeMux) SetOptionsHandler(h Handler) {
+	if h == nil {
+		panic("webmux: nil handler")
+	}
+
+	mux.optionsHandler = h
+}
+
+// SetAutoOptions enables or disables the automatic handling of OPTIONS
+// requests, which is enabled by default. When disabled, OPTIONS requests to
+// routes without an OPTIONS handler are treated like any other method the
+// route does not handle, and OPTIONS is not included in the Allow header.
+func (mux *ServeMux) SetAutoOptions(enabled bool) {
+	mux.noAutoOptions = !enabled
+}
+
+// serveOptions serves the OPTIONS request r with the options handler.
+func (mux *ServeMux) serveOptions(w http.ResponseWriter, r *http.Request, match *MuxMatch, mounted *mountState) error {
+	if mounted != nil {
+		match = mounted.compose(match)
+	}
+
+	ctx, release := newStoreContext(r, mux.clock)
+	defer release()
+
+	return mux.chain(mux.optionsHandler).ServeHTTPErr(w, r.WithContext(NewContext(ctx, match)))
+}
+
+// Allow returns the value of the Allow header for the match, listing the
+// methods the route responds to, like "OPTIONS, GET, HEAD". Unlike Methods,
+// OPTIONS is excluded if automatic OPTIONS handling is disabled and the route
+// does not handle it, so middleware such as CORS can reuse it.
+func (m *MuxMatch) Allow() string {
+	if m.muxEntry == nil {
+		return ""
+	}
+
+	if !m.noAutoOptions || m.hasHandler(http.MethodOptions) {
+		return m.methods.String()
+	}
+
+	methods := make(MethodSet, 0, len(m.methods))
+
+	for _, method := range m.methods {
+		if method != http.MethodOptions {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods.String()
+}
+
+// hasHandler returns true if a handler is registered for method, with or without predicates.
+func (e *muxEntry) hasHandler(method string) bool {
+	_, ok := e.handlers[method]
+
+	return ok || len(e.conditional[method]) > 0
+}
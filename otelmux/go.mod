@@ -0,0 +1,22 @@
+module go.destructure.dev/webmux/otelmux
+
+go 1.21.5
+
+require (
+	github.com/alecthomas/assert/v2 v2.5.0
+	go.destructure.dev/webmux v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/alecthomas/repr v0.3.0 // indirect
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/hexops/gotextdiff v1.0.3 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
+
+replace go.destructure.dev/webmux => ../
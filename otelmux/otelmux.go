@@ -0,0 +1,125 @@
+// Package otelmux traces the requests of a [webmux.ServeMux] with
+// OpenTelemetry, naming spans by the matched route pattern:
+//
+//	mux := webmux.New()
+//	mux.Use(otelmux.Middleware(otelmux.Options{}))
+//
+// Unlike generic HTTP instrumentation, which only sees the URL path, spans
+// are named like "GET /users/:id" rather than "GET /users/42", so the
+// number of span names stays low, and the route params are recorded as
+// attributes.
+package otelmux
+
+import (
+	"net/http"
+
+	"go.destructure.dev/webmux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "go.destructure.dev/webmux/otelmux"
+
+// ParamKeyPrefix prefixes the names of the route params recorded as span
+// attributes, like "webmux.param.id".
+const ParamKeyPrefix = "webmux.param."
+
+// Options configures Middleware.
+type Options struct {
+	// TracerProvider creates the tracer. If nil, the global provider is used.
+	TracerProvider trace.TracerProvider
+
+	// Propagators extract the parent span context from request headers. If
+	// nil, the global propagators are used.
+	Propagators propagation.TextMapPropagator
+
+	// OmitParams does not record the route params, for routes whose params
+	// hold personal data.
+	OmitParams bool
+
+	// Filter returns false for requests which are not traced, like health
+	// checks. If nil, every request is traced.
+	Filter func(r *http.Request) bool
+}
+
+// Middleware returns a middleware starting a server span for each request,
+// to be added with [webmux.ServeMux.Use]. Requests which do not match a
+// route do not run middleware, and are not traced.
+//
+// The span is named after the request method and the route pattern, and has
+// the http.request.method, http.route, url.path and
+// http.response.status_code attributes. Errors returned by the handler are
+// recorded on the span, which then has an error status, as do server error
+// responses. Client disconnects, see [webmux.IsClientDisconnect], are
+// recorded as an event instead.
+func Middleware(opts Options) webmux.Middleware {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if opts.Propagators == nil {
+		opts.Propagators = otel.GetTextMapPropagator()
+	}
+
+	tracer := opts.TracerProvider.Tracer(ScopeName)
+
+	return func(next webmux.Handler) webmux.Handler {
+		return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if opts.Filter != nil && !opts.Filter(r) {
+				return next.ServeHTTPErr(w, r)
+			}
+
+			ctx := opts.Propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			}
+
+			name := r.Method
+
+			if match, ok := webmux.FromContext(ctx); ok {
+				name += " " + match.Pattern()
+				attrs = append(attrs, semconv.HTTPRoute(match.Pattern()))
+
+				if !opts.OmitParams {
+					for _, param := range match.Params() {
+						key := param
+
+						if key == "" {
+							key = "*"
+						}
+
+						attrs = append(attrs, attribute.String(ParamKeyPrefix+key, match.Param(key)))
+					}
+				}
+			}
+
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			sw := &webmux.StatusWriter{ResponseWriter: w}
+			err := next.ServeHTTPErr(sw, r.WithContext(ctx))
+
+			if sw.Status() != 0 {
+				span.SetAttributes(semconv.HTTPResponseStatusCode(sw.Status()))
+			}
+
+			switch {
+			case err != nil && webmux.IsClientDisconnect(r, err):
+				span.AddEvent("client disconnected", trace.WithAttributes(attribute.String("error.message", err.Error())))
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			case sw.Status() >= http.StatusInternalServerError:
+				span.SetStatus(codes.Error, http.StatusText(sw.Status()))
+			}
+
+			return err
+		})
+	}
+}
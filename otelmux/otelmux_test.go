@@ -0,0 +1,95 @@
+package otelmux_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/otelmux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	mux := webmux.New()
+	mux.Use(otelmux.Middleware(otelmux.Options{
+		TracerProvider: provider,
+		Propagators:    propagation.TraceContext{},
+		Filter: func(r *http.Request) bool {
+			return r.URL.Path != "/healthz"
+		},
+	}))
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		assert.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		w.Write([]byte("ok"))
+
+		return nil
+	})
+	mux.HandleFunc(http.MethodPost, "/users/:id/files/*", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("storage is down")
+	})
+	mux.HandleFunc(http.MethodGet, "/slow", func(w http.ResponseWriter, r *http.Request) error {
+		return context.Canceled
+	})
+	mux.HandleFunc(http.MethodGet, "/healthz", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/42/files/a/b", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+
+	spans := recorder.Ended()
+	assert.Equal(t, 3, len(spans))
+
+	get := spans[0]
+	assert.Equal(t, "GET /users/:id", get.Name())
+	assert.Equal(t, trace.SpanKindServer, get.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", get.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", get.Parent().SpanID().String())
+	assert.Equal(t, codes.Unset, get.Status().Code)
+
+	attrs := attributes(get.Attributes())
+	assert.Equal(t, "/users/:id", attrs["http.route"])
+	assert.Equal(t, "42", attrs["webmux.param.id"])
+	assert.Equal(t, "200", attrs["http.response.status_code"])
+
+	post := spans[1]
+	assert.Equal(t, "POST /users/:id/files/*", post.Name())
+	assert.Equal(t, "a/b", attributes(post.Attributes())["webmux.param.*"])
+	assert.Equal(t, codes.Error, post.Status().Code)
+	assert.Equal(t, "storage is down", post.Status().Description)
+	assert.Equal(t, "exception", post.Events()[0].Name)
+
+	slow := spans[2]
+	assert.Equal(t, codes.Unset, slow.Status().Code)
+	assert.Equal(t, "client disconnected", slow.Events()[0].Name)
+}
+
+func attributes(kvs []attribute.KeyValue) map[string]string {
+	m := make(map[string]string, len(kvs))
+
+	for _, kv := range kvs {
+		m[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	return m
+}
@@ -0,0 +1,142 @@
+package webmux
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MethodOverrideHeader is the default header overriding the request method.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// defaultOverrideMaxBody is the default of MethodOverrideOptions.MaxBodyBytes.
+const defaultOverrideMaxBody = 64 << 10
+
+// MethodOverrideOptions configures SetMethodOverride.
+type MethodOverrideOptions struct {
+	// Methods are the methods a POST request may be overridden to. If nil,
+	// PUT, PATCH, and DELETE are allowed.
+	Methods MethodSet
+
+	// Header is the request header naming the method. If empty,
+	// MethodOverrideHeader is used.
+	Header string
+
+	// Field is the form field naming the method. If empty, "_method" is
+	// used.
+	Field string
+
+	// MaxBodyBytes is the size of the largest URL-encoded body read for the
+	// form field. Larger bodies are handled with POST unless the header is
+	// set. If zero, a default of 64KiB is used.
+	MaxBodyBytes int64
+}
+
+// SetMethodOverride enables overriding the method of POST requests, so that
+// HTML forms, which can only submit GET and POST requests, and clients
+// behind proxies restricting methods can reach the routes of other methods:
+//
+//	mux.SetMethodOverride(webmux.MethodOverrideOptions{})
+//	mux.Handle(http.MethodDelete, "/posts/:id", deletePost)
+//
+//	<form method="post" action="/posts/1">
+//		<input type="hidden" name="_method" value="DELETE">
+//	</form>
+//
+// The method is read from the header of opts, or else from the form field
+// of URL-encoded bodies up to opts.MaxBodyBytes, which are left unread for
+// the handler. Malformed bodies are rejected with a 400 Bad Request
+// [HTTPError]. Multipart bodies are not read before routing, so that they
+// can be streamed with MultipartForm, and must name the method with the
+// header. The request is routed and handled with the overriding method if it
+// is one of the methods of opts, and with POST otherwise. Only POST requests
+// are overridden, since a link or image could otherwise make a browser send a
+// GET request deleting a resource.
+func (mux *ServeMux) SetMethodOverride(opts MethodOverrideOptions) {
+	if opts.Methods == nil {
+		opts.Methods = Methods(http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+
+	if opts.Header == "" {
+		opts.Header = MethodOverrideHeader
+	}
+
+	if opts.Field == "" {
+		opts.Field = "_method"
+	}
+
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultOverrideMaxBody
+	}
+
+	mux.methodOverride = &opts
+}
+
+// overrideMethod returns r with its method overridden as configured by
+// opts, or r if it is not overridden. It returns an error if the form of r
+// is malformed.
+func overrideMethod(r *http.Request, opts *MethodOverrideOptions) (*http.Request, error) {
+	if r.Method != http.MethodPost {
+		return r, nil
+	}
+
+	method := r.Header.Get(opts.Header)
+
+	if method == "" {
+		form, err := peekForm(r, opts.MaxBodyBytes)
+
+		if err != nil {
+			return r, NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		method = form.Get(opts.Field)
+	}
+
+	method = strings.ToUpper(strings.TrimSpace(method))
+
+	if method == "" || !opts.Methods.Has(method) {
+		return r, nil
+	}
+
+	r = r.WithContext(r.Context())
+	r.Method = method
+
+	return r, nil
+}
+
+// peekForm returns the values of the URL-encoded body of r, leaving the body
+// unread for the handler. The values are empty for other bodies, and for
+// bodies larger than max, which are not read beyond max.
+func peekForm(r *http.Request, max int64) (url.Values, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType != "application/x-www-form-urlencoded" || r.Body == nil || r.ContentLength > max {
+		return url.Values{}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	r.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), body: r.Body}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > max {
+		return url.Values{}, nil
+	}
+
+	return url.ParseQuery(string(body))
+}
+
+// peekedBody is a request body whose start was read by peekForm.
+type peekedBody struct {
+	io.Reader
+	body io.ReadCloser // the original body, closed by Close
+}
+
+func (b *peekedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,117 @@
+package webmux_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestSetMethodOverride(t *testing.T) {
+	mux := webmux.New()
+	mux.SetMethodOverride(webmux.MethodOverrideOptions{})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+		mux.HandleFunc(method, "/posts/:id", func(w http.ResponseWriter, r *http.Request) error {
+			w.Write([]byte(r.Method + " " + r.PostFormValue("title")))
+			return nil
+		})
+	}
+
+	tests := []struct {
+		method, contentType, body, header, want string
+	}{
+		{http.MethodPost, "application/x-www-form-urlencoded", "_method=delete", "", "DELETE "},
+		{http.MethodPost, "application/x-www-form-urlencoded", "_method=PUT&title=Hello", "", "PUT Hello"},
+		{http.MethodPost, "application/json", `{}`, "PUT", "PUT "},
+		{http.MethodPost, "application/x-www-form-urlencoded", "_method=GET", "", "POST "},
+		{http.MethodPost, "application/json", `{"_method":"PUT"}`, "", "POST "},
+		{http.MethodGet, "", "", "DELETE", "GET "},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/posts/1", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+
+		if tt.header != "" {
+			r.Header.Set(webmux.MethodOverrideHeader, tt.header)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code, tt.body)
+		assert.Equal(t, tt.want, w.Body.String(), tt.body)
+	}
+
+	mux.SetMethodOverride(webmux.MethodOverrideOptions{Methods: webmux.Methods(http.MethodDelete), Field: "verb"})
+
+	r := httptest.NewRequest(http.MethodPost, "/posts/1", strings.NewReader("verb=PUT"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, "POST ", w.Body.String())
+}
+
+func TestSetMethodOverrideBody(t *testing.T) {
+	upload := &webmux.MultipartForm{Store: webmux.FileStoreFunc(func(ctx context.Context, file *webmux.FilePart) (string, error) {
+		data, err := io.ReadAll(file)
+
+		return string(data), err
+	})}
+
+	mux := webmux.New()
+	mux.SetMethodOverride(webmux.MethodOverrideOptions{MaxBodyBytes: 32})
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		mux.HandleFunc(method, "/upload", func(w http.ResponseWriter, r *http.Request) error {
+			form, err := upload.Parse(r)
+
+			if err != nil {
+				return err
+			}
+
+			_, err = io.WriteString(w, r.Method+" "+form.Get("name")+" "+form.Get("avatar"))
+
+			return err
+		})
+		mux.HandleFunc(method, "/posts/:id", func(w http.ResponseWriter, r *http.Request) error {
+			_, err := io.WriteString(w, r.Method+" "+r.PostFormValue("title"))
+			return err
+		})
+	}
+
+	// Multipart bodies are streamed to the handler, and only the header overrides
+	r := newMultipartRequest(t, map[string]string{"name": "Ann", "_method": "PUT"}, map[string]string{"avatar": "PNG data"})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "POST Ann PNG data", w.Body.String())
+
+	r = newMultipartRequest(t, map[string]string{"name": "Ann"}, map[string]string{"avatar": "PNG data"})
+	r.Header.Set(webmux.MethodOverrideHeader, "PUT")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal(t, "PUT Ann PNG data", w.Body.String())
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/posts/1", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// Bodies larger than the limit are not read before routing
+	w = post("title=" + strings.Repeat("a", 32) + "&_method=PUT")
+	assert.Equal(t, "POST "+strings.Repeat("a", 32), w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, post("_method=%zz").Code)
+}
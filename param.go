@@ -0,0 +1,120 @@
+package webmux
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidParam is matched by a ParamError with [errors.Is].
+// The default error handler responds with 400 Bad Request.
+var ErrInvalidParam = errors.New("webmux: invalid param")
+
+// ParamError is returned by the typed param accessors of MuxMatch when a
+// parameter cannot be parsed.
+type ParamError struct {
+	Name  string // name of the parameter
+	Value string // value of the parameter, empty if it is missing
+	Err   error  // error parsing the value
+}
+
+// Error implements the error interface.
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("webmux: invalid param %s %q: %s", e.Name, e.Value, e.Err)
+}
+
+// Unwrap returns ErrInvalidParam and the error parsing the value.
+func (e *ParamError) Unwrap() []error {
+	return []error{ErrInvalidParam, e.Err}
+}
+
+// ParamInt returns the parameter value for name parsed as a base 10 int.
+// If the value is invalid a *ParamError is returned, so handlers can return
+// the error to respond with 400 Bad Request:
+//
+//	id, err := match.ParamInt("id")
+//	if err != nil {
+//		return err
+//	}
+func (m *MuxMatch) ParamInt(name string) (int, error) {
+	v := m.Param(name)
+	n, err := strconv.Atoi(v)
+
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: v, Err: numError(err)}
+	}
+
+	return n, nil
+}
+
+// ParamInt64 returns the parameter value for name parsed as a base 10 int64.
+// If the value is invalid a *ParamError is returned.
+func (m *MuxMatch) ParamInt64(name string) (int64, error) {
+	v := m.Param(name)
+	n, err := strconv.ParseInt(v, 10, 64)
+
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: v, Err: numError(err)}
+	}
+
+	return n, nil
+}
+
+// ParamBool returns the parameter value for name parsed with
+// [strconv.ParseBool]. If the value is invalid a *ParamError is returned.
+func (m *MuxMatch) ParamBool(name string) (bool, error) {
+	v := m.Param(name)
+	b, err := strconv.ParseBool(v)
+
+	if err != nil {
+		return false, &ParamError{Name: name, Value: v, Err: numError(err)}
+	}
+
+	return b, nil
+}
+
+// ParamUUID returns the parameter value for name if it is a UUID in the
+// canonical form "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx", in lower case.
+// If the value is invalid a *ParamError is returned.
+func (m *MuxMatch) ParamUUID(name string) (string, error) {
+	v := m.Param(name)
+
+	if !isUUID(v) {
+		return "", &ParamError{Name: name, Value: v, Err: errors.New("invalid UUID")}
+	}
+
+	return strings.ToLower(v), nil
+}
+
+// numError returns the underlying error of a [strconv.NumError], which
+// repeats the value and function name.
+func numError(err error) error {
+	var numErr *strconv.NumError
+
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+
+	return err
+}
+
+// isUUID returns true if s is a UUID in the canonical textual form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for _, i := range []int{8, 13, 18, 23} {
+		if s[i] != '-' {
+			return false
+		}
+	}
+
+	var b [16]byte
+
+	_, err := hex.Decode(b[:], []byte(s[0:8]+s[9:13]+s[14:18]+s[19:23]+s[24:36]))
+
+	return err == nil
+}
@@ -1,6 +1,9 @@
 package webmux
 
 import (
+	"net/http"
+	"net/url"
+	"path"
 	"strings"
 )
 
@@ -16,17 +19,62 @@ func shiftPath(p string) (head string, tail string) {
 	return p[1:i], p[i:]
 }
 
-// cleanPath returns the canonical URL path for p.
+// unescapeSegment returns the path segment s with its escapes decoded, or s
+// if it is not validly escaped.
+func unescapeSegment(s string) string {
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+
+	return s
+}
+
+// cleanPath returns the canonical URL path for p, with a leading slash,
+// duplicate slashes collapsed, and "." and ".." segments resolved, as by
+// [path.Clean]. Unlike path.Clean a trailing slash is kept. Escaped dots and
+// slashes, as in "%2E%2E", are part of their segment and left alone.
 func cleanPath(p string) string {
 	if p == "" {
 		return "/"
-
 	}
 
 	if p[0] != '/' {
 		p = "/" + p
+	}
+
+	np := path.Clean(p)
+
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+
+	return np
+}
+
+// SetRedirectCleanPath enables or disables redirecting requests to the clean
+// form of their path, which is disabled by default. Request paths with
+// duplicate slashes or "." and ".." segments, like "//users" or
+// "/a/../users", are routed as their clean form, "/users". When enabled,
+// such requests are instead redirected permanently to the clean path, with
+// the query kept, so clients and caches only see canonical URLs. GET and HEAD
+// requests are redirected with 301 Moved Permanently, and others with 308
+// Permanent Redirect, see Redirect. Requests to mounted muxes are redirected
+// by the parent mux.
+func (mux *ServeMux) SetRedirectCleanPath(enabled bool) {
+	mux.redirectCleanPath = enabled
+}
+
+// redirectCleanPath redirects r to the clean path, keeping its query.
+func redirectCleanPath(w http.ResponseWriter, r *http.Request, clean string) {
+	url := clean
 
+	if r.URL.RawQuery != "" {
+		url += "?" + r.URL.RawQuery
 	}
 
-	return p
+	Redirect(w, r, url, true)
 }
@@ -0,0 +1,182 @@
+package webmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SegmentKind is the kind of a Segment.
+type SegmentKind int
+
+// Kinds of segments.
+const (
+	LiteralSegment  SegmentKind = iota // matches its value exactly, like "users"
+	ParamSegment                       // matches a single segment, like ":id"
+	WildcardSegment                    // matches one or more segments, like "*path"
+)
+
+// Segment is a path segment of a route pattern, as parsed by a PatternEngine.
+type Segment struct {
+	Kind SegmentKind
+
+	// Value is the text of a literal segment, or the name of a param or
+	// wildcard. A literal segment ending the pattern may be empty, for a
+	// pattern ending with a slash.
+	Value string
+
+	// Constraint is a regular expression the whole segment must match for a
+	// param, or empty if any segment matches.
+	Constraint string
+}
+
+// PatternEngine parses the patterns given to the methods of ServeMux into
+// segments, so a mux can use a syntax other than its own, see
+// SetPatternEngine. The segments are matched by the routing tree of the mux,
+// whatever the syntax.
+type PatternEngine interface {
+	ParsePattern(pattern string) ([]Segment, error)
+}
+
+// The PatternEngineFunc type is an adapter to allow functions to be used as
+// pattern engines.
+type PatternEngineFunc func(pattern string) ([]Segment, error)
+
+// ParsePattern calls f(pattern).
+func (f PatternEngineFunc) ParsePattern(pattern string) ([]Segment, error) {
+	return f(pattern)
+}
+
+// DefaultPatternEngine parses the syntax of webmux, with named params like
+// ":id", constrained params like ":id(\d+)" and wildcards like "*path".
+var DefaultPatternEngine PatternEngine = PatternEngineFunc(parseDefaultPattern)
+
+// ExactPatternEngine parses patterns made of literal segments only, for muxes
+// which must not route on params, like those of an allowlist of callback
+// URLs. Patterns with a segment starting with ":" or "*" are rejected.
+var ExactPatternEngine PatternEngine = PatternEngineFunc(parseExactPattern)
+
+// SetPatternEngine sets the engine parsing the patterns given to mux, which
+// is DefaultPatternEngine if engine is nil. It must be called before routes
+// are registered, and replaces AcceptBraceParams:
+//
+//	mux.SetPatternEngine(webmux.PatternEngineFunc(func(pattern string) ([]webmux.Segment, error) {
+//		// parse patterns like "/users/<id>"
+//	}))
+//
+// Patterns are converted to the syntax of webmux when registered, which is
+// the syntax of the patterns reported by MuxMatch, Routes and Explain.
+func (mux *ServeMux) SetPatternEngine(engine PatternEngine) {
+	mux.engine = engine
+}
+
+// normalizePattern returns pattern in the syntax of webmux, as parsed by the
+// pattern engine of mux, panicking if it is invalid.
+func (mux *ServeMux) normalizePattern(pattern string) string {
+	if mux.engine == nil {
+		return pattern
+	}
+
+	segments, err := mux.engine.ParsePattern(pattern)
+
+	if err == nil {
+		var normalized string
+
+		if normalized, err = formatPattern(segments); err == nil {
+			return normalized
+		}
+	}
+
+	panic(fmt.Sprintf("webmux: invalid pattern %s: %s", pattern, err))
+}
+
+// formatPattern returns the pattern of segments in the syntax of webmux.
+func formatPattern(segments []Segment) (string, error) {
+	var b strings.Builder
+
+	for i, s := range segments {
+		if strings.Contains(s.Value, "/") {
+			return "", fmt.Errorf("segment %q contains a slash", s.Value)
+		}
+
+		b.WriteByte('/')
+
+		switch s.Kind {
+		case LiteralSegment:
+			if s.Value == "" && i != len(segments)-1 {
+				return "", fmt.Errorf("empty segment")
+			}
+
+			// The routing tree would take it for a param or wildcard
+			if strings.HasPrefix(s.Value, ":") || strings.HasPrefix(s.Value, "*") {
+				return "", fmt.Errorf("literal segment %q starts with %q", s.Value, s.Value[:1])
+			}
+
+			b.WriteString(s.Value)
+		case ParamSegment:
+			if strings.ContainsAny(s.Value, "()") {
+				return "", fmt.Errorf("param name %q contains a parenthesis", s.Value)
+			}
+
+			if strings.Contains(s.Constraint, "/") {
+				return "", fmt.Errorf("constraint of %s contains a slash", s.Value)
+			}
+
+			b.WriteString(":" + s.Value)
+
+			if s.Constraint != "" {
+				b.WriteString("(" + s.Constraint + ")")
+			}
+		case WildcardSegment:
+			b.WriteString("*" + s.Value)
+		default:
+			return "", fmt.Errorf("unknown segment kind %d", s.Kind)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "/", nil
+	}
+
+	return b.String(), nil
+}
+
+// parseDefaultPattern parses pattern in the syntax of webmux.
+func parseDefaultPattern(pattern string) ([]Segment, error) {
+	parts := strings.Split(cleanPath(pattern)[1:], "/")
+	segments := make([]Segment, 0, len(parts))
+
+	for _, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			name, expr, ok := strings.Cut(part[1:], "(")
+
+			if ok && !strings.HasSuffix(expr, ")") {
+				return nil, fmt.Errorf("invalid constraint in %s", part)
+			}
+
+			segments = append(segments, Segment{Kind: ParamSegment, Value: name, Constraint: strings.TrimSuffix(expr, ")")})
+		case strings.HasPrefix(part, "*"):
+			segments = append(segments, Segment{Kind: WildcardSegment, Value: part[1:]})
+		default:
+			segments = append(segments, Segment{Kind: LiteralSegment, Value: part})
+		}
+	}
+
+	return segments, nil
+}
+
+// parseExactPattern parses pattern as literal segments.
+func parseExactPattern(pattern string) ([]Segment, error) {
+	parts := strings.Split(cleanPath(pattern)[1:], "/")
+	segments := make([]Segment, 0, len(parts))
+
+	for _, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			return nil, fmt.Errorf("dynamic segment %s in exact pattern", part)
+		}
+
+		segments = append(segments, Segment{Kind: LiteralSegment, Value: part})
+	}
+
+	return segments, nil
+}
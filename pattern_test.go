@@ -0,0 +1,120 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServeMuxSetPatternEngine(t *testing.T) {
+	// Parses patterns like "/users/<id>" and "/files/<path...>"
+	angles := webmux.PatternEngineFunc(func(pattern string) ([]webmux.Segment, error) {
+		var segments []webmux.Segment
+
+		for _, part := range strings.Split(strings.Trim(pattern, "/"), "/") {
+			name, ok := strings.CutPrefix(part, "<")
+
+			switch {
+			case !ok:
+				segments = append(segments, webmux.Segment{Kind: webmux.LiteralSegment, Value: part})
+			case strings.HasSuffix(name, "...>"):
+				segments = append(segments, webmux.Segment{Kind: webmux.WildcardSegment, Value: strings.TrimSuffix(name, "...>")})
+			default:
+				name, expr, _ := strings.Cut(strings.TrimSuffix(name, ">"), "|")
+				segments = append(segments, webmux.Segment{Kind: webmux.ParamSegment, Value: name, Constraint: expr})
+			}
+		}
+
+		return segments, nil
+	})
+
+	h := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		m, _ := webmux.FromContext(r.Context())
+		_, err := w.Write([]byte(m.Pattern() + " " + m.Param("id") + m.Param("path")))
+		return err
+	})
+
+	api := webmux.New()
+	api.Handle(http.MethodGet, "/orders/:id", h)
+
+	mux := webmux.New()
+	mux.SetPatternEngine(angles)
+	mux.Handle(http.MethodGet, "/users/<id>", h)
+	mux.Handle(http.MethodGet, "/orders/<id|\\d+>", h)
+	mux.Handle(http.MethodGet, "/files/<path...>", h)
+	mux.Mount("/teams/<team>", api)
+
+	for path, want := range map[string]string{
+		"/users/1":           "/users/:id 1",
+		"/orders/42":         "/orders/:id(\\d+) 42",
+		"/files/a/b.txt":     "/files/*path a/b.txt",
+		"/teams/t1/orders/7": "/teams/:team/orders/:id 7",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, w.Body.String(), path)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/abc", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.True(t, mux.Unhandle(http.MethodGet, "/users/<id>"))
+
+	assert.Panics(t, func() {
+		mux.Handle(http.MethodGet, "/:literal", h)
+	})
+}
+
+func TestExactPatternEngine(t *testing.T) {
+	api := webmux.New()
+	api.Handle(http.MethodGet, "/status", newTestHandler("status"))
+
+	mux := webmux.New()
+	mux.SetPatternEngine(webmux.ExactPatternEngine)
+	mux.Handle(http.MethodPost, "/callbacks/github", newTestHandler("github"))
+	mux.Handle(http.MethodGet, "/", newTestHandler("home"))
+	mux.Mount("/api", api)
+
+	for path, want := range map[string]string{
+		"/callbacks/github": "github",
+		"/":                 "home",
+		"/api/status":       "status",
+	} {
+		method := http.MethodGet
+
+		if strings.HasPrefix(path, "/callbacks") {
+			method = http.MethodPost
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		assert.Equal(t, want, w.Body.String(), path)
+	}
+
+	assert.Panics(t, func() {
+		mux.Handle(http.MethodPost, "/callbacks/:provider", newTestHandler("any"))
+	})
+	assert.Panics(t, func() {
+		mux.Handle(http.MethodGet, "/files/*", newTestHandler("any"))
+	})
+}
+
+func TestDefaultPatternEngine(t *testing.T) {
+	segments, err := webmux.DefaultPatternEngine.ParsePattern("/users/:id(\\d+)/files/*path/")
+	assert.NoError(t, err)
+	assert.Equal(t, []webmux.Segment{
+		{Kind: webmux.LiteralSegment, Value: "users"},
+		{Kind: webmux.ParamSegment, Value: "id", Constraint: "\\d+"},
+		{Kind: webmux.LiteralSegment, Value: "files"},
+		{Kind: webmux.WildcardSegment, Value: "path"},
+		{Kind: webmux.LiteralSegment, Value: ""},
+	}, segments)
+
+	_, err = webmux.DefaultPatternEngine.ParsePattern("/users/:id(\\d+")
+	assert.Error(t, err)
+}
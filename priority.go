@@ -0,0 +1,143 @@
+package webmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority returns a RouteOption setting the priority of the route, for
+// overlapping patterns where the usual precedence does not pick the route
+// you want. Routes have a priority of zero by default. When a request path
+// matches a route with a non-zero priority, the matching route with the
+// highest priority wins over the route chosen by the usual rules, and
+// routes with equal priority are decided by the usual rules:
+//
+//	mux.Handle(http.MethodGet, "/users/:id", showUser)
+//	mux.Handle(http.MethodGet, "/users/me", showMe)
+//	mux.Handle(http.MethodGet, "/*", spa, webmux.Priority(-1))
+//	mux.Handle(http.MethodGet, "/users/:id/avatar", avatar, webmux.Priority(1))
+//
+// Unlike the usual rules, which follow the most exact segment of the path
+// and never go back, prioritized routes are found along every path of the
+// routing tree. Here "/users/me/avatar" is handled by avatar, although
+// "/users/me" is an exact match for the first segments. A negative priority
+// makes a route a fallback, used only when no other route matches, like spa
+// above.
+//
+// The priority belongs to the pattern, with all its methods, in the tree of
+// its host; the host still decides before the priority, see Host. Handle
+// panics if the pattern is registered with different priorities. Lookups
+// are slower once any route has a priority.
+func Priority(n int) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.priority = n
+	}
+}
+
+// setPriority sets the priority of e from cfg, panicking if e already has a
+// different priority.
+func (e *muxEntry) setPriority(cfg *routeConfig) {
+	if cfg.priority == 0 {
+		return
+	}
+
+	if e.priority != 0 && e.priority != cfg.priority {
+		panic(fmt.Sprintf("webmux: conflicting priorities %d and %d for %s", e.priority, cfg.priority, e.pattern))
+	}
+
+	e.priority = cfg.priority
+}
+
+// prioritized is the best route found by lookupPriority.
+type prioritized struct {
+	entry  *muxEntry
+	values []string
+}
+
+// lookupPriority finds the route with the highest priority matching path in
+// the tree root, replacing the route of found, if any, unless its priority
+// is at least as high. The values of the match found are those appended to
+// match.values after its first n values.
+func lookupPriority(root *node, path string, match, found *MuxMatch, n int) *MuxMatch {
+	var best prioritized
+
+	if found != nil {
+		best.entry = found.muxEntry
+	}
+
+	root.searchPriority(path, nil, &best)
+
+	if best.entry == nil || (found != nil && best.entry == found.muxEntry) {
+		return found
+	}
+
+	match.muxEntry = best.entry
+	match.values = append(match.values[:n], best.values...)
+
+	return match
+}
+
+// searchPriority visits the nodes of n matching path in the order of the
+// usual rules, recording the entry with a non-zero priority higher than
+// that of best in best.
+func (n *node) searchPriority(path string, values []string, best *prioritized) {
+	head, tail := "", ""
+
+	if path != "" {
+		head, tail = shiftPath(path)
+	}
+
+	if head == "" {
+		best.offer(n.entry, values)
+		return
+	}
+
+	segment := unescapeSegment(head)
+
+	if next, ok := n.children[segment]; ok {
+		next.searchPriority(tail, values, best)
+	}
+
+	for _, c := range n.constrained {
+		if c.re.MatchString(segment) {
+			c.node.searchPriority(tail, append(values[:len(values):len(values)], head), best)
+		}
+	}
+
+	if next, ok := n.children[":"]; ok {
+		next.searchPriority(tail, append(values[:len(values):len(values)], head), best)
+	}
+
+	if next, ok := n.children["*"]; ok {
+		next.searchWildcard(head+tail, values[:len(values):len(values)], best)
+	}
+}
+
+// searchWildcard is like searchPriority for the wildcard node n and rest, the
+// remaining path without its leading slash, see matchWildcard.
+func (n *node) searchWildcard(rest string, values []string, best *prioritized) {
+	if len(n.children) > 0 || len(n.constrained) > 0 {
+		for i := strings.LastIndexByte(rest, '/'); i > 0; i = strings.LastIndexByte(rest[:i], '/') {
+			if i < len(rest)-1 {
+				n.searchPriority(rest[i:], append(values, rest[:i]), best)
+			}
+		}
+	}
+
+	best.offer(n.entry, append(values, rest))
+}
+
+// offer records e in p if it has a non-zero priority higher than that of
+// the entry of p.
+func (p *prioritized) offer(e *muxEntry, values []string) {
+	if e == nil || e.priority == 0 {
+		return
+	}
+
+	if p.entry != nil && e.priority <= p.entry.priority {
+		return
+	}
+
+	p.entry = e
+	p.values = values
+}
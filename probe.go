@@ -0,0 +1,116 @@
+package webmux
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// A ProbeClassifier recognizes requests probing for vulnerabilities, such as
+// requests for "/wp-admin" or "/.env" on a server that has neither.
+type ProbeClassifier interface {
+	// Classify returns the category of the probe, like "wordpress" or
+	// "secrets", or false if r is not a probe.
+	Classify(r *http.Request) (string, bool)
+}
+
+// The ProbeClassifierFunc type is an adapter to allow the use of ordinary
+// functions as probe classifiers.
+type ProbeClassifierFunc func(r *http.Request) (string, bool)
+
+// Classify calls f(r).
+func (f ProbeClassifierFunc) Classify(r *http.Request) (string, bool) {
+	return f(r)
+}
+
+// probePaths are well-known path prefixes requested by vulnerability scanners, by category.
+var probePaths = []struct {
+	prefix   string
+	category string
+}{
+	{"/wp-admin", "wordpress"},
+	{"/wp-login.php", "wordpress"},
+	{"/wp-content", "wordpress"},
+	{"/wp-includes", "wordpress"},
+	{"/xmlrpc.php", "wordpress"},
+	{"/.env", "secrets"},
+	{"/.git/", "secrets"},
+	{"/.aws/", "secrets"},
+	{"/.ssh/", "secrets"},
+	{"/.htpasswd", "secrets"},
+	{"/config.json", "secrets"},
+	{"/phpmyadmin", "admin"},
+	{"/pma", "admin"},
+	{"/adminer", "admin"},
+	{"/server-status", "admin"},
+	{"/actuator", "admin"},
+	{"/cgi-bin/", "exploit"},
+	{"/vendor/phpunit", "exploit"},
+	{"/boaform", "exploit"},
+	{"/shell", "exploit"},
+}
+
+// DefaultProbeClassifier returns a ProbeClassifier recognizing requests for
+// well-known attack paths, and paths attempting directory traversal.
+func DefaultProbeClassifier() ProbeClassifier {
+	return ProbeClassifierFunc(func(r *http.Request) (string, bool) {
+		path := strings.ToLower(r.URL.EscapedPath())
+
+		if strings.Contains(path, "../") || strings.Contains(path, "..%2f") {
+			return "traversal", true
+		}
+
+		for _, p := range probePaths {
+			if strings.HasPrefix(path, p.prefix) {
+				return p.category, true
+			}
+		}
+
+		if strings.HasSuffix(path, ".php") || strings.HasSuffix(path, ".asp") || strings.HasSuffix(path, ".aspx") {
+			return "exploit", true
+		}
+
+		return "", false
+	})
+}
+
+// ProbeOptions configures probe detection, see DetectProbes.
+type ProbeOptions struct {
+	// Classifier recognizes probes. If nil, DefaultProbeClassifier is used.
+	Classifier ProbeClassifier
+
+	// Alert is called for each probe with its category. If nil, probes are logged.
+	Alert func(r *http.Request, category string)
+}
+
+// DetectProbes turns requests not matching any route into a lightweight
+// intrusion signal. Requests not matching a route are classified with
+// opts.Classifier, and probes are reported to opts.Alert before the request
+// is handled as not found.
+//
+// Since legitimate routes are never classified, paths like "/.env" can act as
+// honeytokens: any request for them indicates a scanner or an attacker.
+func (mux *ServeMux) DetectProbes(opts ProbeOptions) {
+	if opts.Classifier == nil {
+		opts.Classifier = DefaultProbeClassifier()
+	}
+
+	if opts.Alert == nil {
+		opts.Alert = func(r *http.Request, category string) {
+			log.Printf("mux probe: %s %s %s from %s", category, r.Method, r.URL.RequestURI(), r.RemoteAddr)
+		}
+	}
+
+	mux.probes = &opts
+}
+
+// detectProbe reports r if it is classified as a probe.
+func (mux *ServeMux) detectProbe(r *http.Request) {
+	if mux.probes == nil {
+		return
+	}
+
+	if category, ok := mux.probes.Classifier.Classify(r); ok {
+		mux.probes.Alert(r, category)
+	}
+}
@@ -0,0 +1,24 @@
+module go.destructure.dev/webmux/promux
+
+go 1.21.5
+
+require (
+	github.com/alecthomas/assert/v2 v2.5.0
+	github.com/prometheus/client_golang v1.19.1
+	go.destructure.dev/webmux v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/alecthomas/repr v0.3.0 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/hexops/gotextdiff v1.0.3 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+replace go.destructure.dev/webmux => ../
@@ -0,0 +1,147 @@
+// Package promux collects Prometheus metrics of the requests served by a
+// [webmux.ServeMux], labeled by the matched route pattern:
+//
+//	mux := webmux.New()
+//	metrics := promux.New(promux.Options{})
+//
+//	http.ListenAndServe(":8080", metrics.Handler(mux))
+//
+// Unlike generic HTTP instrumentation, which only sees the URL path, the
+// requests for "/users/42" and "/users/43" are both counted under the
+// pattern "/users/:id", so the number of series stays low. Requests which
+// do not match a route, like those of scanners probing for random paths,
+// share the UnmatchedPattern label.
+package promux
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.destructure.dev/webmux"
+)
+
+// UnmatchedPattern is the pattern label of requests which do not match a
+// route.
+const UnmatchedPattern = "(unmatched)"
+
+// OtherMethod is the method label of requests with a method other than the
+// standard ones, which clients can choose freely.
+const OtherMethod = "OTHER"
+
+// Options configures New.
+type Options struct {
+	// Registerer registers the collectors. If nil,
+	// [prometheus.DefaultRegisterer] is used.
+	Registerer prometheus.Registerer
+
+	// Namespace prefixes the metric names, as in "myapp_http_requests_total".
+	Namespace string
+
+	// Buckets are the buckets of the request duration histogram, in
+	// seconds. If nil, [prometheus.DefBuckets] is used.
+	Buckets []float64
+}
+
+// Metrics collects the metrics of requests:
+//
+//   - http_requests_total, a counter of requests labeled by method, pattern
+//     and status code
+//   - http_request_duration_seconds, a histogram of the time to serve
+//     requests labeled by method and pattern
+//   - http_requests_in_flight, a gauge of the requests being served labeled
+//     by method and pattern
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// New returns Metrics whose collectors are registered with the registerer
+// of opts. It panics if the collectors cannot be registered, for instance
+// if New is called twice with the same registerer and namespace.
+func New(opts Options) *Metrics {
+	if opts.Registerer == nil {
+		opts.Registerer = prometheus.DefaultRegisterer
+	}
+
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "pattern", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by method and route pattern.",
+			Buckets:   opts.Buckets,
+		}, []string{"method", "pattern"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: opts.Namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being served by method and route pattern.",
+		}, []string{"method", "pattern"}),
+	}
+
+	opts.Registerer.MustRegister(m.requests, m.duration, m.inFlight)
+
+	return m
+}
+
+// Handler returns a handler collecting the metrics of the requests served by
+// next, which is a ServeMux or a handler wrapping one. Unlike a middleware
+// added with [webmux.ServeMux.Use], it sees the requests failing with 404
+// Not Found and 405 Method Not Allowed, which are often the first sign of a
+// broken client, see [webmux.ObserveRoute].
+//
+// A request is counted in flight as unmatched until the mux matches its
+// route. The pattern of a request served by a mounted mux is the composed
+// pattern of its route, like "/api/users/:id".
+func (m *Metrics) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		method := methodLabel(r.Method)
+		pattern := UnmatchedPattern
+
+		m.inFlight.WithLabelValues(method, pattern).Inc()
+
+		observe := func(p string) {
+			m.inFlight.WithLabelValues(method, pattern).Dec()
+			m.inFlight.WithLabelValues(method, p).Inc()
+			pattern = p
+		}
+
+		sw := &webmux.StatusWriter{ResponseWriter: w}
+
+		defer func() {
+			status := sw.Status()
+
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			m.inFlight.WithLabelValues(method, pattern).Dec()
+			m.duration.WithLabelValues(method, pattern).Observe(time.Since(start).Seconds())
+			m.requests.WithLabelValues(method, pattern, strconv.Itoa(status)).Inc()
+		}()
+
+		next.ServeHTTP(sw, webmux.ObserveRoute(r, observe))
+	})
+}
+
+// methodLabel returns the label of method.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return OtherMethod
+}
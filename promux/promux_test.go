@@ -0,0 +1,104 @@
+package promux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/promux"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := promux.New(promux.Options{Registerer: registry, Namespace: "app"})
+
+	var inFlight float64
+
+	api := webmux.New()
+	api.HandleFunc(http.MethodGet, "/orders/:id", func(w http.ResponseWriter, r *http.Request) error {
+		return webmux.NewHTTPError(http.StatusTeapot, nil)
+	})
+
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) error {
+		inFlight = gauge(t, registry, "/users/:id")
+		w.Write([]byte("ok"))
+
+		return nil
+	})
+	mux.HandleMethodNotAllowed(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil
+	}))
+	mux.Mount("/api", api)
+
+	h := metrics.Handler(mux)
+
+	for _, target := range []string{"/users/1", "/users/2", "/missing", "/api/orders/3"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/users/1", nil))
+
+	assert.Equal(t, 1.0, inFlight)
+
+	expected := `
+# HELP app_http_requests_total Number of HTTP requests by method, route pattern and status code.
+# TYPE app_http_requests_total counter
+app_http_requests_total{code="200",method="GET",pattern="/users/:id"} 2
+app_http_requests_total{code="404",method="GET",pattern="(unmatched)"} 1
+app_http_requests_total{code="405",method="DELETE",pattern="/users/:id"} 1
+app_http_requests_total{code="405",method="OTHER",pattern="/users/:id"} 1
+app_http_requests_total{code="418",method="GET",pattern="/api/orders/:id"} 1
+# HELP app_http_requests_in_flight Number of HTTP requests being served by method and route pattern.
+# TYPE app_http_requests_in_flight gauge
+app_http_requests_in_flight{method="DELETE",pattern="(unmatched)"} 0
+app_http_requests_in_flight{method="DELETE",pattern="/users/:id"} 0
+app_http_requests_in_flight{method="GET",pattern="(unmatched)"} 0
+app_http_requests_in_flight{method="GET",pattern="/api/*"} 0
+app_http_requests_in_flight{method="GET",pattern="/api/orders/:id"} 0
+app_http_requests_in_flight{method="GET",pattern="/users/:id"} 0
+app_http_requests_in_flight{method="OTHER",pattern="(unmatched)"} 0
+app_http_requests_in_flight{method="OTHER",pattern="/users/:id"} 0
+`
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_http_requests_total", "app_http_requests_in_flight")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5, testutil.CollectAndCount(registry, "app_http_request_duration_seconds"))
+}
+
+// gauge returns the value of the in-flight gauge of GET requests for
+// pattern.
+func gauge(t *testing.T, registry *prometheus.Registry, pattern string) float64 {
+	t.Helper()
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "app_http_requests_in_flight" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			if labels["method"] == http.MethodGet && labels["pattern"] == pattern {
+				return m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,46 @@
+package webmux
+
+import (
+	"context"
+	"net/url"
+)
+
+// Query returns the query parameters of the request of ctx, parsed from
+// r.URL.RawQuery once per request and cached in the per-request store, so
+// middleware and handlers do not each re-parse the query like r.URL.Query:
+//
+//	page := webmux.Query(r.Context()).Get("page")
+//
+// The returned values are shared and must not be modified. Malformed pairs
+// are discarded like [url.ParseQuery]. If ctx is not the context of a request
+// dispatched by ServeMux, Query returns nil.
+func Query(ctx context.Context) url.Values {
+	s, _ := ctx.Value(storeKey).(*requestStore)
+
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.query == nil {
+		s.query, _ = url.ParseQuery(s.rawQuery)
+	}
+
+	return s.query
+}
+
+// setRawQuery replaces the query of the request of ctx cached by Query, for
+// middleware rewriting r.URL.RawQuery.
+func setRawQuery(ctx context.Context, rawQuery string) {
+	s, _ := ctx.Value(storeKey).(*requestStore)
+
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.rawQuery, s.query = rawQuery, nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package webmux_test
+
+// raceEnabled is true when testing with the race detector, under which
+// sync.Pool drops items at random, so allocations cannot be counted.
+const raceEnabled = true
@@ -0,0 +1,31 @@
+package webmux
+
+import "errors"
+
+// ErrNotReady is returned by ServeMux when a request is received while the mux is not ready.
+var ErrNotReady = errors.New("webmux: not ready")
+
+// SetReady sets whether mux is ready to serve requests.
+//
+// While not ready, requests for routes are rejected with a 503 Service
+// Unavailable [HTTPError] wrapping ErrNotReady, except for routes registered
+// with AlwaysServe. This allows a server to accept connections while warming
+// up or draining, while still responding to health checks and metrics scrapes.
+//
+// A new ServeMux is ready. SetReady is safe to call concurrently with serving requests.
+func (mux *ServeMux) SetReady(ready bool) {
+	mux.notReady.Store(!ready)
+}
+
+// Ready reports whether mux is ready to serve requests.
+func (mux *ServeMux) Ready() bool {
+	return !mux.notReady.Load()
+}
+
+// AlwaysServe returns a RouteOption marking the route to be served even when
+// the mux is not ready, such as health check and metrics endpoints.
+func AlwaysServe() RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.alwaysServe = true
+	}
+}
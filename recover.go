@@ -0,0 +1,164 @@
+package webmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// recovererName is the function name of recoverer.ServeHTTPErr, the boundary
+// at which panic stacks are trimmed.
+const recovererName = "go.destructure.dev/webmux.(*recoverer).ServeHTTPErr"
+
+// PanicError is the error returned by the Recover middleware when a handler panics.
+type PanicError struct {
+	Value   any               // value passed to panic
+	Method  string            // request method
+	Pattern string            // matched route pattern, empty if unknown
+	Params  map[string]string // matched route params
+	Stack   []byte            // stack of the panic, trimmed to the handler
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	route := e.Pattern
+
+	if route == "" {
+		route = "(unmatched)"
+	}
+
+	return fmt.Sprintf("webmux: panic in %s %s: %v", e.Method, route, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Recover returns a middleware recovering panics of the handler, which
+// returns a 500 Internal Server Error [HTTPError] wrapping a *PanicError.
+//
+// The PanicError holds the pattern and params of the matched route, and a
+// stack trace trimmed to the frames between the panic and the middleware, so
+// that production panic logs show the route and the relevant code only. Error
+// handlers can report it with [errors.As]:
+//
+//	var panicErr *webmux.PanicError
+//	if errors.As(err, &panicErr) {
+//		log.Printf("%s\n%s", panicErr, panicErr.Stack)
+//	}
+//
+// Panics with an *HTTPError, like those of Abort, result in that error
+// instead, without a PanicError. Panics with [http.ErrAbortHandler], which
+// abort the response, are not recovered.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return &recoverer{next: next}
+	}
+}
+
+// Abort stops the handler by panicking with an *HTTPError for code, which the
+// Recover middleware returns to the error handler, so code deep in the call
+// stack of a handler can end the request without returning the error
+// through every caller:
+//
+//	func requireAdmin(r *http.Request) {
+//		if !isAdmin(r) {
+//			webmux.Abort(http.StatusForbidden, "admin only")
+//		}
+//	}
+//
+// The HTTPError wraps an error with message, or nil if message is empty.
+// Handlers using Abort must run behind Recover, otherwise the panic reaches
+// the server. Deferred functions run as with any panic.
+func Abort(code int, message string) {
+	var err error
+
+	if message != "" {
+		err = errors.New(message)
+	}
+
+	panic(NewHTTPError(code, err))
+}
+
+// recoverer is the Handler returned by the Recover middleware.
+type recoverer struct {
+	next Handler
+}
+
+func (rc *recoverer) ServeHTTPErr(w http.ResponseWriter, r *http.Request) (err error) {
+	defer func() {
+		v := recover()
+
+		if v == nil {
+			return
+		}
+
+		if v == http.ErrAbortHandler {
+			panic(v)
+		}
+
+		if httpErr, ok := v.(*HTTPError); ok {
+			err = httpErr
+			return
+		}
+
+		panicErr := &PanicError{Value: v, Method: r.Method, Stack: panicStack()}
+
+		if match, ok := FromContext(r.Context()); ok {
+			panicErr.Pattern = match.Pattern()
+			panicErr.Params = make(map[string]string, len(match.Params()))
+
+			for _, name := range match.Params() {
+				panicErr.Params[name] = match.Param(name)
+			}
+		}
+
+		err = NewHTTPError(http.StatusInternalServerError, panicErr)
+	}()
+
+	return rc.next.ServeHTTPErr(w, r)
+}
+
+// panicStack returns the stack of the goroutine from the panicking function to
+// the recoverer, in the format of [runtime/debug.Stack]. It must be called by
+// the deferred function recovering the panic.
+func panicStack() []byte {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	var b bytes.Buffer
+
+	inPanic := true
+
+	for {
+		frame, more := frames.Next()
+
+		if frame.Function == recovererName {
+			break
+		}
+
+		// Skip the frames of the runtime raising the panic
+		if inPanic && strings.HasPrefix(frame.Function, "runtime.") {
+			if !more {
+				break
+			}
+
+			continue
+		}
+
+		inPanic = false
+
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+
+		if !more {
+			break
+		}
+	}
+
+	return b.Bytes()
+}
@@ -0,0 +1,99 @@
+package webmux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) error {
+	var m map[string]int
+	m["boom"]++
+
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	var got error
+
+	mux := webmux.New()
+	mux.Use(webmux.Recover())
+	mux.HandleFunc(http.MethodGet, "/users/:id", panickingHandler)
+	mux.HandleFunc(http.MethodGet, "/abort", func(w http.ResponseWriter, r *http.Request) error {
+		panic(http.ErrAbortHandler)
+	})
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+		webmux.StatusError(w, r, err)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var panicErr *webmux.PanicError
+
+	assert.True(t, errors.As(got, &panicErr))
+	assert.Equal(t, "/users/:id", panicErr.Pattern)
+	assert.Equal(t, map[string]string{"id": "1"}, panicErr.Params)
+	assert.Contains(t, panicErr.Error(), "webmux: panic in GET /users/:id: assignment to entry in nil map")
+
+	stack := string(panicErr.Stack)
+
+	assert.True(t, strings.HasPrefix(stack, "go.destructure.dev/webmux_test.panickingHandler\n"), stack)
+	assert.NotContains(t, stack, "runtime.")
+	assert.NotContains(t, stack, "recoverer")
+
+	assert.Panics(t, func() {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+}
+
+func TestAbort(t *testing.T) {
+	var got error
+
+	mux := webmux.New()
+	mux.Use(webmux.Recover())
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+		webmux.JSONErrorHandler(false).ErrorHTTP(w, r, err)
+	})
+
+	requireAdmin := func(r *http.Request) {
+		if r.Header.Get("X-Role") != "admin" {
+			webmux.Abort(http.StatusForbidden, "admin only")
+		}
+	}
+
+	mux.HandleFunc(http.MethodGet, "/admin", func(w http.ResponseWriter, r *http.Request) error {
+		requireAdmin(r)
+		w.Write([]byte("ok"))
+
+		return nil
+	})
+	mux.HandleFunc(http.MethodGet, "/gone", func(w http.ResponseWriter, r *http.Request) error {
+		webmux.Abort(http.StatusGone, "")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, `{"error":{"code":"forbidden","message":"admin only"}}`+"\n", w.Body.String())
+
+	var panicErr *webmux.PanicError
+	assert.False(t, errors.As(got, &panicErr))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/gone", nil))
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, `{"error":{"code":"gone","message":"Gone"}}`+"\n", w.Body.String())
+}
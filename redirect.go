@@ -0,0 +1,67 @@
+package webmux
+
+import "net/http"
+
+// RedirectPolicy selects the status codes of redirects.
+//
+// The 301 Moved Permanently and 302 Found status codes let clients change the
+// method of the redirected request to GET, which silently turns POSTs to an
+// API into GETs. 307 Temporary Redirect and 308 Permanent Redirect require
+// clients to repeat the request with the same method and body.
+type RedirectPolicy int
+
+const (
+	// RedirectPreserveMethod redirects GET and HEAD requests with 301 or 302,
+	// which every client understands, and other requests with 308 or 307 so
+	// that their method and body are preserved. This is the default.
+	RedirectPreserveMethod RedirectPolicy = iota
+
+	// RedirectStrict redirects every request with 308 or 307.
+	RedirectStrict
+
+	// RedirectSeeOther redirects GET and HEAD requests with 301 or 302, and
+	// other requests with 303 See Other, telling the client to GET the new
+	// location, as in the POST/redirect/GET flow of forms.
+	RedirectSeeOther
+)
+
+// Code returns the status code redirecting a request with method.
+func (p RedirectPolicy) Code(method string, permanent bool) int {
+	safe := method == http.MethodGet || method == http.MethodHead
+
+	switch {
+	case p == RedirectSeeOther && !safe:
+		return http.StatusSeeOther
+	case p == RedirectStrict || !safe:
+		if permanent {
+			return http.StatusPermanentRedirect
+		}
+
+		return http.StatusTemporaryRedirect
+	case permanent:
+		return http.StatusMovedPermanently
+	default:
+		return http.StatusFound
+	}
+}
+
+// Redirect replies to r with a redirect to url, using the status code
+// selected by the policy, see [http.Redirect] for the handling of url.
+func (p RedirectPolicy) Redirect(w http.ResponseWriter, r *http.Request, url string, permanent bool) {
+	http.Redirect(w, r, url, p.Code(r.Method, permanent))
+}
+
+// Redirect replies to r with a redirect to url, preserving the method of
+// requests other than GET and HEAD with 307 or 308, see RedirectPreserveMethod.
+func Redirect(w http.ResponseWriter, r *http.Request, url string, permanent bool) {
+	RedirectPreserveMethod.Redirect(w, r, url, permanent)
+}
+
+// RedirectHandler returns a handler redirecting every request to url with the
+// status code selected by policy.
+func RedirectHandler(url string, permanent bool, policy RedirectPolicy) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		policy.Redirect(w, r, url, permanent)
+		return nil
+	})
+}
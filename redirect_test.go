@@ -0,0 +1,53 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	tests := []struct {
+		policy    webmux.RedirectPolicy
+		method    string
+		permanent bool
+		code      int
+	}{
+		{webmux.RedirectPreserveMethod, http.MethodGet, true, http.StatusMovedPermanently},
+		{webmux.RedirectPreserveMethod, http.MethodHead, false, http.StatusFound},
+		{webmux.RedirectPreserveMethod, http.MethodPost, true, http.StatusPermanentRedirect},
+		{webmux.RedirectPreserveMethod, http.MethodDelete, false, http.StatusTemporaryRedirect},
+		{webmux.RedirectStrict, http.MethodGet, true, http.StatusPermanentRedirect},
+		{webmux.RedirectStrict, http.MethodGet, false, http.StatusTemporaryRedirect},
+		{webmux.RedirectSeeOther, http.MethodGet, false, http.StatusFound},
+		{webmux.RedirectSeeOther, http.MethodPost, true, http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, tt.policy.Code(tt.method, tt.permanent), tt.method)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	mux := webmux.New()
+	mux.HandleMethods(webmux.Methods(http.MethodGet, http.MethodPost), "/v1/users", webmux.RedirectHandler("/v2/users", true, webmux.RedirectPreserveMethod))
+	mux.HandleFunc(http.MethodPut, "/v1/users", func(w http.ResponseWriter, r *http.Request) error {
+		webmux.Redirect(w, r, "/v2/users", false)
+		return nil
+	})
+
+	for method, code := range map[string]int{
+		http.MethodGet:  http.StatusMovedPermanently,
+		http.MethodPost: http.StatusPermanentRedirect,
+		http.MethodPut:  http.StatusTemporaryRedirect,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/v1/users", nil))
+
+		assert.Equal(t, code, w.Code, method)
+		assert.Equal(t, "/v2/users", w.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,136 @@
+package webmux
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry collects route contributions from several packages, to be applied
+// to a ServeMux with Apply. This lets packages contribute their routes
+// without the main package knowing about each of them.
+//
+// It is safe for concurrent use.
+type Registry struct {
+	mu            sync.Mutex
+	contributions []contribution
+}
+
+// contribution is a function registering routes, added to a Registry.
+type contribution struct {
+	pkg    string // import path of the package which added the contribution
+	source string // package and location for error messages
+	fn     func(*ServeMux)
+}
+
+// DefaultRegistry is the Registry used by Register.
+var DefaultRegistry = &Registry{}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds fn to DefaultRegistry, typically from an init function:
+//
+//	func init() {
+//		webmux.Register(func(mux *webmux.ServeMux) {
+//			mux.Handle(http.MethodGet, "/users/:id", getUser)
+//		})
+//	}
+func Register(fn func(*ServeMux)) {
+	DefaultRegistry.register(fn)
+}
+
+// Register adds fn to the registry.
+func (reg *Registry) Register(fn func(*ServeMux)) {
+	reg.register(fn)
+}
+
+// register adds fn to reg, recording the package of the caller of the exported function.
+func (reg *Registry) register(fn func(*ServeMux)) {
+	if fn == nil {
+		panic("webmux: nil registration")
+	}
+
+	c := contribution{pkg: "unknown", source: "unknown", fn: fn}
+
+	if pc, file, line, ok := runtime.Caller(2); ok {
+		if f := runtime.FuncForPC(pc); f != nil {
+			c.pkg = funcPackage(f.Name())
+		}
+
+		c.source = fmt.Sprintf("%s (%s:%d)", c.pkg, file[strings.LastIndexByte(file, '/')+1:], line)
+	}
+
+	reg.mu.Lock()
+	reg.contributions = append(reg.contributions, c)
+	reg.mu.Unlock()
+}
+
+// funcPackage returns the import path of the package of the function name,
+// like "example.com/app/users" for "example.com/app/users.init.0".
+func funcPackage(name string) string {
+	slash := strings.LastIndexByte(name, '/')
+
+	if i := strings.IndexByte(name[slash+1:], '.'); i >= 0 {
+		return name[:slash+1+i]
+	}
+
+	return name
+}
+
+// sourceName returns the name of the contribution source for error messages.
+func sourceName(source string) string {
+	if source == "" {
+		return "the application"
+	}
+
+	return source
+}
+
+// Apply registers the routes contributed to reg with mux.
+//
+// Contributions are applied in a deterministic order, sorted by the import
+// path of the package that added them, then in the order they were added, so
+// the routes do not depend on package initialization order. Instead of
+// panicking, Apply returns an error listing every route registered by more
+// than one contribution, naming the packages involved.
+func (mux *ServeMux) Apply(reg *Registry) error {
+	reg.mu.Lock()
+	contributions := append([]contribution(nil), reg.contributions...)
+	reg.mu.Unlock()
+
+	sort.SliceStable(contributions, func(i, j int) bool {
+		return contributions[i].pkg < contributions[j].pkg
+	})
+
+	var errs []error
+
+	for _, c := range contributions {
+		if err := mux.applyContribution(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	mux.source = ""
+
+	return errors.Join(errs...)
+}
+
+// applyContribution calls the function of c, recovering registration panics.
+func (mux *ServeMux) applyContribution(c contribution) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+
+	mux.source = c.source
+	c.fn(mux)
+
+	return nil
+}
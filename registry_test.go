@@ -0,0 +1,44 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServeMuxApply(t *testing.T) {
+	reg := webmux.NewRegistry()
+	reg.Register(func(mux *webmux.ServeMux) {
+		mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	})
+	reg.Register(func(mux *webmux.ServeMux) {
+		mux.Handle(http.MethodGet, "/posts", newTestHandler("posts"))
+	})
+
+	mux := webmux.New()
+
+	assert.NoError(t, mux.Apply(reg))
+
+	for _, path := range []string{"/users", "/posts"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// Conflicts are reported with the contributions involved
+	reg.Register(func(mux *webmux.ServeMux) {
+		mux.Handle(http.MethodGet, "/users", newTestHandler("again"))
+	})
+
+	mux = webmux.New()
+	mux.Handle(http.MethodGet, "/posts", newTestHandler("app"))
+
+	err := mux.Apply(reg)
+
+	assert.EqualError(t, err, "webmux: multiple registrations for GET /posts by go.destructure.dev/webmux_test (registry_test.go:17), already registered by the application\n"+
+		"webmux: multiple registrations for GET /users by go.destructure.dev/webmux_test (registry_test.go:33), already registered by go.destructure.dev/webmux_test (registry_test.go:14)")
+}
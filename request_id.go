@@ -0,0 +1,181 @@
+package webmux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader is the default header carrying the request ID.
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDKey is the key of request IDs in contexts and request stores.
+type requestIDKey struct{}
+
+// RequestIDOptions configures the RequestID middleware.
+type RequestIDOptions struct {
+	// Header is the request and response header carrying the ID, unless
+	// Honor or Emit are set. If empty, RequestIDHeader is used.
+	Header string
+
+	// Honor are the request headers the ID is read from, in order of
+	// preference, like "X-Request-Id" and "X-Correlation-Id". The W3C
+	// "traceparent" header provides its trace ID. If nil, the ID is read
+	// from Header.
+	Honor []string
+
+	// Emit are the response headers the ID is set in. If nil, the ID is
+	// set in Header. An empty, non-nil Emit sets no response header.
+	Emit []string
+
+	// Valid returns true if an ID read from a request is acceptable, for
+	// instance if it has the format of Generate. IDs must be up to 128
+	// bytes of printable ASCII in any case.
+	Valid func(id string) bool
+
+	// Generate returns a new ID. If nil, 16 random bytes encoded as hex
+	// are used.
+	Generate func() string
+}
+
+// RequestID returns a middleware assigning an ID to each request, for
+// correlating the logs of a request, including those of the error handlers,
+// and the reports of clients:
+//
+//	mux.Use(webmux.RequestID(webmux.RequestIDOptions{}))
+//
+// The ID is read from the header of opts, so proxies and upstream services
+// can assign it, and generated if the header is missing, longer than 128
+// bytes, or has characters other than printable ASCII, which could forge
+// log lines. The ID is set in the header of the response, and handlers get
+// it with RequestIDFromContext.
+//
+// Services bridging different conventions honor and emit several headers.
+// Since the middleware of a route runs after those of the mux, routes can
+// use their own conventions with [WithMiddleware], and so can the routes of
+// a mounted mux with Use:
+//
+//	mux.Use(webmux.RequestID(webmux.RequestIDOptions{}))
+//	mux.Handle(http.MethodPost, "/partner/orders", createOrder, webmux.WithMiddleware(webmux.RequestID(webmux.RequestIDOptions{
+//		Honor: []string{"X-Correlation-Id", "traceparent"},
+//		Emit:  []string{"X-Correlation-Id", webmux.RequestIDHeader},
+//	})))
+//
+// The ID assigned by the innermost middleware wins.
+func RequestID(opts RequestIDOptions) Middleware {
+	if opts.Header == "" {
+		opts.Header = RequestIDHeader
+	}
+
+	if opts.Honor == nil {
+		opts.Honor = []string{opts.Header}
+	}
+
+	if opts.Emit == nil {
+		opts.Emit = []string{opts.Header}
+	}
+
+	if opts.Valid == nil {
+		opts.Valid = validRequestID
+	}
+
+	if opts.Generate == nil {
+		opts.Generate = newRequestID
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			id := honoredRequestID(r, opts)
+
+			if id == "" {
+				id = opts.Generate()
+			}
+
+			for _, header := range opts.Emit {
+				w.Header().Set(header, id)
+			}
+
+			// The store makes the ID available to the error handler, which
+			// gets the request of the mux rather than that of the middleware
+			Set(r.Context(), requestIDKey{}, id)
+
+			return next.ServeHTTPErr(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the ID assigned to the request of ctx by the
+// RequestID middleware, or an empty string if there is none. It can be used
+// by handlers and by error handlers.
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+
+	id, _ := Get[string](ctx, requestIDKey{})
+
+	return id
+}
+
+// honoredRequestID returns the first valid ID of the headers honored by
+// opts, or an empty string if there is none.
+func honoredRequestID(r *http.Request, opts RequestIDOptions) string {
+	for _, header := range opts.Honor {
+		id := r.Header.Get(header)
+
+		if strings.EqualFold(header, "traceparent") {
+			id = traceID(id)
+		}
+
+		if id != "" && validRequestID(id) && opts.Valid(id) {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// traceID returns the trace ID of the version 00 W3C traceparent header v,
+// like "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", or an
+// empty string if v is not valid.
+func traceID(v string) string {
+	parts := strings.Split(v, "-")
+
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 {
+		return ""
+	}
+
+	if _, err := hex.DecodeString(parts[1]); err != nil || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// validRequestID returns true if id is acceptable as a request ID.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns 16 random bytes encoded as hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		panic("webmux: " + err.Error())
+	}
+
+	return hex.EncodeToString(b)
+}
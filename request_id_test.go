@@ -0,0 +1,109 @@
+package webmux_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestRequestID(t *testing.T) {
+	mux := webmux.New()
+	mux.Use(webmux.RequestID(webmux.RequestIDOptions{}))
+	mux.HandleFunc(http.MethodGet, "/users", func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte(webmux.RequestIDFromContext(r.Context())))
+		return nil
+	})
+	mux.HandleFunc(http.MethodGet, "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database is down")
+	})
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{strings.Repeat("a", 129), false},
+		{"forged\nmux error: x", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		r.Header.Set(webmux.RequestIDHeader, tt.header)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		id := w.Header().Get(webmux.RequestIDHeader)
+		assert.Equal(t, id, w.Body.String())
+
+		if tt.keep {
+			assert.Equal(t, tt.header, id)
+		} else {
+			assert.Equal(t, 32, len(id))
+		}
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	r := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	r.Header.Set(webmux.RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, logs.String(), "mux error: request abc-123: database is down")
+}
+
+func TestRequestIDOptions(t *testing.T) {
+	mux := webmux.New()
+	mux.Use(webmux.RequestID(webmux.RequestIDOptions{}))
+	mux.HandleFunc(http.MethodGet, "/orders", func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte(webmux.RequestIDFromContext(r.Context())))
+		return nil
+	}, webmux.WithMiddleware(webmux.RequestID(webmux.RequestIDOptions{
+		Honor: []string{"X-Correlation-Id", "traceparent"},
+		Emit:  []string{"X-Correlation-Id", webmux.RequestIDHeader},
+		Valid: func(id string) bool { return len(id) >= 8 },
+	})))
+
+	tests := []struct {
+		headers map[string]string
+		want    string
+	}{
+		{map[string]string{"X-Correlation-Id": "corr-1234"}, "corr-1234"},
+		{map[string]string{"X-Correlation-Id": "short", "traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{map[string]string{"X-Request-Id": "ignored-by-route"}, ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		id := w.Body.String()
+
+		if tt.want != "" {
+			assert.Equal(t, tt.want, id)
+		} else {
+			assert.Equal(t, 32, len(id))
+		}
+
+		assert.Equal(t, id, w.Header().Get("X-Correlation-Id"))
+		assert.Equal(t, id, w.Header().Get(webmux.RequestIDHeader))
+	}
+}
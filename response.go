@@ -0,0 +1,111 @@
+package webmux
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// responseCapture is an [http.ResponseWriter] that passes writes through to
+// the underlying ResponseWriter while capturing the status code, headers, and
+// up to limit bytes of the body.
+type responseCapture struct {
+	http.ResponseWriter
+	limit     int
+	code      int
+	header    http.Header
+	body      bytes.Buffer
+	truncated bool
+}
+
+// newResponseCapture returns a new responseCapture wrapping w.
+func newResponseCapture(w http.ResponseWriter, limit int) *responseCapture {
+	return &responseCapture{ResponseWriter: w, limit: limit}
+}
+
+// WriteHeader records code and a snapshot of the headers before passing it on.
+func (c *responseCapture) WriteHeader(code int) {
+	if c.code != 0 {
+		return
+	}
+
+	c.code = code
+	c.header = c.ResponseWriter.Header().Clone()
+	c.ResponseWriter.WriteHeader(code)
+}
+
+// Write records p before passing it on.
+func (c *responseCapture) Write(p []byte) (int, error) {
+	if c.code == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if !c.truncated {
+		if c.body.Len()+len(p) > c.limit {
+			c.truncated = true
+			c.body.Reset()
+		} else {
+			c.body.Write(p)
+		}
+	}
+
+	return c.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// capturedResponse is a complete response recorded by a responseCapture.
+type capturedResponse struct {
+	code    int
+	header  http.Header
+	body    []byte
+	trailer http.Header
+}
+
+// response returns the captured response, or false if the body was truncated.
+func (c *responseCapture) response() (capturedResponse, bool) {
+	if c.truncated {
+		return capturedResponse{}, false
+	}
+
+	code, header := c.code, c.header
+
+	if code == 0 {
+		code, header = http.StatusOK, c.ResponseWriter.Header().Clone()
+	}
+
+	resp := capturedResponse{
+		code:    code,
+		header:  header,
+		body:    bytes.Clone(c.body.Bytes()),
+		trailer: responseTrailers(c.ResponseWriter.Header(), header),
+	}
+
+	return resp, true
+}
+
+// writeTo replays the captured response to w.
+func (c capturedResponse) writeTo(w http.ResponseWriter) error {
+	h := w.Header()
+
+	for k, v := range c.header {
+		if !strings.HasPrefix(k, http.TrailerPrefix) {
+			h[k] = v
+		}
+	}
+
+	w.WriteHeader(c.code)
+
+	if _, err := w.Write(c.body); err != nil {
+		return err
+	}
+
+	for k, v := range c.trailer {
+		h[http.TrailerPrefix+k] = v
+	}
+
+	return nil
+}
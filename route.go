@@ -0,0 +1,143 @@
+package webmux
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// A Predicate reports whether a request satisfies a routing condition.
+type Predicate func(r *http.Request) bool
+
+// A RouteOption configures a route when it is registered. Options are passed
+// as trailing arguments to Handle and the other registration methods, so new
+// route settings do not require new registration methods:
+//
+//	mux.Handle(http.MethodGet, "/admin", admin,
+//		webmux.AllowCIDR("10.0.0.0/8"),
+//		webmux.WithMiddleware(auth),
+//		webmux.Header("Cache-Control", "no-store"),
+//	)
+//
+// Options are applied in the order they are given.
+type RouteOption func(*routeConfig)
+
+// routeConfig is the configuration of a single route registration.
+type routeConfig struct {
+	predicates  []Predicate
+	aliases     map[string]string // locale to alias pattern
+	middleware  []Middleware      // applied to the handler, outermost first
+	alwaysServe bool
+	networks    []netip.Prefix    // allowed client networks, any if empty
+	meta        map[string]string // metadata, see WithMeta
+	ttl         time.Duration     // lifetime of the handler, see WithTTL
+	expires     time.Time         // expiry of the handler, zero if it has no ttl
+	now         time.Time         // time of registration, only set if handlers may expire
+	host        string            // host of the route, see Host
+	source      string            // contribution registering the route, see Apply
+	priority    int               // priority of the route, see Priority
+	envs        []string          // environments of the route, any if nil, see Env
+	timeout     time.Duration     // timeout of the handler, see WithTimeout, or inheritTimeout
+}
+
+// newRouteConfig applies opts to a new routeConfig.
+func newRouteConfig(opts []RouteOption) *routeConfig {
+	cfg := &routeConfig{timeout: inheritTimeout}
+
+	for _, opt := range opts {
+		if opt == nil {
+			panic("webmux: nil route option")
+		}
+
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// wrap applies the route middleware to h. The network restriction is
+// outermost, so that no middleware runs for clients which are not allowed.
+func (cfg *routeConfig) wrap(h Handler) Handler {
+	for i := len(cfg.middleware) - 1; i >= 0; i-- {
+		h = cfg.middleware[i](h)
+	}
+
+	if len(cfg.networks) > 0 {
+		h = allowNetworks(cfg.networks)(h)
+	}
+
+	return h
+}
+
+// middlewareNames returns the names of the route middleware, in the order
+// wrap applies them, outermost first.
+func (cfg *routeConfig) middlewareNames() []string {
+	var names []string
+
+	if len(cfg.networks) > 0 {
+		names = append(names, MiddlewareName(allowNetworks(cfg.networks)))
+	}
+
+	for _, mw := range cfg.middleware {
+		names = append(names, MiddlewareName(mw))
+	}
+
+	return names
+}
+
+// Options combines opts into a single RouteOption, so that a set of options
+// shared by several routes can be declared once:
+//
+//	internal := webmux.Options(webmux.AllowCIDR("10.0.0.0/8"), webmux.WithMiddleware(auth))
+//
+//	mux.Handle(http.MethodGet, "/metrics", metrics, internal)
+//	mux.Handle(http.MethodGet, "/debug/vars", vars, internal)
+func Options(opts ...RouteOption) RouteOption {
+	for _, opt := range opts {
+		if opt == nil {
+			panic("webmux: nil route option")
+		}
+	}
+
+	return func(cfg *routeConfig) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// When returns a RouteOption restricting the handler to requests that satisfy
+// all of the predicates.
+//
+// Conditional handlers may be registered alongside an unconditional handler
+// for the same method and pattern. Conditional handlers are tried in the order
+// they were registered, and the unconditional handler is used when none match.
+// If there is no unconditional handler the request is not found, and the
+// error handler receives a *NotFoundError.
+func When(predicates ...Predicate) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.predicates = append(cfg.predicates, predicates...)
+	}
+}
+
+// conditionalHandler is a handler registered with predicates.
+type conditionalHandler struct {
+	predicates []Predicate
+	handler    Handler
+	chained    Handler // handler wrapped by the middleware of the mux, see muxEntry.precompose
+	meta       map[string]string
+	middleware []string      // names of the route middleware, see RouteInfo
+	timeout    time.Duration // see WithTimeout, or inheritTimeout
+	expires    time.Time     // zero if the handler does not expire
+}
+
+// match returns true if r satisfies all of the predicates of c.
+func (c conditionalHandler) match(r *http.Request) bool {
+	for _, p := range c.predicates {
+		if !p(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,216 @@
+package webmux
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// RouteInfo describes a route registered with a ServeMux.
+type RouteInfo struct {
+	Host     string             // host as given to Host, empty for routes without a host
+	Pattern  string             // pattern as registered, like "/users/:id"
+	Methods  MethodSet          // methods with a registered handler, in sorted order
+	Params   []string           // param names in the order they appear in Pattern
+	Handlers map[string]Handler // method to handler, excluding handlers registered with predicates
+	Priority int                // priority set with Priority, zero by default
+
+	// Meta maps methods to the metadata attached with WithMeta to their
+	// handler, excluding handlers registered with predicates.
+	Meta map[string]map[string]string
+
+	// Middleware maps methods to the names of the middleware wrapping their
+	// handler, outermost first, see MiddlewareName. The middleware added
+	// with Use come first, followed by those of the route, like those of
+	// WithMiddleware. Handlers registered with predicates are excluded.
+	Middleware map[string][]string
+}
+
+// Routes returns the routes registered with mux, in the order documented by
+// Walk. Implicit HEAD and OPTIONS methods are not included. This is useful
+// for printing a route table at startup, generating documentation, and
+// asserting in tests that the expected routes exist:
+//
+//	for _, route := range mux.Routes() {
+//		for _, method := range route.Methods {
+//			fmt.Printf("%-7s %-30s %s\n", method, route.Pattern, webmux.HandlerName(route.Handlers[method]))
+//		}
+//	}
+//
+// The middleware chains show what wraps each handler, for auditing that
+// sensitive routes are behind authentication:
+//
+//	GET     /admin/users    go.destructure.dev/webmux.RequestID > main.auth
+func (mux *ServeMux) Routes() []RouteInfo {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	chain := make([]string, len(mux.middleware))
+
+	for i, mw := range mux.middleware {
+		chain[i] = MiddlewareName(mw)
+	}
+
+	routes := mux.root.routes("", chain, nil)
+
+	for _, h := range mux.hosts {
+		routes = h.root.routes(h.host, chain, routes)
+	}
+
+	return routes
+}
+
+// routes appends the routes of the tree n for host to routes. The chain is
+// the names of the middleware of the mux.
+func (n *node) routes(host string, chain []string, routes []RouteInfo) []RouteInfo {
+	n.walkEntries(func(e *muxEntry) error {
+		methods := e.registeredMethods()
+
+		if len(methods) == 0 {
+			return nil
+		}
+
+		handlers := make(map[string]Handler, len(e.handlers))
+
+		for method, h := range e.handlers {
+			handlers[method] = h
+		}
+
+		var meta map[string]map[string]string
+
+		if len(e.meta) > 0 {
+			meta = make(map[string]map[string]string, len(e.meta))
+
+			for method, m := range e.meta {
+				meta[method] = maps.Clone(m)
+			}
+		}
+
+		var middleware map[string][]string
+
+		if len(chain) > 0 || len(e.middleware) > 0 {
+			middleware = make(map[string][]string, len(e.handlers))
+
+			for method := range e.handlers {
+				if names := append(slices.Clone(chain), e.middleware[method]...); len(names) > 0 {
+					middleware[method] = names
+				}
+			}
+		}
+
+		routes = append(routes, RouteInfo{
+			Host:       host,
+			Pattern:    e.pattern,
+			Methods:    MethodSet(methods),
+			Params:     slices.Clone(e.params),
+			Handlers:   handlers,
+			Meta:       meta,
+			Middleware: middleware,
+			Priority:   e.priority,
+		})
+
+		return nil
+	})
+
+	return routes
+}
+
+// HandlerName returns a name identifying h for display, such as the name of
+// the function of a HandlerFunc like "main.getUser", or the type of other
+// handlers like "*webmux.SSEHub". Handlers wrapped by route middleware are
+// named after the middleware's function.
+func HandlerName(h Handler) string {
+	if h == nil {
+		return ""
+	}
+
+	if f, ok := h.(HandlerFunc); ok {
+		if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+
+	return fmt.Sprintf("%T", h)
+}
+
+// MiddlewareName returns a name identifying mw for display, the name given to
+// Named, or else the name of its function, like "main.auth". The closures
+// returned by middleware constructors are named after the constructor, like
+// "go.destructure.dev/webmux.Recover" rather than
+// "go.destructure.dev/webmux.Recover.func1".
+func MiddlewareName(mw Middleware) string {
+	if mw == nil {
+		return ""
+	}
+
+	pc := reflect.ValueOf(mw).Pointer()
+
+	if pc == namedFunc {
+		probe := &nameProbe{}
+		mw(probe)
+
+		return probe.name
+	}
+
+	fn := runtime.FuncForPC(pc)
+
+	if fn == nil {
+		return fmt.Sprintf("%T", mw)
+	}
+
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+
+	for {
+		i := strings.LastIndexByte(name, '.')
+
+		if i < 0 || !closureSuffix(name[i+1:]) {
+			return name
+		}
+
+		name = name[:i]
+	}
+}
+
+// closureSuffix returns true if s is a segment the runtime appends to the
+// name of a function for its closures, like "func1" or "2".
+func closureSuffix(s string) bool {
+	s = strings.TrimPrefix(s, "func")
+
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ObserveRoute returns a shallow copy of r whose route is reported to fn by
+// ServeMux, for instrumentation wrapping the mux, like metrics, which sees
+// every request, including those failing with 404 Not Found or 405 Method
+// Not Allowed, but not the match of the request:
+//
+//	func instrument(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			pattern := ""
+//			next.ServeHTTP(w, webmux.ObserveRoute(r, func(p string) { pattern = p }))
+//			// record the request with pattern
+//		})
+//	}
+//
+// The mux calls fn with the pattern of the matched route before calling the
+// handler, including for methods the route does not handle, and not at all
+// if no route matches. If the route is a Mount, fn is called again with the
+// composed pattern of the route of the mounted mux, if it has one.
+func ObserveRoute(r *http.Request, fn func(pattern string)) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), observerKey, fn))
+}
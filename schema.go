@@ -0,0 +1,569 @@
+package webmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidBody is wrapped by the errors of request bodies rejected by
+// ValidateBody.
+var ErrInvalidBody = errors.New("invalid request body")
+
+// Schema is a JSON Schema validating JSON documents. It supports the subset
+// of JSON Schema shared with the schema objects of OpenAPI 3: types, nullable,
+// enums, required and additional properties, items, allOf, anyOf and oneOf,
+// and the length, size and range keywords. Local references like
+// "#/$defs/User" or "#/components/schemas/User" are resolved in the
+// document the schema was parsed from.
+//
+// A Schema marshals to its JSON document, so the schema validating requests
+// can also be embedded in an API description.
+type Schema struct {
+	root  map[string]any // document resolving the references of value
+	value any
+}
+
+// ParseSchema parses the JSON Schema document data.
+func ParseSchema(data []byte) (*Schema, error) {
+	var root map[string]any
+
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("webmux: invalid schema: %w", err)
+	}
+
+	if root == nil {
+		return nil, errors.New("webmux: invalid schema: not an object")
+	}
+
+	return &Schema{root: root, value: root}, nil
+}
+
+// Lookup returns the schema at the local reference ref within the document
+// of s, like "#/components/schemas/User", for validating against a part of
+// a larger document such as an OpenAPI description.
+func (s *Schema) Lookup(ref string) (*Schema, bool) {
+	v, ok := resolvePointer(s.root, ref)
+
+	if !ok {
+		return nil, false
+	}
+
+	return &Schema{root: s.root, value: v}, true
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.value)
+}
+
+// Validate returns nil if data is a JSON document valid for s. Otherwise a
+// *FieldError is returned for each violation, joined with [errors.Join].
+// The fields are paths in the document like "$.items[0].id", where "$" is
+// the whole document.
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+
+	if err := dec.Decode(&value); err != nil {
+		return &FieldError{Field: "$", Message: "invalid JSON: " + err.Error()}
+	}
+
+	if dec.More() {
+		return &FieldError{Field: "$", Message: "invalid JSON: trailing data"}
+	}
+
+	v := &schemaValidator{root: s.root}
+	v.validate(s.value, value, "$", 0)
+
+	return errors.Join(v.errs...)
+}
+
+// ValidateBodyOptions configures ValidateBody.
+type ValidateBodyOptions struct {
+	// MaxBytes limits the size of the body. If zero, DefaultMaxJSONBytes is
+	// used.
+	MaxBytes int64
+}
+
+// ValidateBody returns a middleware validating JSON request bodies against
+// s before calling the handler, typically applied to a single route. A nil
+// opts uses the defaults:
+//
+//	mux.Handle(http.MethodPost, "/users", createUser, webmux.WithMiddleware(webmux.ValidateBody(userSchema, nil)))
+//
+// Invalid bodies are rejected with a 400 Bad Request [HTTPError] wrapping
+// ErrInvalidBody and a *FieldError for each violation, which JSONErrorHandler
+// lists in the fields of the error. Bodies which are not JSON are rejected
+// with a 415 Unsupported Media Type, and bodies larger than the limit with a
+// 413 Request Entity Too Large wrapping ErrBodyTooLarge. The body is
+// buffered, so the handler can read it again.
+func ValidateBody(s *Schema, opts *ValidateBodyOptions) Middleware {
+	if s == nil {
+		panic("webmux: nil schema")
+	}
+
+	var o ValidateBodyOptions
+
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultMaxJSONBytes
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			var body []byte
+
+			if r.Body != nil {
+				var err error
+
+				body, err = io.ReadAll(io.LimitReader(r.Body, o.MaxBytes+1))
+
+				if err != nil {
+					return NewHTTPError(http.StatusBadRequest, err)
+				}
+
+				if int64(len(body)) > o.MaxBytes {
+					return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, o.MaxBytes))
+				}
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			if len(body) == 0 {
+				return NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidBody, &FieldError{Field: "$", Message: "missing body"}))
+			}
+
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+			if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+				return NewHTTPError(http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", mediaType))
+			}
+
+			if err := s.Validate(body); err != nil {
+				return NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidBody, err))
+			}
+
+			return next.ServeHTTPErr(w, r)
+		})
+	}
+}
+
+// maxSchemaDepth limits the nesting of schemas, which may be recursive.
+const maxSchemaDepth = 64
+
+// schemaValidator collects the violations of a document.
+type schemaValidator struct {
+	root map[string]any
+	errs []error
+}
+
+// fail records a violation at path.
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, &FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check returns the violations of value for the schema s, without recording them.
+func (v *schemaValidator) check(s any, value any, path string, depth int) []error {
+	sub := &schemaValidator{root: v.root}
+	sub.validate(s, value, path, depth)
+
+	return sub.errs
+}
+
+// validate records the violations of value, at path, for the schema s.
+func (v *schemaValidator) validate(s any, value any, path string, depth int) {
+	schema := resolveSchema(v.root, s)
+
+	if schema == nil {
+		return
+	}
+
+	if depth > maxSchemaDepth {
+		v.fail(path, "schema too deep")
+		return
+	}
+
+	if nullable, _ := schema["nullable"].(bool); nullable && value == nil {
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !inEnum(enum, value) {
+		v.fail(path, "%s is not one of the allowed values", describeJSON(value))
+		return
+	}
+
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path, depth+1)
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		var first []error
+
+		for i, sub := range anyOf {
+			errs := v.check(sub, value, path, depth+1)
+
+			if len(errs) == 0 {
+				first = nil
+				break
+			}
+
+			if i == 0 {
+				first = errs
+			}
+		}
+
+		v.errs = append(v.errs, first...)
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		n := 0
+
+		for _, sub := range oneOf {
+			if len(v.check(sub, value, path, depth+1)) == 0 {
+				n++
+			}
+		}
+
+		if n != 1 {
+			v.fail(path, "%s matches %d schemas of oneOf, expected 1", describeJSON(value), n)
+		}
+	}
+
+	if typ, _ := schema["type"].(string); typ != "" && !hasJSONType(value, typ) {
+		v.fail(path, "expected %s, got %s", typ, describeJSON(value))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		n := float64(len([]rune(value)))
+
+		if limit, ok := schema["minLength"].(float64); ok && n < limit {
+			v.fail(path, "string shorter than %v", limit)
+		}
+
+		if limit, ok := schema["maxLength"].(float64); ok && n > limit {
+			v.fail(path, "string longer than %v", limit)
+		}
+	case json.Number:
+		n, _ := value.Float64()
+
+		if limit, ok := schema["minimum"].(float64); ok && n < limit {
+			v.fail(path, "%s is less than %v", value, limit)
+		}
+
+		if limit, ok := schema["maximum"].(float64); ok && n > limit {
+			v.fail(path, "%s is greater than %v", value, limit)
+		}
+	case []any:
+		n := float64(len(value))
+
+		if limit, ok := schema["minItems"].(float64); ok && n < limit {
+			v.fail(path, "fewer than %v items", limit)
+		}
+
+		if limit, ok := schema["maxItems"].(float64); ok && n > limit {
+			v.fail(path, "more than %v items", limit)
+		}
+
+		for i, item := range value {
+			v.validate(schema["items"], item, path+"["+strconv.Itoa(i)+"]", depth+1)
+		}
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := value[name]; !ok {
+					v.fail(path, "missing property %q", name)
+				}
+			}
+		}
+
+		props, _ := schema["properties"].(map[string]any)
+
+		for _, name := range sortedKeys(value) {
+			prop, ok := props[name]
+
+			if !ok {
+				switch additional := schema["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						v.fail(path, "unexpected property %q", name)
+					}
+
+					continue
+				case map[string]any:
+					prop = additional
+				default:
+					continue
+				}
+			}
+
+			v.validate(prop, value[name], path+"."+name, depth+1)
+		}
+	}
+}
+
+// resolveSchema returns the schema s as an object, following local
+// references in root.
+func resolveSchema(root map[string]any, s any) map[string]any {
+	obj, _ := s.(map[string]any)
+
+	for i := 0; i < maxSchemaDepth; i++ {
+		ref, ok := obj["$ref"].(string)
+
+		if !ok {
+			return obj
+		}
+
+		target, _ := resolvePointer(root, ref)
+		obj, _ = target.(map[string]any)
+	}
+
+	return obj
+}
+
+// resolvePointer returns the value of the local reference ref in root, like
+// "#/components/schemas/User".
+func resolvePointer(root map[string]any, ref string) (any, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+
+	if !ok {
+		return nil, false
+	}
+
+	var target any = root
+
+	if pointer == "" {
+		return target, true
+	}
+
+	for _, key := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+		obj, _ := target.(map[string]any)
+
+		if target, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return target, true
+}
+
+// hasJSONType returns true if the decoded JSON value has the schema type typ.
+func hasJSONType(value any, typ string) bool {
+	switch value := value.(type) {
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+
+		f, err := value.Float64()
+
+		return typ == "integer" && err == nil && f == math.Trunc(f)
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	}
+
+	return typ == "null"
+}
+
+// describeJSON describes a decoded JSON value in violations.
+func describeJSON(value any) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+
+	return "null"
+}
+
+// inEnum returns true if the decoded JSON value is one of the values of enum.
+func inEnum(enum []any, value any) bool {
+	for _, e := range enum {
+		if n, ok := value.(json.Number); ok {
+			f, _ := n.Float64()
+
+			if ef, ok := e.(float64); ok && ef == f {
+				return true
+			}
+
+			continue
+		}
+
+		if reflect.DeepEqual(e, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SchemaOf returns a Schema for the JSON encoding of values of the type of v,
+// a struct or a pointer to one, so request types declared in Go can be
+// validated before they are decoded:
+//
+//	type createUser struct {
+//		Name  string   `json:"name"`
+//		Email string   `json:"email"`
+//		Tags  []string `json:"tags,omitempty"`
+//	}
+//
+//	mux.Handle(http.MethodPost, "/users", h, webmux.WithMiddleware(webmux.ValidateBody(webmux.SchemaOf(createUser{}), nil)))
+//
+// Fields follow the rules of [encoding/json]. They are required unless they
+// are tagged omitempty or are pointers, which are also nullable. Unknown
+// properties are accepted, as they are by [json.Unmarshal].
+func SchemaOf(v any) *Schema {
+	t := reflect.TypeOf(v)
+
+	if t == nil {
+		panic("webmux: SchemaOf nil")
+	}
+
+	value := typeSchema(t, make(map[reflect.Type]bool))
+	root, _ := value.(map[string]any)
+
+	return &Schema{root: root, value: value}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// typeSchema returns the schema of the JSON encoding of t. Types being
+// visited are accepted as any value, since schemas of recursive types would
+// need references.
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	if visiting[t] || t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema, _ := typeSchema(t.Elem(), visiting).(map[string]any)
+
+		if len(schema) > 0 {
+			schema["nullable"] = true
+		}
+
+		return schema
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		props := make(map[string]any)
+		var required []any
+
+		structFields(t, visiting, props, &required)
+
+		schema := map[string]any{"type": "object", "properties": props}
+
+		if len(required) > 0 {
+			sort.Slice(required, func(i, j int) bool { return required[i].(string) < required[j].(string) })
+			schema["required"] = required
+		}
+
+		return schema
+	}
+
+	return map[string]any{}
+}
+
+// structFields adds the properties of the fields of the struct type t to
+// props, including those of embedded structs, and the required ones to
+// required.
+func structFields(t reflect.Type, visiting map[reflect.Type]bool, props map[string]any, required *[]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				structFields(ft, visiting, props, required)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = typeSchema(f.Type, visiting)
+
+		if !strings.Contains(","+opts+",", ",omitempty,") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package webmux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestValidateBody(t *testing.T) {
+	schema, err := webmux.ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}}
+		},
+		"$defs": {"tag": {"enum": ["admin", "staff"]}}
+	}`))
+	assert.NoError(t, err)
+
+	mux := webmux.New()
+	mux.HandleError(webmux.JSONErrorHandler(false))
+	mux.Handle(http.MethodPost, "/users", newTestHandler("created"), webmux.WithMiddleware(webmux.ValidateBody(schema, &webmux.ValidateBodyOptions{MaxBytes: 64})))
+
+	var tests = []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{"valid", "application/json", `{"name": "Ada", "age": 36, "tags": ["admin"]}`, http.StatusOK, "created"},
+		{"invalid", "application/json", `{"age": -1, "tags": ["root"]}`, http.StatusBadRequest,
+			`{"error":{"code":"bad_request","message":"invalid request body: $: missing property \"name\"\n$.age: -1 is less than 0\n$.tags[0]: \"root\" is not one of the allowed values",` +
+				`"fields":[{"field":"$","message":"missing property \"name\""},{"field":"$.age","message":"-1 is less than 0"},{"field":"$.tags[0]","message":"\"root\" is not one of the allowed values"}]}}` + "\n"},
+		{"missing", "application/json", ``, http.StatusBadRequest,
+			`{"error":{"code":"bad_request","message":"invalid request body: $: missing body","fields":[{"field":"$","message":"missing body"}]}}` + "\n"},
+		{"not json", "text/plain", `name=Ada`, http.StatusUnsupportedMediaType,
+			`{"error":{"code":"unsupported_media_type","message":"unsupported content type \"text/plain\""}}` + "\n"},
+		{"too large", "application/json", `{"name": "` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge,
+			`{"error":{"code":"request_entity_too_large","message":"request body too large: limit is 64 bytes"}}` + "\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			assert.Equal(t, tc.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+
+	type user struct {
+		Name    string            `json:"name"`
+		Email   *string           `json:"email"`
+		Tags    []string          `json:"tags,omitempty"`
+		Address address           `json:"address"`
+		Labels  map[string]int    `json:"labels,omitempty"`
+		Secret  string            `json:"-"`
+		Extra   map[string]string `json:",omitempty"`
+	}
+
+	schema := webmux.SchemaOf(user{})
+
+	assert.NoError(t, schema.Validate([]byte(`{"name": "Ada", "email": null, "address": {"city": "London"}, "labels": {"a": 1}}`)))
+
+	err := schema.Validate([]byte(`{"name": 1, "address": {}, "labels": {"a": "b"}, "Extra": {"k": "v"}}`))
+
+	var fieldErr *webmux.FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.EqualError(t, err, "$.address: missing property \"city\"\n$.labels.a: expected integer, got \"b\"\n$.name: expected string, got 1")
+
+	data, err := schema.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"required":["address","name"]`)
+}
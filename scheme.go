@@ -0,0 +1,48 @@
+package webmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RequestScheme returns the scheme of r, "https" or "http".
+//
+// If r.URL.Scheme is set it is used, otherwise the scheme is "https" if the
+// request was received over TLS. Behind a proxy terminating TLS, set
+// r.URL.Scheme from a trusted header like X-Forwarded-Proto before the request
+// reaches the mux.
+func RequestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Scheme returns a Predicate satisfied by requests with the given scheme, see RequestScheme.
+func Scheme(scheme string) Predicate {
+	scheme = strings.ToLower(scheme)
+
+	return func(r *http.Request) bool {
+		return RequestScheme(r) == scheme
+	}
+}
+
+// SchemeHTTPS returns a RouteOption restricting the route to requests over
+// HTTPS. Requests over HTTP are handled as if the route was not registered
+// for the method, unless another handler is registered without the option.
+func SchemeHTTPS() RouteOption {
+	return When(Scheme("https"))
+}
+
+// SchemeHTTP returns a RouteOption restricting the route to requests over
+// cleartext HTTP, such as ACME HTTP-01 challenges:
+//
+//	mux.Handle(http.MethodGet, "/.well-known/acme-challenge/:token", acme, webmux.SchemeHTTP())
+func SchemeHTTP() RouteOption {
+	return When(Scheme("http"))
+}
@@ -0,0 +1,341 @@
+package webmux
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Errors returned by Server when a request exceeds its limits.
+var (
+	ErrURITooLong     = errors.New("webmux: request URI too long")
+	ErrHeaderTooLarge = errors.New("webmux: request header too large")
+)
+
+// Defaults for Server limits.
+const (
+	defaultMaxURILength   = 8 << 10
+	defaultMaxHeaderBytes = http.DefaultMaxHeaderBytes
+)
+
+// Server is an HTTP server for a handler, typically a ServeMux, which wraps
+// [http.Server] with webmux conventions.
+//
+// Requests exceeding the URI and header limits are rejected with the error
+// handler, as a 414 URI Too Long or 431 Request Header Fields Too Large
+// [HTTPError], instead of the plain text responses of [http.Server].
+//
+// A Server can also serve several handlers on separate listeners with Run,
+// such as a public mux, an admin mux, and a metrics endpoint, which share
+// the server's limits, middleware, and lifecycle.
+type Server struct {
+	Addr      string       // TCP address to listen on, ":http" if empty
+	Handler   http.Handler // handler to invoke
+	TLSConfig *tls.Config  // optional TLS config, used by ServeTLS and ListenAndServeTLS
+
+	// Bindings are additional listeners served by Run.
+	Bindings []Binding
+
+	// Middleware is applied to the handler of every listener, before routing.
+	Middleware []Middleware
+
+	// MaxURILength is the maximum length of the request URI.
+	// If zero, a default of 8KiB is used.
+	MaxURILength int
+
+	// MaxHeaderBytes is the maximum size of the request line and headers.
+	// If zero, [http.DefaultMaxHeaderBytes] is used. Requests far exceeding the
+	// limit are rejected by [http.Server] before they reach the error handler.
+	MaxHeaderBytes int
+
+	// ErrorHandler handles requests exceeding the limits, and errors returned
+	// by Middleware for handlers which are not a ServeMux.
+	// If nil, StatusErrorHandler is used.
+	ErrorHandler ErrorHandler
+
+	once    sync.Once
+	srv     *http.Server
+	mu      sync.Mutex
+	servers []*http.Server // all running servers, for Shutdown and Close
+}
+
+// Binding is a listener served by a Server with its own handler.
+type Binding struct {
+	Addr      string       // TCP address to listen on, unless Listener is set
+	Listener  net.Listener // optional listener to serve instead of listening on Addr
+	Handler   http.Handler // handler to invoke
+	TLSConfig *tls.Config  // if set, connections are served over TLS
+}
+
+// server returns the [http.Server] for s.Addr and s.Handler, creating it on first use.
+func (s *Server) server() *http.Server {
+	s.once.Do(func() {
+		s.srv = s.newServer(s.Addr, s.Handler, s.TLSConfig)
+	})
+
+	return s.srv
+}
+
+// newServer returns a new [http.Server] serving handler, and tracks it for Shutdown.
+func (s *Server) newServer(addr string, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	maxHeaderBytes := s.MaxHeaderBytes
+
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = defaultMaxHeaderBytes
+	}
+
+	// http.Server reads some more, so requests slightly exceeding the limit
+	// reach limitHandler
+	srv := &http.Server{
+		Addr:           addr,
+		Handler:        s.limitHandler(handler),
+		TLSConfig:      tlsConfig,
+		MaxHeaderBytes: maxHeaderBytes,
+	}
+
+	s.mu.Lock()
+	s.servers = append(s.servers, srv)
+	s.mu.Unlock()
+
+	return srv
+}
+
+// errorHandler returns the error handler for requests to handler. It is
+// called for every request, since the error handler of a ServeMux may change.
+func (s *Server) errorHandler(handler http.Handler) ErrorHandler {
+	if mux, ok := handler.(*ServeMux); ok {
+		return mux.errHandler
+	}
+
+	if s.ErrorHandler == nil {
+		return StatusErrorHandler()
+	}
+
+	return s.ErrorHandler
+}
+
+// limitHandler returns a handler enforcing the limits and middleware before calling handler.
+func (s *Server) limitHandler(handler http.Handler) http.Handler {
+	maxURILength := s.MaxURILength
+
+	if maxURILength <= 0 {
+		maxURILength = defaultMaxURILength
+	}
+
+	maxHeaderBytes := s.MaxHeaderBytes
+
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = defaultMaxHeaderBytes
+	}
+
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+
+	var h Handler
+
+	switch handler := handler.(type) {
+	case *ServeMux:
+		// The mux handles its errors, with the MuxMatch in the context
+		h = HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			handler.ServeHTTP(w, r)
+			return nil
+		})
+	case Handler:
+		h = handler
+	default:
+		h = FallibleFunc(handler)
+	}
+
+	for i := len(s.Middleware) - 1; i >= 0; i-- {
+		h = s.Middleware[i](h)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.RequestURI) > maxURILength {
+			s.errorHandler(handler).ErrorHTTP(w, r, NewHTTPError(http.StatusRequestURITooLong, ErrURITooLong))
+			return
+		}
+
+		if headerSize(r) > maxHeaderBytes {
+			s.errorHandler(handler).ErrorHTTP(w, r, NewHTTPError(http.StatusRequestHeaderFieldsTooLarge, ErrHeaderTooLarge))
+			return
+		}
+
+		if err := h.ServeHTTPErr(w, r); err != nil {
+			s.errorHandler(handler).ErrorHTTP(w, r, err)
+		}
+	})
+}
+
+// headerSize returns the approximate size of the request line and headers of r on the wire.
+func headerSize(r *http.Request) int {
+	n := len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4
+
+	if r.Host != "" {
+		n += len("Host: \r\n") + len(r.Host)
+	}
+
+	for name, values := range r.Header {
+		for _, v := range values {
+			n += len(name) + len(v) + 4
+		}
+	}
+
+	return n
+}
+
+// Serve accepts incoming connections on l, see [http.Server.Serve].
+func (s *Server) Serve(l net.Listener) error {
+	return s.server().Serve(l)
+}
+
+// ServeTLS accepts incoming TLS connections on l, see [http.Server.ServeTLS].
+func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	return s.server().ServeTLS(l, certFile, keyFile)
+}
+
+// ListenAndServe listens on s.Addr and serves requests, see [http.Server.ListenAndServe].
+func (s *Server) ListenAndServe() error {
+	return s.server().ListenAndServe()
+}
+
+// ListenAndServeTLS listens on s.Addr and serves TLS requests, see [http.Server.ListenAndServeTLS].
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	return s.server().ListenAndServeTLS(certFile, keyFile)
+}
+
+// Run serves all bindings, and s.Handler on s.Addr if s.Handler is set,
+// until one of them fails or the server is shut down:
+//
+//	srv := &webmux.Server{
+//		Bindings: []webmux.Binding{
+//			{Addr: ":8443", Handler: public, TLSConfig: tlsConfig},
+//			{Addr: "127.0.0.1:9000", Handler: admin},
+//		},
+//	}
+//
+//	err := srv.Run()
+//
+// If any listener fails the others are closed, and the error is returned.
+// After Shutdown or Close, Run returns [http.ErrServerClosed] once every
+// listener is closed; as with [http.Server.Shutdown], in-flight requests may
+// still be draining until Shutdown returns.
+func (s *Server) Run() error {
+	bindings := append([]Binding(nil), s.Bindings...)
+
+	if s.Handler != nil {
+		bindings = append(bindings, Binding{Addr: s.Addr, Handler: s.Handler, TLSConfig: s.TLSConfig})
+	}
+
+	if len(bindings) == 0 {
+		return errors.New("webmux: no handlers to serve")
+	}
+
+	listeners := make([]net.Listener, 0, len(bindings))
+
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	for _, b := range bindings {
+		l := b.Listener
+
+		if l == nil {
+			addr := b.Addr
+
+			if addr == "" {
+				addr = ":http"
+
+				if b.TLSConfig != nil {
+					addr = ":https"
+				}
+			}
+
+			var err error
+
+			l, err = net.Listen("tcp", addr)
+
+			if err != nil {
+				closeAll()
+				return err
+			}
+		}
+
+		if b.TLSConfig != nil {
+			l = tls.NewListener(l, b.TLSConfig)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	errs := make(chan error, len(bindings))
+	servers := make([]*http.Server, 0, len(bindings))
+
+	for i, b := range bindings {
+		srv := s.newServer(b.Addr, b.Handler, b.TLSConfig)
+		servers = append(servers, srv)
+
+		go func(l net.Listener) {
+			errs <- srv.Serve(l)
+		}(listeners[i])
+	}
+
+	err := <-errs
+
+	// After Shutdown the other servers may still be draining requests
+	if !errors.Is(err, http.ErrServerClosed) {
+		for _, srv := range servers {
+			srv.Close()
+		}
+	}
+
+	for i := 1; i < len(servers); i++ {
+		<-errs
+	}
+
+	return err
+}
+
+// Shutdown gracefully shuts down all listeners, see [http.Server.Shutdown].
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.server()
+
+	var errs []error
+
+	for _, srv := range s.runningServers() {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close immediately closes all listeners, see [http.Server.Close].
+func (s *Server) Close() error {
+	s.server()
+
+	var errs []error
+
+	for _, srv := range s.runningServers() {
+		if err := srv.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// runningServers returns the servers created for s.
+func (s *Server) runningServers() []*http.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*http.Server(nil), s.servers...)
+}
@@ -0,0 +1,198 @@
+package webmux_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServerLimits(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/*", newTestHandler("ok"))
+
+	srv := &webmux.Server{
+		Handler:        mux,
+		MaxURILength:   64,
+		MaxHeaderBytes: 1024,
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	base := "http://" + l.Addr().String()
+
+	get := func(path, header string) int {
+		r, err := http.NewRequest(http.MethodGet, base+path, nil)
+		assert.NoError(t, err)
+
+		if header != "" {
+			r.Header.Set("X-Large", header)
+		}
+
+		resp, err := http.DefaultClient.Do(r)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, get("/short", ""))
+	assert.Equal(t, http.StatusRequestURITooLong, get("/"+strings.Repeat("a", 100), ""))
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, get("/short", strings.Repeat("a", 1500)))
+}
+
+func TestServerErrorHandler(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/users/:id", newTestHandler("user"))
+
+	srv := &webmux.Server{Handler: mux}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	// The error handler is set after the server started, and sees the match
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		if match, ok := webmux.FromContext(r.Context()); ok {
+			w.Header().Set("X-Pattern", match.Pattern())
+		}
+
+		webmux.StatusError(w, r, err)
+	})
+
+	resp, err := http.Post("http://"+l.Addr().String()+"/users/1", "text/plain", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "OPTIONS, GET, HEAD", resp.Header.Get("Allow"))
+	assert.Equal(t, "/users/:id", resp.Header.Get("X-Pattern"))
+}
+
+func TestServerRun(t *testing.T) {
+	public := webmux.New()
+	public.Handle(http.MethodGet, "/", newTestHandler("public"))
+
+	admin := webmux.New()
+	admin.Handle(http.MethodGet, "/", newTestHandler("admin"))
+
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	srv := &webmux.Server{
+		Bindings: []webmux.Binding{
+			{Listener: l1, Handler: public},
+			{Listener: l2, Handler: admin},
+		},
+		Middleware: []webmux.Middleware{func(next webmux.Handler) webmux.Handler {
+			return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("X-Server", "webmux")
+				return next.ServeHTTPErr(w, r)
+			})
+		}},
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- srv.Run()
+	}()
+
+	for l, want := range map[net.Listener]string{l1: "public", l2: "admin"} {
+		resp, err := http.Get("http://" + l.Addr().String() + "/")
+		assert.NoError(t, err)
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assert.Equal(t, want, string(body))
+		assert.Equal(t, "webmux", resp.Header.Get("X-Server"))
+	}
+
+	assert.NoError(t, srv.Shutdown(context.Background()))
+	assert.IsError(t, <-done, http.ErrServerClosed)
+}
+
+func TestServerRunShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	public := webmux.New()
+	public.Handle(http.MethodGet, "/", newTestHandler("public"))
+
+	slow := webmux.New()
+	slow.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) error {
+		close(started)
+		<-release
+		_, err := w.Write([]byte("slow"))
+		return err
+	})
+
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	srv := &webmux.Server{
+		Bindings: []webmux.Binding{
+			{Listener: l1, Handler: public},
+			{Listener: l2, Handler: slow},
+		},
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- srv.Run()
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+
+	resp := make(chan result, 1)
+
+	go func() {
+		res, err := http.Get("http://" + l2.Addr().String() + "/")
+
+		if err != nil {
+			resp <- result{err: err}
+			return
+		}
+
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		resp <- result{string(body), err}
+	}()
+
+	<-started
+
+	shutdown := make(chan error, 1)
+
+	go func() {
+		shutdown <- srv.Shutdown(context.Background())
+	}()
+
+	// Run returns once the listeners are closed, while the request is in flight
+	assert.IsError(t, <-done, http.ErrServerClosed)
+	close(release)
+
+	res := <-resp
+	assert.NoError(t, res.err)
+	assert.Equal(t, "slow", res.body)
+	assert.NoError(t, <-shutdown)
+}
@@ -0,0 +1,147 @@
+package webmux
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A ShardFunc returns the name of the shard handling a request.
+type ShardFunc func(r *http.Request) string
+
+// ShardByHost is a ShardFunc naming shards after the request host, lower
+// cased and without the port.
+func ShardByHost(r *http.Request) string {
+	host := r.Host
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(host)
+}
+
+// ShardByFirstSegment is a ShardFunc naming shards after the first segment
+// of the request path, so "/users/1" is handled by the shard "users".
+func ShardByFirstSegment(r *http.Request) string {
+	path := strings.TrimLeft(r.URL.Path, "/")
+
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
+	}
+
+	return path
+}
+
+// Sharded dispatches requests to one of several ServeMux shards, each with
+// its own routing tree, chosen by a ShardFunc. It is meant for gateways with
+// very large route tables which change frequently: a shard can be rebuilt
+// and swapped in with Swap without affecting lookups in other shards, and
+// lookups never wait for a lock.
+//
+// Requests whose shard does not exist are dispatched to the fallback mux.
+// Each shard is a regular ServeMux, so routes are registered and errors are
+// handled per shard.
+type Sharded struct {
+	key      ShardFunc
+	mu       sync.Mutex // serializes writers of shards
+	shards   atomic.Pointer[map[string]*ServeMux]
+	fallback *ServeMux
+}
+
+// NewSharded returns a new Sharded dispatching requests by key.
+func NewSharded(key ShardFunc) *Sharded {
+	if key == nil {
+		panic("webmux: nil shard func")
+	}
+
+	s := &Sharded{key: key, fallback: New()}
+	s.shards.Store(&map[string]*ServeMux{})
+
+	return s
+}
+
+// Shard returns the mux of the shard name, creating an empty one if it does
+// not exist yet.
+func (s *Sharded) Shard(name string) *ServeMux {
+	if mux, ok := (*s.shards.Load())[name]; ok {
+		return mux
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mux, ok := (*s.shards.Load())[name]; ok {
+		return mux
+	}
+
+	mux := New()
+	s.update(name, mux)
+
+	return mux
+}
+
+// Swap replaces the mux of the shard name with mux, returning the previous
+// mux or nil. If mux is nil the shard is removed and its requests go to the
+// fallback mux. Requests already dispatched to the previous mux complete
+// with it.
+func (s *Sharded) Swap(name string, mux *ServeMux) *ServeMux {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := (*s.shards.Load())[name]
+	s.update(name, mux)
+
+	return old
+}
+
+// update publishes a copy of the shards with name set to mux, or removed if
+// mux is nil. It must be called with s.mu held.
+func (s *Sharded) update(name string, mux *ServeMux) {
+	current := *s.shards.Load()
+	shards := make(map[string]*ServeMux, len(current)+1)
+
+	for k, v := range current {
+		shards[k] = v
+	}
+
+	if mux == nil {
+		delete(shards, name)
+	} else {
+		shards[name] = mux
+	}
+
+	s.shards.Store(&shards)
+}
+
+// Fallback returns the mux handling requests without a shard.
+func (s *Sharded) Fallback() *ServeMux {
+	return s.fallback
+}
+
+// Len returns the number of shards, excluding the fallback mux.
+func (s *Sharded) Len() int {
+	return len(*s.shards.Load())
+}
+
+// mux returns the mux handling r.
+func (s *Sharded) mux(r *http.Request) *ServeMux {
+	if mux, ok := (*s.shards.Load())[s.key(r)]; ok {
+		return mux
+	}
+
+	return s.fallback
+}
+
+// ServeHTTPErr dispatches the request to the ServeHTTPErr method of its shard.
+func (s *Sharded) ServeHTTPErr(w http.ResponseWriter, r *http.Request) error {
+	return s.mux(r).ServeHTTPErr(w, r)
+}
+
+// ServeHTTP implements [http.Handler] by dispatching the request to its shard,
+// whose error handler handles any error.
+func (s *Sharded) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux(r).ServeHTTP(w, r)
+}
@@ -0,0 +1,66 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestSharded(t *testing.T) {
+	h := func(body string) webmux.Handler {
+		return webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			_, err := w.Write([]byte(body))
+			return err
+		})
+	}
+
+	s := webmux.NewSharded(webmux.ShardByFirstSegment)
+	s.Shard("users").Handle(http.MethodGet, "/users/:id", h("user"))
+	s.Shard("orders").Handle(http.MethodGet, "/orders/:id", h("order"))
+	s.Fallback().Handle(http.MethodGet, "/*", h("fallback"))
+
+	serve := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w.Code, w.Body.String()
+	}
+
+	code, body := serve("/users/1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "user", body)
+
+	code, body = serve("/orders/1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "order", body)
+
+	code, _ = serve("/users/1/orders")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	_, body = serve("/about")
+	assert.Equal(t, "fallback", body)
+	assert.Equal(t, 2, s.Len())
+
+	orders := webmux.New()
+	orders.Handle(http.MethodGet, "/orders/:id", h("order v2"))
+	old := s.Swap("orders", orders)
+	assert.True(t, old != nil)
+
+	_, body = serve("/orders/1")
+	assert.Equal(t, "order v2", body)
+
+	s.Swap("orders", nil)
+	_, body = serve("/orders/1")
+	assert.Equal(t, "fallback", body)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestShardByHost(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "API.Example.com:8443"
+
+	assert.Equal(t, "api.example.com", webmux.ShardByHost(r))
+}
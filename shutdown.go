@@ -0,0 +1,66 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrShuttingDown is returned by ServeMux when a request is received after
+// Shutdown was called.
+var ErrShuttingDown = errors.New("webmux: shutting down")
+
+// Shutdown stops mux from serving new requests and waits for the requests
+// being served to complete, or for ctx to be done, in which case the
+// context's error is returned:
+//
+//	mux.SetReady(false)
+//	time.Sleep(5 * time.Second) // let the load balancer notice
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//
+//	if err := mux.Shutdown(ctx); err != nil {
+//		log.Printf("requests still in flight: %d", mux.InFlight())
+//	}
+//
+// Once Shutdown is called, requests for routes are rejected with a 503
+// Service Unavailable [HTTPError] wrapping ErrShuttingDown, except for routes
+// registered with AlwaysServe, so that health checks keep answering while the
+// server drains. Unlike [http.Server.Shutdown], Shutdown does not close
+// listeners or idle connections, which makes it usable behind proxies and
+// platforms that keep connections open, and for muxes mounted in a larger
+// server. The mux remains shut down once Shutdown returns.
+//
+// Handlers which outlived their timeout, see WithTimeout, are not waited for.
+func (mux *ServeMux) Shutdown(ctx context.Context) error {
+	mux.draining.Store(true)
+
+	for mux.inFlight.Load() > 0 {
+		select {
+		case <-mux.idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// InFlight returns the number of requests being served by mux.
+func (mux *ServeMux) InFlight() int {
+	return int(mux.inFlight.Load())
+}
+
+// end counts the completion of a request, waking up Shutdown if it was the
+// last one.
+func (mux *ServeMux) end() {
+	if mux.inFlight.Add(-1) > 0 || !mux.draining.Load() {
+		return
+	}
+
+	// Shutdown checks the count again, so a pending wake-up is enough
+	select {
+	case mux.idle <- struct{}{}:
+	default:
+	}
+}
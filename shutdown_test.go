@@ -0,0 +1,72 @@
+package webmux_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var shutdownErr error
+
+	mux := webmux.New()
+	mux.HandleFunc(http.MethodGet, "/slow", func(w http.ResponseWriter, r *http.Request) error {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+
+		return nil
+	})
+	mux.Handle(http.MethodGet, "/users", newTestHandler("users"))
+	mux.Handle(http.MethodGet, "/healthz", newTestHandler("ok"), webmux.AlwaysServe())
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		shutdownErr = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	slow := httptest.NewRecorder()
+	served := make(chan struct{})
+
+	go func() {
+		mux.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(served)
+	}()
+
+	<-started
+	assert.Equal(t, 1, mux.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.IsError(t, mux.Shutdown(ctx), context.DeadlineExceeded)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.IsError(t, shutdownErr, webmux.ErrShuttingDown)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- mux.Shutdown(context.Background())
+	}()
+
+	close(release)
+
+	assert.NoError(t, <-done)
+	<-served
+	assert.Equal(t, "done", slow.Body.String())
+	assert.Equal(t, 0, mux.InFlight())
+}
@@ -0,0 +1,75 @@
+package webmux
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// Assets computes Subresource Integrity hashes of the files of an asset
+// directory, so that pages can emit integrity attributes for the scripts and
+// stylesheets they load. Hashes are computed once per file and cached, which
+// assumes the files do not change, as with fingerprinted assets.
+//
+// It is safe for concurrent use.
+type Assets struct {
+	fsys fs.FS
+	mu   sync.Mutex
+	sums map[string]string // file name to integrity value
+}
+
+// NewAssets returns Assets for the files of fsys, typically the same
+// [fs.FS] served with [http.FileServer]:
+//
+//	assets := webmux.NewAssets(static)
+//	tmpl := template.New("page").Funcs(assets.FuncMap())
+func NewAssets(fsys fs.FS) *Assets {
+	return &Assets{fsys: fsys, sums: make(map[string]string)}
+}
+
+// SRI returns the integrity value of the file name, like "sha384-...", for the
+// integrity attribute of script and link elements.
+func (a *Assets) SRI(name string) (string, error) {
+	a.mu.Lock()
+	sum, ok := a.sums[name]
+	a.mu.Unlock()
+
+	if ok {
+		return sum, nil
+	}
+
+	f, err := a.fsys.Open(name)
+
+	if err != nil {
+		return "", fmt.Errorf("webmux: asset integrity: %w", err)
+	}
+
+	defer f.Close()
+
+	h := sha512.New384()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("webmux: asset integrity: %w", err)
+	}
+
+	sum = "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	a.mu.Lock()
+	a.sums[name] = sum
+	a.mu.Unlock()
+
+	return sum, nil
+}
+
+// FuncMap returns the template function "assetSRI" calling SRI, for
+// [html/template.Template.Funcs]:
+//
+//	<script src="/assets/app.js" integrity="{{assetSRI "app.js"}}" crossorigin="anonymous"></script>
+func (a *Assets) FuncMap() map[string]any {
+	return map[string]any{
+		"assetSRI": a.SRI,
+	}
+}
@@ -0,0 +1,35 @@
+package webmux_test
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestAssetsSRI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js": {Data: []byte("alert('hello')")},
+	}
+
+	assets := webmux.NewAssets(fsys)
+
+	sum, err := assets.SRI("app.js")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sha384-nK45OZX/RRKGmhPEj7lSXYQ3NDNNqiBUgbymhjhJ/jpCg0eAyZQ5UuzakE/UFcBd", sum)
+
+	_, err = assets.SRI("missing.js")
+
+	assert.Error(t, err)
+
+	tmpl := template.Must(template.New("page").Funcs(assets.FuncMap()).Parse(`<script integrity="{{assetSRI "app.js"}}"></script>`))
+
+	var b strings.Builder
+
+	assert.NoError(t, tmpl.Execute(&b, nil))
+	assert.Equal(t, `<script integrity="`+sum+`"></script>`, b.String())
+}
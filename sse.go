@@ -0,0 +1,322 @@
+package webmux
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults for SSEHubOptions.
+const (
+	defaultSSEBufferSize = 256
+	defaultSSEQueueSize  = 64
+	defaultSSEKeepAlive  = 30 * time.Second
+)
+
+// SSEEvent is a server-sent event.
+type SSEEvent struct {
+	ID    string        // event ID, assigned by SSEHub.Publish if empty
+	Event string        // event type, "message" if empty
+	Data  []byte        // event data, which may contain newlines
+	Retry time.Duration // reconnection time for the client, if non-zero
+}
+
+// writeTo writes ev in the text/event-stream format to w.
+func (ev *SSEEvent) writeTo(w *bytes.Buffer) {
+	if ev.ID != "" {
+		w.WriteString("id: " + ev.ID + "\n")
+	}
+
+	if ev.Event != "" {
+		w.WriteString("event: " + ev.Event + "\n")
+	}
+
+	if ev.Retry > 0 {
+		w.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	for _, line := range bytes.Split(ev.Data, []byte("\n")) {
+		w.WriteString("data: ")
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+
+	w.WriteByte('\n')
+}
+
+// SSEHubOptions configures an SSEHub.
+type SSEHubOptions struct {
+	// Param is the name of the route parameter selecting the topic a client
+	// subscribes to, such as "channel" for "/events/:channel". If empty,
+	// clients subscribe to the topic "".
+	Param string
+
+	// BufferSize is the number of recent events kept per topic for replay.
+	// If zero, a default of 256 is used.
+	BufferSize int
+
+	// QueueSize is the number of events queued per client. Clients whose
+	// queue is full are disconnected, and catch up by reconnecting with their
+	// last event ID. If zero, a default of 64 is used.
+	QueueSize int
+
+	// KeepAlive is the interval of comments sent to keep idle connections open.
+	// If zero, a default of 30 seconds is used.
+	KeepAlive time.Duration
+}
+
+// SSEHub broadcasts server-sent events to subscribed clients.
+//
+// Clients subscribe to a topic by requesting a route served by the hub.
+// Recent events of each topic are kept in a ring buffer, so that reconnecting
+// clients receive the events they missed, starting after the ID in their
+// Last-Event-ID header:
+//
+//	hub := webmux.NewSSEHub(webmux.SSEHubOptions{Param: "channel"})
+//	mux.Handle(http.MethodGet, "/events/:channel", hub)
+//
+//	hub.Publish("general", webmux.SSEEvent{Event: "message", Data: data})
+type SSEHub struct {
+	opts    SSEHubOptions
+	mu      sync.Mutex
+	seq     uint64
+	topics  map[string][]SSEEvent // topic to buffered events, oldest first
+	subs    map[*sseSubscriber]bool
+	closed  bool
+	closing chan struct{} // closed by Shutdown
+	active  sync.WaitGroup
+}
+
+// sseSubscriber is a client subscribed to a topic.
+type sseSubscriber struct {
+	topic   string
+	queue   chan SSEEvent
+	dropped chan struct{} // closed when the subscriber is disconnected for being slow
+}
+
+// NewSSEHub returns a new SSEHub.
+func NewSSEHub(opts SSEHubOptions) *SSEHub {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultSSEBufferSize
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultSSEQueueSize
+	}
+
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = defaultSSEKeepAlive
+	}
+
+	return &SSEHub{
+		opts:    opts,
+		topics:  make(map[string][]SSEEvent),
+		subs:    make(map[*sseSubscriber]bool),
+		closing: make(chan struct{}),
+	}
+}
+
+// Publish sends ev to the subscribers of topic and adds it to the replay
+// buffer of the topic. If ev has no ID, a unique increasing ID is assigned.
+// Publish never blocks on slow clients.
+func (h *SSEHub) Publish(topic string, ev SSEEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ev.ID == "" {
+		h.seq++
+		ev.ID = strconv.FormatUint(h.seq, 10)
+	}
+
+	buf := append(h.topics[topic], ev)
+
+	if len(buf) > h.opts.BufferSize {
+		buf = buf[len(buf)-h.opts.BufferSize:]
+	}
+
+	h.topics[topic] = buf
+
+	for sub := range h.subs {
+		if sub.topic != topic {
+			continue
+		}
+
+		select {
+		case sub.queue <- ev:
+		default:
+			delete(h.subs, sub)
+			close(sub.dropped)
+		}
+	}
+}
+
+// subscribe registers a subscriber to topic, returning it with the buffered
+// events published after lastID. If lastID is unknown, all buffered events are
+// returned.
+func (h *SSEHub) subscribe(topic, lastID string) (*sseSubscriber, []SSEEvent, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, false
+	}
+
+	var replay []SSEEvent
+
+	if lastID != "" {
+		buf := h.topics[topic]
+		replay = buf
+
+		for i := len(buf) - 1; i >= 0; i-- {
+			if buf[i].ID == lastID {
+				replay = buf[i+1:]
+				break
+			}
+		}
+
+		replay = append([]SSEEvent(nil), replay...)
+	}
+
+	sub := &sseSubscriber{
+		topic:   topic,
+		queue:   make(chan SSEEvent, h.opts.QueueSize),
+		dropped: make(chan struct{}),
+	}
+
+	h.subs[sub] = true
+	h.active.Add(1)
+
+	return sub, replay, true
+}
+
+// unsubscribe removes sub from the hub.
+func (h *SSEHub) unsubscribe(sub *sseSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+
+	h.active.Done()
+}
+
+// ServeHTTPErr streams the events of the requested topic to the client until
+// it disconnects or the hub is shut down. It implements Handler.
+func (h *SSEHub) ServeHTTPErr(w http.ResponseWriter, r *http.Request) error {
+	topic := ""
+
+	if h.opts.Param != "" {
+		if match, ok := FromContext(r.Context()); ok {
+			topic = match.Param(h.opts.Param)
+		}
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+
+	sub, replay, ok := h.subscribe(topic, lastID)
+
+	if !ok {
+		return NewHTTPError(http.StatusServiceUnavailable, ErrHubClosed)
+	}
+
+	defer h.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+
+	var buf bytes.Buffer
+
+	send := func(events ...SSEEvent) error {
+		buf.Reset()
+
+		for i := range events {
+			events[i].writeTo(&buf)
+		}
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	if err := send(replay...); err != nil {
+		return err
+	}
+
+	keepAlive := time.NewTicker(h.opts.KeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-sub.dropped:
+			return nil
+		case <-h.closing:
+			// Drain the queued events before disconnecting
+			for {
+				select {
+				case ev := <-sub.queue:
+					if err := send(ev); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		case ev := <-sub.queue:
+			if err := send(ev); err != nil {
+				return err
+			}
+		case <-keepAlive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return err
+			}
+
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Shutdown disconnects all clients after sending their queued events, and
+// rejects new subscriptions with 503 Service Unavailable. It waits until the
+// clients are disconnected or ctx is done.
+//
+// Call Shutdown before [http.Server.Shutdown], which waits for the streaming
+// handlers to return.
+func (h *SSEHub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+
+	if !h.closed {
+		h.closed = true
+		close(h.closing)
+	}
+
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
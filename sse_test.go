@@ -0,0 +1,71 @@
+package webmux_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestSSEHub(t *testing.T) {
+	hub := webmux.NewSSEHub(webmux.SSEHubOptions{Param: "channel", BufferSize: 3})
+
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/events/:channel", hub)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for _, data := range []string{"1", "2", "3", "4"} {
+		hub.Publish("general", webmux.SSEEvent{Data: []byte(data)})
+	}
+
+	hub.Publish("random", webmux.SSEEvent{Data: []byte("other")})
+
+	r, err := http.NewRequest(http.MethodGet, srv.URL+"/events/general", nil)
+	assert.NoError(t, err)
+	r.Header.Set("Last-Event-ID", "2")
+
+	resp, err := http.DefaultClient.Do(r)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() string {
+		var event []string
+
+		for lines.Scan() && lines.Text() != "" {
+			event = append(event, lines.Text())
+		}
+
+		return strings.Join(event, "|")
+	}
+
+	// Events after the last ID are replayed
+	assert.Equal(t, "id: 3|data: 3", next())
+	assert.Equal(t, "id: 4|data: 4", next())
+
+	hub.Publish("random", webmux.SSEEvent{Data: []byte("other")})
+	hub.Publish("general", webmux.SSEEvent{ID: "custom", Event: "greeting", Data: []byte("hello\nworld")})
+
+	assert.Equal(t, "id: custom|event: greeting|data: hello|data: world", next())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, hub.Shutdown(ctx))
+	assert.Equal(t, "", next())
+
+	resp, err = http.Get(srv.URL + "/events/general")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
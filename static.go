@@ -0,0 +1,200 @@
+package webmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// immutableMaxAge is the max-age of files with a fingerprint in their name.
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// StaticOptions configures FileServer.
+type StaticOptions struct {
+	// MaxAge is how long clients may cache files without revalidating them.
+	// If zero, clients must revalidate files before each use.
+	MaxAge time.Duration
+
+	// Immutable reports whether the file name is fingerprinted, so that its
+	// content never changes and it can be cached for a year. If nil, names
+	// with a segment of at least 8 letters and digits before the extension,
+	// like "app.3f2a9c1d.js" or "app-BzQm3k1a.js", are fingerprinted.
+	Immutable func(name string) bool
+
+	// Index serves the file "index.html" of directories. Otherwise
+	// directories are not found.
+	Index bool
+}
+
+// FileServer returns a Handler serving the files of fsys, named by the last
+// param of the matched route:
+//
+//	mux.Handle(http.MethodGet, "/assets/*path", webmux.FileServer(assets, nil))
+//
+// Unlike [http.FileServer], errors are returned so they are handled by the
+// error handler of the mux. Missing files and directories return an error
+// wrapping ErrNotFound, and files which can not be read due to permissions a
+// 403 Forbidden [HTTPError]. Directories are never listed.
+//
+// Files are served with [http.ServeContent], which handles range and
+// conditional requests, and a Cache-Control header according to opts.
+// A nil opts is the same as the zero StaticOptions.
+func FileServer(fsys fs.FS, opts *StaticOptions) Handler {
+	if fsys == nil {
+		panic("webmux: nil file system")
+	}
+
+	if opts == nil {
+		opts = &StaticOptions{}
+	}
+
+	immutable := opts.Immutable
+
+	if immutable == nil {
+		immutable = isFingerprinted
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var name string
+
+		if m, ok := FromContext(r.Context()); ok {
+			name = m.Param("*")
+		}
+
+		name = path.Clean("/" + name)[1:]
+
+		if name == "" {
+			name = "."
+		}
+
+		f, info, err := openFile(fsys, name, opts.Index)
+
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		content, ok := f.(io.ReadSeeker)
+
+		if !ok {
+			b, err := io.ReadAll(f)
+
+			if err != nil {
+				return err
+			}
+
+			content = bytes.NewReader(b)
+		}
+
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+
+		switch {
+		case immutable(info.Name()):
+			h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(immutableMaxAge.Seconds()))+", immutable")
+		case opts.MaxAge > 0:
+			h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(opts.MaxAge.Seconds())))
+		default:
+			h.Set("Cache-Control", "no-cache")
+		}
+
+		http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+
+		return nil
+	})
+}
+
+// Static registers a FileServer for the files of fsys with the default
+// StaticOptions, for GET and HEAD requests of pattern, which must end with a
+// wildcard:
+//
+//	mux.Static("/assets/*path", assets)
+func (mux *ServeMux) Static(pattern string, fsys fs.FS, opts ...RouteOption) {
+	normalized := mux.normalizePattern(pattern)
+
+	if i := strings.LastIndexByte(normalized, '/'); i < 0 || !strings.HasPrefix(normalized[i+1:], "*") {
+		panic(fmt.Sprintf("webmux: static pattern %s must end with a wildcard", pattern))
+	}
+
+	mux.register(Methods(http.MethodGet), normalized, FileServer(fsys, nil), opts)
+}
+
+// openFile opens the file name of fsys for serving, or the index of the
+// directory name if index is true.
+func openFile(fsys fs.FS, name string, index bool) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+
+	if err != nil {
+		return nil, nil, fileError(err)
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		f.Close()
+		return nil, nil, fileError(err)
+	}
+
+	if !info.IsDir() {
+		return f, info, nil
+	}
+
+	f.Close()
+
+	if !index {
+		return nil, nil, fmt.Errorf("%w: %s is a directory", ErrNotFound, name)
+	}
+
+	return openFile(fsys, path.Join(name, "index.html"), false)
+}
+
+// fileError maps errors opening a file to the errors returned by FileServer.
+func fileError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, fs.ErrPermission):
+		return NewHTTPError(http.StatusForbidden, err)
+	}
+
+	return err
+}
+
+// isFingerprinted returns true if name has a segment of at least 8 letters
+// and digits, including a digit, before its extension.
+func isFingerprinted(name string) bool {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	i := strings.LastIndexAny(base, ".-_")
+
+	if i < 0 {
+		return false
+	}
+
+	hash := base[i+1:]
+
+	if len(hash) < 8 {
+		return false
+	}
+
+	digit := false
+
+	for _, c := range hash {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		default:
+			return false
+		}
+	}
+
+	return digit
+}
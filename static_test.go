@@ -0,0 +1,92 @@
+package webmux_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServeMuxStatic(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.css":          {Data: []byte("body {}")},
+		"app.3f2a9c1d.js":  {Data: []byte("console.log(1)")},
+		"docs/index.html":  {Data: []byte("<h1>Docs</h1>")},
+		"images/logo.webp": {Data: []byte("RIFF")},
+	}
+
+	var errs []error
+
+	mux := webmux.New()
+	mux.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request, err error) {
+		errs = append(errs, err)
+		webmux.StatusErrorHandler().ErrorHTTP(w, r, err)
+	})
+	mux.Static("/assets/*path", fsys)
+	mux.Handle(http.MethodGet, "/site/*", webmux.FileServer(fsys, &webmux.StaticOptions{Index: true}))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+		return w
+	}
+
+	w := serve(http.MethodGet, "/assets/app.css")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body {}", w.Body.String())
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "text/css; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = serve(http.MethodGet, "/assets/app.3f2a9c1d.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+
+	w = serve(http.MethodHead, "/assets/app.css")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(http.MethodGet, "/assets/missing.css")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, 1, len(errs))
+	assert.IsError(t, errs[0], webmux.ErrNotFound)
+	assert.True(t, errors.Is(errs[0], fs.ErrNotExist))
+
+	w = serve(http.MethodGet, "/assets/docs")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(http.MethodGet, "/assets/./app.css")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The path is cleaned to "/app.css" before routing
+	w = serve(http.MethodGet, "/assets/../app.css")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(http.MethodGet, "/site/docs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>Docs</h1>", w.Body.String())
+
+	assert.Panics(t, func() {
+		mux.Static("/files", fsys)
+	})
+}
+
+func TestFileServerRange(t *testing.T) {
+	fsys := fstest.MapFS{"data.txt": {Data: []byte("0123456789")}}
+
+	mux := webmux.New()
+	mux.Static("/files/*", fsys)
+
+	r := httptest.NewRequest(http.MethodGet, "/files/data.txt", nil)
+	r.Header.Set("Range", "bytes=2-4")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "234", w.Body.String())
+}
@@ -0,0 +1,125 @@
+package webmux
+
+import (
+	"unsafe"
+)
+
+// mapEntryOverhead approximates the memory used by a map entry besides its
+// key and value, accounting for buckets, tophash bytes and the load factor.
+const mapEntryOverhead = 16
+
+// Stats describes the size and shape of the routing tree of a ServeMux.
+type Stats struct {
+	// Nodes is the number of nodes in the tree, including the root and the
+	// roots of the trees of hosts registered with Host.
+	Nodes int
+
+	// Entries is the number of registered patterns.
+	Entries int
+
+	// Handlers is the number of registered handlers, counting each method
+	// and conditional handler of a pattern separately.
+	Handlers int
+
+	// MaxDepth is the largest number of path segments of a pattern.
+	MaxDepth int
+
+	// Params is the number of parameter and wildcard nodes, including
+	// constrained parameters. Each is tried at lookup when no literal
+	// segment matches, so a high count slows down lookups.
+	Params int
+
+	// Constrained is the number of parameter nodes with a regular
+	// expression constraint.
+	Constrained int
+
+	// MaxParams is the largest number of parameters of a pattern.
+	MaxParams int
+
+	// Static is the number of patterns without parameters indexed for
+	// direct lookup by Freeze.
+	Static int
+
+	// Bytes is an estimate of the memory used by the tree, excluding
+	// handlers and the compiled constraints.
+	Bytes int
+}
+
+// Stats returns statistics about the routing tree of mux, useful for tuning
+// applications registering a large number of routes.
+// The tree is walked on every call, so Stats should not be called per request.
+func (mux *ServeMux) Stats() Stats {
+	var s Stats
+
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	mux.root.stats(&s, 0)
+
+	for _, h := range mux.hosts {
+		h.root.stats(&s, 0)
+	}
+	s.Static = len(mux.static)
+
+	for path := range mux.static {
+		s.Bytes += len(path) + int(unsafe.Sizeof(path)) + int(unsafe.Sizeof(uintptr(0))) + mapEntryOverhead
+	}
+
+	return s
+}
+
+// stats adds the statistics of n and its descendants to s. The depth is the
+// number of segments from the root to n.
+func (n *node) stats(s *Stats, depth int) {
+	s.Nodes++
+	s.Bytes += int(unsafe.Sizeof(*n))
+	s.MaxDepth = max(s.MaxDepth, depth)
+
+	if n.entry != nil {
+		n.entry.stats(s)
+	}
+
+	for segment, child := range n.children {
+		s.Bytes += len(segment) + int(unsafe.Sizeof(segment)) + int(unsafe.Sizeof(child)) + mapEntryOverhead
+
+		if segment == ":" || segment == "*" {
+			s.Params++
+		}
+
+		child.stats(s, depth+1)
+	}
+
+	for _, c := range n.constrained {
+		s.Params++
+		s.Constrained++
+		s.Bytes += int(unsafe.Sizeof(*c)) + int(unsafe.Sizeof(c)) + len(c.expr)
+
+		c.node.stats(s, depth+1)
+	}
+}
+
+// stats adds the statistics of e to s.
+func (e *muxEntry) stats(s *Stats) {
+	s.Entries++
+	s.MaxParams = max(s.MaxParams, len(e.params))
+	s.Bytes += int(unsafe.Sizeof(*e)) + len(e.pattern)
+
+	for _, p := range e.params {
+		s.Bytes += len(p) + int(unsafe.Sizeof(p))
+	}
+
+	for method := range e.handlers {
+		s.Handlers++
+		s.Bytes += len(method) + int(unsafe.Sizeof(method)) + int(unsafe.Sizeof(Handler(nil))) + mapEntryOverhead
+	}
+
+	for method, handlers := range e.conditional {
+		s.Handlers += len(handlers)
+		s.Bytes += len(method) + int(unsafe.Sizeof(method)) + int(unsafe.Sizeof(handlers)) + mapEntryOverhead
+		s.Bytes += len(handlers) * int(unsafe.Sizeof(conditionalHandler{}))
+	}
+
+	for _, m := range e.methods {
+		s.Bytes += int(unsafe.Sizeof(m))
+	}
+}
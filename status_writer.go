@@ -0,0 +1,58 @@
+package webmux
+
+import "net/http"
+
+// StatusWriter is an [http.ResponseWriter] recording the status code of the
+// response, for middleware reporting it, like metrics and tracing:
+//
+//	sw := &webmux.StatusWriter{ResponseWriter: w}
+//	err := next.ServeHTTPErr(sw, r)
+//	log.Printf("%s %s %d", r.Method, r.URL.Path, sw.Status())
+//
+// Informational responses are passed on without being recorded, since they
+// precede the final status.
+type StatusWriter struct {
+	http.ResponseWriter
+
+	// Code is the status code written by Write if WriteHeader was not
+	// called. If zero, 200 OK is used.
+	Code int
+
+	status int
+}
+
+// Status returns the status code of the response, or zero if it was not
+// written yet.
+func (sw *StatusWriter) Status() int {
+	return sw.status
+}
+
+// WriteHeader records code before passing it on.
+func (sw *StatusWriter) WriteHeader(code int) {
+	if sw.status == 0 && code >= http.StatusOK {
+		sw.status = code
+	}
+
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the default status code if no status was written, then
+// passes p on.
+func (sw *StatusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		code := sw.Code
+
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		sw.WriteHeader(code)
+	}
+
+	return sw.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (sw *StatusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,41 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestStatusWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	sw := &webmux.StatusWriter{ResponseWriter: w}
+
+	assert.Equal(t, 0, sw.Status())
+
+	sw.WriteHeader(http.StatusEarlyHints)
+	assert.Equal(t, 0, sw.Status())
+
+	sw.Write([]byte("ok"))
+	assert.Equal(t, http.StatusOK, sw.Status())
+
+	w = httptest.NewRecorder()
+	sw = &webmux.StatusWriter{ResponseWriter: w, Code: http.StatusNotFound}
+
+	sw.Write([]byte("missing"))
+	assert.Equal(t, http.StatusNotFound, sw.Status())
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	sw = &webmux.StatusWriter{ResponseWriter: w, Code: http.StatusNotFound}
+
+	sw.WriteHeader(http.StatusGone)
+	sw.Write([]byte("gone"))
+	assert.Equal(t, http.StatusGone, sw.Status())
+	assert.Equal(t, http.StatusGone, w.Code)
+
+	assert.NoError(t, http.NewResponseController(sw).Flush())
+	assert.True(t, w.Flushed)
+}
@@ -0,0 +1,100 @@
+package webmux
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// requestStore is the per-request storage of values set with Set.
+type requestStore struct {
+	mu       sync.Mutex
+	values   map[any]any
+	rawQuery string     // r.URL.RawQuery at dispatch
+	query    url.Values // rawQuery parsed by Query, nil until parsed
+	clock    Clock      // clock of the dispatching mux, nil for the system clock
+}
+
+// storePool recycles request stores between requests.
+var storePool = sync.Pool{
+	New: func() any {
+		return &requestStore{values: make(map[any]any)}
+	},
+}
+
+// newStoreContext returns a context for r carrying a new request store, and a
+// function releasing the store once the request is handled. If the context
+// already carries a store, as for requests dispatched to a mounted mux, it is
+// reused along with its clock.
+func newStoreContext(r *http.Request, clock Clock) (context.Context, func()) {
+	ctx := r.Context()
+
+	if s, _ := ctx.Value(storeKey).(*requestStore); s != nil {
+		return ctx, func() {}
+	}
+
+	s := storePool.Get().(*requestStore)
+	s.rawQuery = r.URL.RawQuery
+	s.clock = clock
+
+	return context.WithValue(ctx, storeKey, s), func() {
+		s.mu.Lock()
+		clear(s.values)
+		s.rawQuery, s.query, s.clock = "", nil, nil
+		s.mu.Unlock()
+
+		storePool.Put(s)
+	}
+}
+
+// Set stores v for key in the per-request store of ctx, returning false if
+// ctx is not the context of a request dispatched by ServeMux. Unlike
+// [context.WithValue], Set does not allocate a new context, so middleware can
+// share values with handlers cheaply:
+//
+//	webmux.Set(r.Context(), userKey{}, user)
+//
+// The store is created when the request is matched and recycled when the
+// handler returns, so values must not be used by goroutines outliving the
+// request. As with context values, keys should be of unexported types to
+// avoid collisions.
+func Set(ctx context.Context, key, v any) bool {
+	s, _ := ctx.Value(storeKey).(*requestStore)
+
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	s.values[key] = v
+	s.mu.Unlock()
+
+	return true
+}
+
+// Get returns the value stored for key with Set in the per-request store of
+// ctx. It returns false if there is no value of type T for key.
+//
+//	user, ok := webmux.Get[*User](r.Context(), userKey{})
+func Get[T any](ctx context.Context, key any) (T, bool) {
+	var zero T
+
+	s, _ := ctx.Value(storeKey).(*requestStore)
+
+	if s == nil {
+		return zero, false
+	}
+
+	s.mu.Lock()
+	v, ok := s.values[key]
+	s.mu.Unlock()
+
+	if !ok {
+		return zero, false
+	}
+
+	t, ok := v.(T)
+
+	return t, ok
+}
@@ -0,0 +1,226 @@
+package webmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned by ServeMux when a handler does not complete within
+// its timeout, see WithTimeout, and by the writes of the handler once the
+// timeout has passed.
+var ErrTimeout = errors.New("webmux: handler timeout")
+
+// inheritTimeout is the timeout of handlers registered without WithTimeout,
+// which use the timeout of the mux.
+const inheritTimeout time.Duration = -1
+
+// SetTimeout sets the default timeout of the handlers of mux, which is
+// disabled by default. Handlers registered with WithTimeout use their own
+// timeout instead. See WithTimeout.
+func (mux *ServeMux) SetTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		panic("webmux: invalid timeout")
+	}
+
+	mux.timeout = timeout
+}
+
+// WithTimeout returns a RouteOption limiting the time the handler has to
+// respond, overriding the timeout of the mux set with SetTimeout. A timeout
+// of zero disables the timeout of the mux for the handler, for long-lived
+// responses like downloads and server-sent events:
+//
+//	mux.SetTimeout(10 * time.Second)
+//	mux.Handle(http.MethodPost, "/reports", generateReport, webmux.WithTimeout(time.Minute))
+//	mux.Handle(http.MethodGet, "/events", hub, webmux.WithTimeout(0))
+//
+// The timeout covers the middleware of the mux and of the route along with
+// the handler, whose request context is canceled when it passes. If the
+// handler has not responded by then, a 503 Service Unavailable [HTTPError]
+// wrapping ErrTimeout is passed to the error handler. The handler may keep
+// running until it notices the cancellation, but its writes are discarded
+// and fail with ErrTimeout, and its MuxMatch and request store are not
+// recycled, so they remain valid. Headers set by the handler are only sent
+// with its response.
+//
+// A response which is already being written when the timeout passes is cut
+// short, and the error is passed to the error handler as well.
+func WithTimeout(timeout time.Duration) RouteOption {
+	if timeout < 0 {
+		panic("webmux: invalid timeout")
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// serveTimeout serves r with h, giving up after timeout. It returns true if h
+// is still running, and the error of h or ErrTimeout.
+func serveTimeout(w http.ResponseWriter, r *http.Request, h Handler, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	tw := &timeoutWriter{w: w, h: w.Header().Clone(), ctx: ctx}
+	done := make(chan error, 1)
+	panicked := make(chan any, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+		}()
+
+		done <- h.ServeHTTPErr(tw, r.WithContext(ctx))
+	}()
+
+	select {
+	case err := <-done:
+		// The handler may return as soon as it sees the deadline
+		if tw.finish() {
+			return false, NewHTTPError(http.StatusServiceUnavailable, ErrTimeout)
+		}
+
+		return false, err
+	case p := <-panicked:
+		tw.finish()
+		panic(p)
+	case <-ctx.Done():
+		tw.mu.Lock()
+		tw.timedOut = true
+		tw.mu.Unlock()
+
+		go logLatePanic(r, panicked, done)
+
+		// The client is gone, see IsClientDisconnect
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return true, ctx.Err()
+		}
+
+		return true, NewHTTPError(http.StatusServiceUnavailable, ErrTimeout)
+	}
+}
+
+// logLatePanic logs the panic of a handler which timed out, since there is
+// no request left to fail.
+func logLatePanic(r *http.Request, panicked <-chan any, done <-chan error) {
+	select {
+	case p := <-panicked:
+		logError(r, fmt.Errorf("panic after timeout: %v", p))
+	case <-done:
+	}
+}
+
+// timeoutWriter is the ResponseWriter of a handler with a timeout. Its
+// headers are copied to the underlying ResponseWriter when the response is
+// written, and writes fail once the timeout has passed.
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	ctx         context.Context // context of the handler, with the deadline
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() || tw.wroteHeader {
+		return
+	}
+
+	tw.writeHeader(code)
+}
+
+// expired returns true if the timeout has passed, even if the handler sees
+// it first. It must be called with tw.mu held.
+func (tw *timeoutWriter) expired() bool {
+	if !tw.timedOut && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+	}
+
+	return tw.timedOut
+}
+
+// writeHeader copies the headers and writes the status code. It must be
+// called with tw.mu held.
+func (tw *timeoutWriter) writeHeader(code int) {
+	tw.copyHeader()
+
+	// Informational responses precede the final status
+	if code >= http.StatusOK {
+		tw.wroteHeader = true
+	}
+
+	tw.w.WriteHeader(code)
+}
+
+// copyHeader replaces the headers of the underlying ResponseWriter with those
+// of tw. It must be called with tw.mu held.
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	clear(dst)
+
+	for name, values := range tw.h {
+		dst[name] = values
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() {
+		return 0, ErrTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+
+	return tw.w.Write(p)
+}
+
+// Flush sends the buffered response to the client, see [http.Flusher].
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() {
+		return
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+
+	http.NewResponseController(tw.w).Flush()
+}
+
+// finish copies the headers set by the handler after its response was
+// written, like trailers, or without writing a response, for the error
+// handler and the implicit response of the server. It returns true instead
+// if the handler returned without a response after the timeout.
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() && !tw.wroteHeader {
+		return true
+	}
+
+	tw.copyHeader()
+
+	return false
+}
@@ -0,0 +1,84 @@
+package webmux_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+
+	mux := webmux.New()
+	mux.SetTimeout(20 * time.Millisecond)
+	mux.HandleFunc(http.MethodGet, "/slow", func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("X-Partial", "1")
+		<-r.Context().Done()
+
+		// The match is not recycled while the handler runs
+		match, _ := webmux.FromContext(r.Context())
+		assert.Equal(t, "/slow", match.Pattern())
+
+		_, err := io.WriteString(w, "late")
+		lateWrite <- err
+
+		return r.Context().Err()
+	})
+	mux.HandleFunc(http.MethodGet, "/report", func(w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(40 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/csv")
+		w.WriteHeader(http.StatusCreated)
+		_, err := io.WriteString(w, "a,b")
+
+		return err
+	}, webmux.WithTimeout(time.Second))
+	mux.HandleFunc(http.MethodGet, "/events", func(w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(40 * time.Millisecond)
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline)
+
+		return nil
+	}, webmux.WithTimeout(0))
+	mux.HandleFunc(http.MethodGet, "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Retry-After", "5")
+		return webmux.NewHTTPError(http.StatusTooManyRequests, nil)
+	})
+	mux.HandleFunc(http.MethodGet, "/panic", func(w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "", w.Header().Get("X-Partial"))
+	assert.True(t, errors.Is(<-lateWrite, webmux.ErrTimeout))
+	assert.NotContains(t, w.Body.String(), "late")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "a,b", w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+
+	assert.Panics(t, func() {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+
+	assert.Panics(t, func() { mux.SetTimeout(-time.Second) })
+	assert.Panics(t, func() { webmux.WithTimeout(-time.Second) })
+}
@@ -0,0 +1,148 @@
+package webmux
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timingMetric is a single Server-Timing metric.
+type timingMetric struct {
+	name string
+	dur  time.Duration
+	desc string
+}
+
+// String formats m as a Server-Timing metric, like `db;dur=12.5;desc="Load user"`.
+func (m timingMetric) String() string {
+	var b strings.Builder
+
+	b.WriteString(m.name)
+	b.WriteString(";dur=")
+	b.WriteString(strconv.FormatFloat(float64(m.dur)/float64(time.Millisecond), 'f', -1, 64))
+
+	if m.desc != "" {
+		b.WriteString(";desc=")
+		b.WriteString(strconv.Quote(m.desc))
+	}
+
+	return b.String()
+}
+
+// timingState collects the Server-Timing metrics of a request.
+type timingState struct {
+	mu      sync.Mutex
+	clock   Clock
+	start   time.Time
+	metrics []timingMetric
+	written bool
+}
+
+// header returns the Server-Timing header value, including the total time so far.
+func (s *timingState) header() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.written = true
+	values := make([]string, 0, len(s.metrics)+1)
+
+	for _, m := range s.metrics {
+		values = append(values, m.String())
+	}
+
+	values = append(values, timingMetric{name: "total", dur: s.clock.Now().Sub(s.start)}.String())
+
+	return strings.Join(values, ", ")
+}
+
+// ServerTiming returns a middleware that emits the metrics recorded with
+// AddServerTiming and StartServerTiming in a Server-Timing header, along with
+// the total time spent before the response header was written. Browsers show
+// the metrics in their developer tools, which helps debugging the performance
+// of pages and API calls from the frontend.
+//
+// Metrics recorded after the response header is written are not sent.
+// Since the metrics may reveal implementation details, consider only enabling
+// the middleware for internal users.
+func ServerTiming() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			clock := clockFrom(r.Context())
+			state := &timingState{clock: clock, start: clock.Now()}
+			ctx := context.WithValue(r.Context(), timingKey, state)
+
+			tw := &timingWriter{ResponseWriter: w, state: state}
+			err := next.ServeHTTPErr(tw, r.WithContext(ctx))
+
+			// The error handler writes the response to w, set the header for it
+			if !tw.wroteHeader {
+				w.Header().Set("Server-Timing", state.header())
+			}
+
+			return err
+		})
+	}
+}
+
+// AddServerTiming records a Server-Timing metric named name, like "db" or
+// "cache", which took dur. The description is optional.
+// It does nothing unless ctx is from a request handled by ServerTiming.
+func AddServerTiming(ctx context.Context, name string, dur time.Duration, desc string) {
+	state, ok := ctx.Value(timingKey).(*timingState)
+
+	if !ok {
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if !state.written {
+		state.metrics = append(state.metrics, timingMetric{name: name, dur: dur, desc: desc})
+	}
+}
+
+// StartServerTiming starts timing the metric name, returning a function that
+// records the metric when called:
+//
+//	defer webmux.StartServerTiming(ctx, "db", "Load user")()
+func StartServerTiming(ctx context.Context, name, desc string) func() {
+	clock := clockFrom(ctx)
+	start := clock.Now()
+
+	return func() {
+		AddServerTiming(ctx, name, clock.Now().Sub(start), desc)
+	}
+}
+
+// timingWriter is a ResponseWriter setting the Server-Timing header before the header is written.
+type timingWriter struct {
+	http.ResponseWriter
+	state       *timingState
+	wroteHeader bool
+}
+
+func (t *timingWriter) WriteHeader(code int) {
+	if !t.wroteHeader {
+		t.wroteHeader = true
+		t.Header().Set("Server-Timing", t.state.header())
+	}
+
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *timingWriter) Write(p []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+
+	return t.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (t *timingWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
@@ -0,0 +1,37 @@
+package webmux_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestServerTiming(t *testing.T) {
+	mux := webmux.New()
+	mux.Handle(http.MethodGet, "/ok", webmux.ServerTiming()(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		webmux.AddServerTiming(r.Context(), "db", 12500*time.Microsecond, "Load user")
+		webmux.StartServerTiming(r.Context(), "cache", "")()
+		_, err := w.Write([]byte("ok"))
+		webmux.AddServerTiming(r.Context(), "late", time.Second, "")
+		return err
+	})))
+	mux.Handle(http.MethodGet, "/err", webmux.ServerTiming()(webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		webmux.AddServerTiming(r.Context(), "db", time.Millisecond, "")
+		return webmux.NewHTTPError(http.StatusConflict, errors.New("conflict"))
+	})))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.True(t, regexp.MustCompile(`^db;dur=12.5;desc="Load user", cache;dur=[0-9.]+, total;dur=[0-9.]+$`).MatchString(w.Header().Get("Server-Timing")), w.Header().Get("Server-Timing"))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/err", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.True(t, regexp.MustCompile(`^db;dur=1, total;dur=[0-9.]+$`).MatchString(w.Header().Get("Server-Timing")), w.Header().Get("Server-Timing"))
+}
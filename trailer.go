@@ -0,0 +1,102 @@
+package webmux
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// DeclareTrailers announces the trailers that will be set after the body
+// with SetTrailer, using the Trailer header. Declaring trailers is optional,
+// but some clients only read declared trailers. It must be called before the
+// response header is written.
+func DeclareTrailers(w http.ResponseWriter, names ...string) {
+	for _, name := range names {
+		w.Header().Add("Trailer", http.CanonicalHeaderKey(name))
+	}
+}
+
+// SetTrailer sets the trailer name to value. It may be called at any time
+// before the handler returns, including after the body has been written,
+// which allows sending values only known at the end of the response:
+//
+//	start := time.Now()
+//	webmux.DeclareTrailers(w, "X-Processing-Time")
+//	err := render(w)
+//	webmux.SetTrailer(w, "X-Processing-Time", time.Since(start).String())
+//
+// Trailers are only sent for chunked HTTP/1.1 and HTTP/2 responses. A small
+// response may be sent with a Content-Length instead, dropping its trailers,
+// unless they were declared with DeclareTrailers before the body was written.
+func SetTrailer(w http.ResponseWriter, name, value string) {
+	w.Header().Set(http.TrailerPrefix+http.CanonicalHeaderKey(name), value)
+}
+
+// responseTrailers returns the trailers in the response header h. Trailers
+// are either prefixed with [http.TrailerPrefix], or declared in the Trailer
+// header of declared and set after the header was written.
+func responseTrailers(h, declared http.Header) http.Header {
+	trailer := make(http.Header)
+
+	for name, values := range h {
+		if k, ok := strings.CutPrefix(name, http.TrailerPrefix); ok {
+			trailer[http.CanonicalHeaderKey(k)] = values
+		}
+	}
+
+	for _, v := range declared.Values("Trailer") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+
+			if values, ok := h[name]; ok && len(trailer[name]) == 0 {
+				trailer[name] = values
+			}
+		}
+	}
+
+	return trailer
+}
+
+// ContentDigestTrailer returns a middleware that sends the SHA-256 digest of
+// the response body in a Content-Digest trailer, as described in RFC 9530.
+// Clients can use the digest to verify the integrity of streamed responses,
+// whose checksum is unknown until the body is complete.
+//
+// The trailer is not sent if the handler returns an error.
+func ContentDigestTrailer() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			DeclareTrailers(w, "Content-Digest")
+
+			dw := &digestWriter{ResponseWriter: w, hash: sha256.New()}
+
+			if err := next.ServeHTTPErr(dw, r); err != nil {
+				return err
+			}
+
+			SetTrailer(w, "Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(dw.hash.Sum(nil))+":")
+
+			return nil
+		})
+	}
+}
+
+// digestWriter is a ResponseWriter hashing the response body.
+type digestWriter struct {
+	http.ResponseWriter
+	hash hash.Hash
+}
+
+func (d *digestWriter) Write(p []byte) (int, error) {
+	n, err := d.ResponseWriter.Write(p)
+	d.hash.Write(p[:n])
+
+	return n, err
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] for [http.ResponseController].
+func (d *digestWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
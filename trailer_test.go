@@ -0,0 +1,49 @@
+package webmux_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+func TestTrailers(t *testing.T) {
+	mux := webmux.New()
+	hello := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte("hello"))
+		return err
+	})
+	mux.Handle(http.MethodGet, "/digest", webmux.ContentDigestTrailer()(hello))
+
+	submit := webmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		webmux.DeclareTrailers(w, "X-Result")
+		w.Write([]byte("done"))
+		webmux.SetTrailer(w, "X-Result", "created")
+		return nil
+	})
+	dedupe := webmux.Dedupe(webmux.DedupeOptions{Key: func(r *http.Request) string { return "k" }, Replay: true})
+	mux.Handle(http.MethodPost, "/submit", dedupe(submit))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/digest")
+	assert.NoError(t, err)
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, "sha-256=:LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=:", resp.Trailer.Get("Content-Digest"))
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(srv.URL+"/submit", "text/plain", nil)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assert.Equal(t, "done", string(body))
+		assert.Equal(t, "created", resp.Trailer.Get("X-Result"))
+	}
+}
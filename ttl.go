@@ -0,0 +1,157 @@
+package webmux
+
+import (
+	"time"
+)
+
+// WithTTL returns a RouteOption expiring the handler ttl after it is
+// registered, according to the clock of the mux. It is meant for routes
+// registered while serving, like one-time webhook callback URLs or short
+// lived preview environments:
+//
+//	mux.Handle(http.MethodPost, "/callbacks/"+id, callback, webmux.WithTTL(time.Hour))
+//
+// Once expired, the handler is no longer matched, and a route whose handlers
+// have all expired responds as if it was never registered. A new handler can
+// be registered for the method and pattern of an expired handler. The
+// memory used by expired handlers is reclaimed by Prune.
+func WithTTL(ttl time.Duration) RouteOption {
+	if ttl <= 0 {
+		panic("webmux: invalid route ttl")
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// Prune removes the expired handlers of routes registered with WithTTL from
+// mux, along with routes left without handlers.
+func (mux *ServeMux) Prune() {
+	now := mux.now()
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.root.prune(now)
+	mux.root.compact()
+
+	for _, h := range mux.hosts {
+		h.root.prune(now)
+		h.root.compact()
+	}
+
+	if mux.frozen {
+		mux.static = make(map[string]*muxEntry)
+		mux.root.collectStatic("", mux.static)
+	}
+}
+
+// now returns the current time of the clock of mux.
+func (mux *ServeMux) now() time.Time {
+	if mux.clock == nil {
+		return time.Now()
+	}
+
+	return mux.clock.Now()
+}
+
+// prune removes the expired handlers of n and its descendants, and the
+// entries left without handlers.
+func (n *node) prune(now time.Time) {
+	if n.entry != nil && n.entry.expiring {
+		e := n.entry.clone()
+
+		if e.prune(now) {
+			n.entry = nil
+		} else {
+			n.entry = e
+		}
+	}
+
+	for _, child := range n.children {
+		child.prune(now)
+	}
+
+	for _, c := range n.constrained {
+		c.node.prune(now)
+	}
+}
+
+// prune removes the expired handlers of e, returning true if it has no
+// handlers left.
+func (e *muxEntry) prune(now time.Time) bool {
+	for method, expires := range e.expires {
+		if now.Before(expires) {
+			continue
+		}
+
+		delete(e.handlers, method)
+		delete(e.expires, method)
+		delete(e.sources, method)
+		delete(e.meta, method)
+		delete(e.middleware, method)
+		delete(e.timeouts, method)
+		delete(e.chained, method)
+	}
+
+	for method, handlers := range e.conditional {
+		// The slices are shared with the entry being served, filter a copy
+		live := make([]conditionalHandler, 0, len(handlers))
+
+		for _, c := range handlers {
+			if c.expires.IsZero() || now.Before(c.expires) {
+				live = append(live, c)
+			}
+		}
+
+		if len(live) == 0 {
+			delete(e.conditional, method)
+		} else {
+			e.conditional[method] = live
+		}
+	}
+
+	e.resetMethods()
+	e.expiring = len(e.expires) > 0
+
+	for _, handlers := range e.conditional {
+		for _, c := range handlers {
+			e.expiring = e.expiring || !c.expires.IsZero()
+		}
+	}
+
+	return len(e.handlers) == 0 && len(e.conditional) == 0
+}
+
+// expired returns true if e has handlers registered with WithTTL, and all of
+// its handlers have expired at now.
+func (e *muxEntry) expired(now time.Time) bool {
+	if !e.expiring {
+		return false
+	}
+
+	for method := range e.handlers {
+		if !e.handlerExpired(method, now) {
+			return false
+		}
+	}
+
+	for _, handlers := range e.conditional {
+		for _, c := range handlers {
+			if c.expires.IsZero() || now.Before(c.expires) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// handlerExpired returns true if the handler registered for method without
+// predicates has expired at now.
+func (e *muxEntry) handlerExpired(method string, now time.Time) bool {
+	expires, ok := e.expires[method]
+
+	return ok && !now.Before(expires)
+}
@@ -0,0 +1,95 @@
+package webmux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+	"go.destructure.dev/webmux/muxtest"
+)
+
+func TestServeMuxWithTTL(t *testing.T) {
+	clock := muxtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	mux := webmux.New()
+	mux.SetClock(clock)
+	mux.Handle(http.MethodGet, "/", newTestHandler("home"))
+	mux.Handle(http.MethodPost, "/callbacks/:id", newTestHandler("callback"), webmux.WithTTL(time.Hour))
+	mux.Handle(http.MethodGet, "/previews/a", newTestHandler("preview"), webmux.WithTTL(time.Hour))
+	mux.Handle(http.MethodPost, "/previews/a", newTestHandler("deploy"), webmux.WithTTL(2*time.Hour))
+
+	serve := func(method, path string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/callbacks/1"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/previews/a"))
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/callbacks/1"))
+	assert.NotEqual(t, http.StatusOK, serve(http.MethodGet, "/previews/a"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/previews/a"))
+
+	// Expired handlers can be registered again
+	mux.Handle(http.MethodPost, "/callbacks/:id", newTestHandler("callback"), webmux.WithTTL(time.Hour))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/callbacks/1"))
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, 4, mux.Stats().Handlers)
+
+	mux.Prune()
+	assert.Equal(t, 1, mux.Stats().Handlers)
+	assert.Equal(t, 1, mux.Stats().Nodes)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/"))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/previews/a"))
+
+	assert.Panics(t, func() {
+		webmux.WithTTL(0)
+	})
+}
+
+func TestServeMuxPruneConcurrent(t *testing.T) {
+	clock := muxtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	never := webmux.When(func(r *http.Request) bool { return false })
+
+	mux := webmux.New()
+	mux.SetClock(clock)
+	mux.Handle(http.MethodGet, "/", newTestHandler("home"), webmux.When(func(r *http.Request) bool { return true }))
+
+	done := make(chan struct{})
+	served := make(chan struct{})
+
+	go func() {
+		defer close(served)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, "home", w.Body.String())
+			runtime.Gosched()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		mux.Handle(http.MethodGet, "/", newTestHandler("expired"), never, webmux.WithTTL(time.Minute))
+		runtime.Gosched()
+		clock.Advance(time.Minute)
+		mux.Prune()
+	}
+
+	close(done)
+	<-served
+}
@@ -0,0 +1,135 @@
+package webmux
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// A Tx is a transaction which is either committed or rolled back.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// A TxProvider begins transactions.
+type TxProvider interface {
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// The TxProviderFunc type is an adapter to allow the use of ordinary functions
+// as transaction providers.
+type TxProviderFunc func(ctx context.Context) (Tx, error)
+
+// BeginTx calls f(ctx).
+func (f TxProviderFunc) BeginTx(ctx context.Context) (Tx, error) {
+	return f(ctx)
+}
+
+// SQLTxProvider returns a TxProvider beginning transactions on db with opts.
+// Within a handler the transaction can be retrieved with TxFromContext and
+// asserted to a [*sql.Tx].
+func SQLTxProvider(db *sql.DB, opts *sql.TxOptions) TxProvider {
+	return TxProviderFunc(func(ctx context.Context) (Tx, error) {
+		return db.BeginTx(ctx, opts)
+	})
+}
+
+// Transactional returns a RouteOption that runs the handler in a transaction.
+//
+// A transaction is started using provider before the handler is called, and
+// is available to the handler with TxFromContext. If the handler returns nil
+// the transaction is committed, otherwise it is rolled back. If the handler
+// panics the transaction is rolled back before the panic continues.
+func Transactional(provider TxProvider) RouteOption {
+	if provider == nil {
+		panic("webmux: nil tx provider")
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.middleware = append(cfg.middleware, func(next Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				return serveTx(w, r, provider, next)
+			})
+		})
+	}
+}
+
+// serveTx calls next within a transaction begun with provider.
+func serveTx(w http.ResponseWriter, r *http.Request, provider TxProvider, next Handler) (err error) {
+	tx, err := provider.BeginTx(r.Context())
+
+	if err != nil {
+		return fmt.Errorf("webmux: begin transaction: %w", err)
+	}
+
+	committed := false
+
+	defer func() {
+		if !committed {
+			if rerr := tx.Rollback(); rerr != nil && err != nil {
+				err = errors.Join(err, fmt.Errorf("webmux: rollback transaction: %w", rerr))
+			}
+		}
+	}()
+
+	state := &txState{tx: tx}
+	ctx := context.WithValue(r.Context(), txKey, state)
+
+	if err := next.ServeHTTPErr(w, r.WithContext(ctx)); err != nil {
+		return err
+	}
+
+	committed = true
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("webmux: commit transaction: %w", err)
+	}
+
+	// Callbacks may outlive the request, so they must not be canceled with it
+	detached := context.WithoutCancel(ctx)
+
+	for _, fn := range state.afterCommit {
+		fn(detached)
+	}
+
+	return nil
+}
+
+// txState is the transaction of a request and its after commit callbacks.
+type txState struct {
+	tx          Tx
+	afterCommit []func(ctx context.Context)
+}
+
+// TxFromContext returns the transaction stored in ctx by a Transactional route, if any.
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	state, ok := ctx.Value(txKey).(*txState)
+
+	if !ok {
+		return nil, false
+	}
+
+	return state.tx, true
+}
+
+// AfterCommit registers fn to be called after the transaction stored in ctx
+// by a Transactional route commits. If the transaction is rolled back fn is
+// never called. This is useful for side effects like sending emails or
+// publishing events, which must only happen if the transaction succeeds.
+//
+// Callbacks are called in the order they were registered, with a context
+// that is not canceled when the request completes. If ctx does not carry a
+// transaction fn is called immediately.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	state, ok := ctx.Value(txKey).(*txState)
+
+	if !ok {
+		fn(ctx)
+		return
+	}
+
+	state.afterCommit = append(state.afterCommit, fn)
+}
@@ -0,0 +1,81 @@
+package webmux_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+type fakeTx struct {
+	state string
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.state = "committed"
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	if tx.state == "" {
+		tx.state = "rolled back"
+	}
+	return nil
+}
+
+func TestTransactional(t *testing.T) {
+	var tx *fakeTx
+
+	provider := webmux.TxProviderFunc(func(ctx context.Context) (webmux.Tx, error) {
+		tx = &fakeTx{}
+		return tx, nil
+	})
+
+	errFailed := errors.New("failed")
+
+	mux := webmux.New()
+
+	var events []string
+
+	mux.HandleFunc(http.MethodPost, "/ok", func(w http.ResponseWriter, r *http.Request) error {
+		_, ok := webmux.TxFromContext(r.Context())
+		assert.True(t, ok)
+
+		webmux.AfterCommit(r.Context(), func(ctx context.Context) {
+			events = append(events, "sent "+tx.state)
+		})
+
+		return nil
+	}, webmux.Transactional(provider))
+
+	mux.HandleFunc(http.MethodPost, "/fail", func(w http.ResponseWriter, r *http.Request) error {
+		webmux.AfterCommit(r.Context(), func(ctx context.Context) {
+			events = append(events, "sent "+tx.state)
+		})
+
+		return errFailed
+	}, webmux.Transactional(provider))
+
+	mux.HandleFunc(http.MethodPost, "/panic", func(w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	}, webmux.Transactional(provider))
+
+	err := mux.ServeHTTPErr(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/ok", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "committed", tx.state)
+	assert.Equal(t, []string{"sent committed"}, events)
+
+	err = mux.ServeHTTPErr(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", nil))
+	assert.IsError(t, err, errFailed)
+	assert.Equal(t, "rolled back", tx.state)
+	assert.Equal(t, []string{"sent committed"}, events)
+
+	assert.Panics(t, func() {
+		mux.ServeHTTPErr(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/panic", nil))
+	})
+	assert.Equal(t, "rolled back", tx.state)
+}
@@ -0,0 +1,110 @@
+package webmux
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+)
+
+// Unhandle removes the handlers registered for method and pattern, including
+// conditional handlers, returning false if there are none. Routes registered
+// with Host are removed by passing the same Host option. Aliases of the route
+// are not removed.
+//
+// Like Handle, Unhandle may be called while mux is serving requests, which
+// allows plugins to add and remove routes at runtime. Requests already
+// dispatched to a removed handler complete normally.
+// Unhandle panics if mux is frozen.
+func (mux *ServeMux) Unhandle(method, pattern string, opts ...RouteOption) bool {
+	cfg := newRouteConfig(opts)
+	pattern = mux.normalizePattern(pattern)
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if mux.frozen {
+		panic(fmt.Sprintf("webmux: removal of %s after Freeze", pattern))
+	}
+
+	root := mux.hostTree(cfg.host)
+	n := root.find(pattern)
+
+	if n == nil || n.entry == nil || !n.entry.hasHandler(method) {
+		return false
+	}
+
+	e := n.entry.clone()
+
+	delete(e.handlers, method)
+	delete(e.conditional, method)
+	delete(e.sources, method)
+	delete(e.meta, method)
+	delete(e.middleware, method)
+	delete(e.timeouts, method)
+	delete(e.expires, method)
+	delete(e.chained, method)
+	e.resetMethods()
+
+	if len(e.handlers) == 0 && len(e.conditional) == 0 {
+		n.entry = nil
+		root.compact()
+	} else {
+		n.entry = e
+	}
+
+	return true
+}
+
+// compact removes the descendants of n without entries, returning true if n
+// is left without entry and children.
+func (n *node) compact() bool {
+	for segment, child := range n.children {
+		if child.compact() {
+			delete(n.children, segment)
+		}
+	}
+
+	constrained := n.constrained[:0]
+
+	for _, c := range n.constrained {
+		if !c.node.compact() {
+			constrained = append(constrained, c)
+		}
+	}
+
+	clear(n.constrained[len(constrained):])
+	n.constrained = constrained
+
+	return n.entry == nil && len(n.children) == 0 && len(n.constrained) == 0
+}
+
+// clone returns a copy of e which can be modified without affecting requests
+// using e. Entries are copied on write since matches reference them without
+// holding the lock of the mux.
+func (e *muxEntry) clone() *muxEntry {
+	c := *e
+	c.handlers = maps.Clone(e.handlers)
+	c.conditional = maps.Clone(e.conditional)
+	c.sources = maps.Clone(e.sources)
+	c.meta = maps.Clone(e.meta)
+	c.middleware = maps.Clone(e.middleware)
+	c.timeouts = maps.Clone(e.timeouts)
+	c.chained = maps.Clone(e.chained)
+	c.expires = maps.Clone(e.expires)
+	c.methods = append(MethodSet(nil), e.methods...)
+
+	return &c
+}
+
+// resetMethods recomputes the allowed methods of e from its handlers.
+func (e *muxEntry) resetMethods() {
+	e.methods = Methods(http.MethodOptions)
+
+	for _, method := range e.registeredMethods() {
+		e.methods = e.methods.Add(method)
+	}
+
+	if e.methods.Has(http.MethodGet) && !e.methods.Has(http.MethodHead) {
+		e.methods = e.methods.Add(http.MethodHead)
+	}
+}
@@ -0,0 +1,162 @@
+package webmux
+
+import (
+	"sort"
+)
+
+// WalkFunc is the type of the function called by Walk for each registered handler.
+// If WalkFunc returns an error, Walk stops and returns that error.
+type WalkFunc func(method, pattern string, handler Handler) error
+
+// Walk calls fn for each handler registered with mux, including conditional
+// handlers. Routes are visited in a stable order: sorted by path segment
+// depth-first, with literal segments before constrained parameters, parameters and
+// wildcards, and
+// methods in sorted order.
+//
+// Routes registered with Host are visited after the routes registered without
+// a host, host by host in order of precedence.
+func (mux *ServeMux) Walk(fn WalkFunc) error {
+	var entries []*muxEntry
+
+	collect := func(e *muxEntry) error {
+		entries = append(entries, e)
+		return nil
+	}
+
+	// Entries are copied on write, so fn is called without holding the lock,
+	// allowing it to register routes
+	mux.mu.RLock()
+	mux.root.walkEntries(collect)
+
+	for _, h := range mux.hosts {
+		h.root.walkEntries(collect)
+	}
+
+	mux.mu.RUnlock()
+
+	for _, e := range entries {
+		if err := e.walk(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walk calls fn for the handlers of e.
+func (e *muxEntry) walk(fn WalkFunc) error {
+	for _, method := range e.registeredMethods() {
+		for _, c := range e.conditional[method] {
+			if err := fn(method, e.pattern, c.handler); err != nil {
+				return err
+			}
+		}
+
+		if h, ok := e.handlers[method]; ok {
+			if err := fn(method, e.pattern, h); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// walkEntries calls fn for the entries of n and its descendants, in the order
+// documented by Walk.
+func (n *node) walkEntries(fn func(e *muxEntry) error) error {
+	if n.entry != nil {
+		if err := fn(n.entry); err != nil {
+			return err
+		}
+	}
+
+	constrained := false
+
+	for _, segment := range n.sortedSegments() {
+		// Constrained params are visited before the other params
+		if segmentWeight(segment) > 0 && !constrained {
+			if err := n.walkConstrained(fn); err != nil {
+				return err
+			}
+
+			constrained = true
+		}
+
+		if err := n.children[segment].walkEntries(fn); err != nil {
+			return err
+		}
+	}
+
+	if !constrained {
+		return n.walkConstrained(fn)
+	}
+
+	return nil
+}
+
+// walkConstrained calls fn for the entries below the constrained params of n.
+func (n *node) walkConstrained(fn func(e *muxEntry) error) error {
+	for _, c := range n.constrained {
+		if err := c.node.walkEntries(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortedSegments returns the child segments of n sorted with literal
+// segments first, followed by the param and wildcard segments.
+func (n *node) sortedSegments() []string {
+	segments := make([]string, 0, len(n.children))
+
+	for s := range n.children {
+		segments = append(segments, s)
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		wi, wj := segmentWeight(segments[i]), segmentWeight(segments[j])
+
+		if wi != wj {
+			return wi < wj
+		}
+
+		return segments[i] < segments[j]
+	})
+
+	return segments
+}
+
+// segmentWeight orders literal segments before params before wildcards.
+func segmentWeight(segment string) int {
+	switch segment {
+	case ":":
+		return 1
+	case "*":
+		return 2
+	}
+
+	return 0
+}
+
+// registeredMethods returns the methods with a registered handler in sorted order.
+// Unlike methods, this excludes the implicit HEAD and OPTIONS methods.
+func (e *muxEntry) registeredMethods() []string {
+	methods := make([]string, 0, len(e.handlers)+len(e.conditional))
+
+	for m := range e.handlers {
+		methods = append(methods, m)
+	}
+
+	for m := range e.conditional {
+		if _, ok := e.handlers[m]; !ok {
+			methods = append(methods, m)
+		}
+	}
+
+	sort.Strings(methods)
+
+	return methods
+}
@@ -0,0 +1,35 @@
+package webmux
+
+import "strings"
+
+// matchWildcard matches the wildcard node n against rest, the remaining path
+// without its leading slash, returning the node of the matching route and
+// values with the captured values appended, or nil if there is none.
+//
+// A wildcard ending the pattern captures all of rest. A wildcard followed by
+// more segments, like "/files/*path/meta", captures at least one segment,
+// and as many as possible while the rest of the pattern still matches, so
+// "/files/a/meta/meta" captures "a/meta". Routes continuing after the
+// wildcard take precedence over a route ending with it.
+func (n *node) matchWildcard(rest string, values []string) (*node, []string) {
+	if len(n.children) > 0 || len(n.constrained) > 0 {
+		for i := strings.LastIndexByte(rest, '/'); i > 0; i = strings.LastIndexByte(rest[:i], '/') {
+			// A trailing slash is captured, as by a wildcard ending the pattern
+			if i == len(rest)-1 {
+				continue
+			}
+
+			found, captured := n.match(rest[i:], append(values, rest[:i]))
+
+			if found != nil && found.entry != nil {
+				return found, captured
+			}
+		}
+	}
+
+	if n.entry == nil {
+		return nil, values
+	}
+
+	return n, append(values, rest)
+}
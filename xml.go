@@ -0,0 +1,157 @@
+package webmux
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedMediaType is returned when the request body has an unexpected Content-Type.
+var ErrUnsupportedMediaType = errors.New("webmux: unsupported media type")
+
+// XMLOptions configures how DecodeXML reads request bodies.
+type XMLOptions struct {
+	// StrictContentType rejects requests that do not declare an XML media type
+	// such as "application/xml", "text/xml", or "application/soap+xml".
+	StrictContentType bool
+
+	// CharsetReader converts input in a non-UTF-8 charset to UTF-8. The charset
+	// is taken from the Content-Type header, falling back to the encoding in the
+	// XML declaration. If nil, ISO-8859-1 and US-ASCII are supported.
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+}
+
+// DecodeXML decodes the XML request body of r into v.
+// Malformed bodies result in a 400 [HTTPError], and when opts.StrictContentType
+// is set a non-XML Content-Type results in a 415 [HTTPError].
+// A nil opts is equivalent to a zero XMLOptions.
+func DecodeXML(r *http.Request, v any, opts *XMLOptions) error {
+	if opts == nil {
+		opts = &XMLOptions{}
+	}
+
+	charsetReader := opts.CharsetReader
+
+	if charsetReader == nil {
+		charsetReader = defaultCharsetReader
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if opts.StrictContentType && !isXMLMediaType(mediaType) {
+		return NewHTTPError(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+	}
+
+	var body io.Reader = r.Body
+
+	// The charset in the Content-Type header takes precedence over the XML declaration
+	if charset := params["charset"]; charset != "" && !isUTF8(charset) {
+		converted, err := charsetReader(charset, body)
+
+		if err != nil {
+			return NewHTTPError(http.StatusUnsupportedMediaType, err)
+		}
+
+		body = converted
+		charsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+
+	dec := xml.NewDecoder(body)
+	dec.CharsetReader = charsetReader
+
+	if err := dec.Decode(v); err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Errorf("webmux: decode xml: %w", err))
+	}
+
+	return nil
+}
+
+// WriteXML writes v to w as an XML document with the status code code.
+// The Content-Type is set to "application/xml; charset=utf-8" unless already set.
+func WriteXML(w http.ResponseWriter, code int, v any) error {
+	b, err := xml.Marshal(v)
+
+	if err != nil {
+		return fmt.Errorf("webmux: encode xml: %w", err)
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	}
+
+	w.WriteHeader(code)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+// isXMLMediaType returns true if mediaType is an XML media type.
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == "application/xml" ||
+		mediaType == "text/xml" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+xml"))
+}
+
+// isUTF8 returns true if charset names UTF-8.
+func isUTF8(charset string) bool {
+	return strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "utf8")
+}
+
+// defaultCharsetReader supports UTF-8, US-ASCII, and ISO-8859-1 input.
+func defaultCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+
+	return nil, fmt.Errorf("%w: charset %q", ErrUnsupportedMediaType, charset)
+}
+
+// latin1Reader converts ISO-8859-1 input to UTF-8.
+type latin1Reader struct {
+	r   io.ByteReader
+	buf []byte // encoded bytes not yet returned
+}
+
+// Read implements [io.Reader].
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) {
+		if len(l.buf) > 0 {
+			c := copy(p[n:], l.buf)
+			l.buf = l.buf[c:]
+			n += c
+			continue
+		}
+
+		b, err := l.r.ReadByte()
+
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+
+			return n, err
+		}
+
+		l.buf = utf8.AppendRune(l.buf[:0], rune(b))
+	}
+
+	return n, nil
+}
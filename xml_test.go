@@ -0,0 +1,111 @@
+package webmux_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"go.destructure.dev/webmux"
+)
+
+type xmlGreeting struct {
+	XMLName xml.Name `xml:"greeting"`
+	Name    string   `xml:"name"`
+}
+
+func TestDecodeXML(t *testing.T) {
+	var tests = []struct {
+		name        string
+		contentType string
+		body        string
+		strict      bool
+		want        string
+		code        int
+	}{
+		{
+			"utf-8",
+			"application/xml",
+			"<greeting><name>Zoë</name></greeting>",
+			true,
+			"Zoë",
+			0,
+		},
+		{
+			"latin1 header",
+			"text/xml; charset=ISO-8859-1",
+			"<greeting><name>Zo\xeb</name></greeting>",
+			true,
+			"Zoë",
+			0,
+		},
+		{
+			"latin1 declaration",
+			"application/soap+xml",
+			`<?xml version="1.0" encoding="ISO-8859-1"?><greeting><name>Zo` + "\xeb" + `</name></greeting>`,
+			true,
+			"Zoë",
+			0,
+		},
+		{
+			"strict content type",
+			"application/json",
+			"<greeting><name>Zoë</name></greeting>",
+			true,
+			"",
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"lenient content type",
+			"text/plain",
+			"<greeting><name>Zoë</name></greeting>",
+			false,
+			"Zoë",
+			0,
+		},
+		{
+			"malformed",
+			"application/xml",
+			"<greeting><name>",
+			false,
+			"",
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+
+			var v xmlGreeting
+
+			err := webmux.DecodeXML(r, &v, &webmux.XMLOptions{StrictContentType: tc.strict})
+
+			if tc.code != 0 {
+				var httpErr *webmux.HTTPError
+
+				assert.True(t, errors.As(err, &httpErr))
+				assert.Equal(t, tc.code, httpErr.Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, v.Name)
+		})
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := webmux.WriteXML(w, http.StatusCreated, xmlGreeting{Name: "Zoë"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+"<greeting><name>Zoë</name></greeting>", w.Body.String())
+}